@@ -0,0 +1,181 @@
+package tox
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PublicKey is a long term public key of a tox peer.
+type PublicKey [PUBLIC_KEY_SIZE]byte
+
+// Nospam is the anti-spam value embedded in a tox address.
+type Nospam uint32
+
+// Address is a tox friend address: public key, nospam and a 2 byte checksum.
+type Address [ADDRESS_SIZE]byte
+
+var ErrAddressChecksum = errors.New("tox address checksum mismatch")
+
+// decodeHex decodes any-case hex into dst, which it must fill exactly.
+func decodeHex(dst []byte, s string, what string) error {
+	if len(s) != len(dst)*2 {
+		return fmt.Errorf("invalid %s length: %d, want %d hex chars", what, len(s), len(dst)*2)
+	}
+	if _, err := hex.Decode(dst, []byte(s)); err != nil {
+		return fmt.Errorf("invalid %s: %w", what, err)
+	}
+	return nil
+}
+
+func ParsePublicKey(s string) (PublicKey, error) {
+	var pk PublicKey
+	err := decodeHex(pk[:], strings.TrimSpace(s), "public key")
+	return pk, err
+}
+
+// parseKeyOrAddress takes the public key part of an address too, the checksum
+// is not checked.
+func parseKeyOrAddress(s string) (PublicKey, error) {
+	if len(s) == ADDRESS_SIZE*2 {
+		var addr Address
+		err := decodeHex(addr[:], s, "address")
+		return addr.PublicKey(), err
+	}
+	return ParsePublicKey(s)
+}
+
+func (this PublicKey) String() string {
+	return strings.ToUpper(hex.EncodeToString(this[:]))
+}
+
+func (this PublicKey) MarshalText() ([]byte, error) {
+	return []byte(this.String()), nil
+}
+
+func (this *PublicKey) UnmarshalText(text []byte) error {
+	pk, err := ParsePublicKey(string(text))
+	if err == nil {
+		*this = pk
+	}
+	return err
+}
+
+func ParseNospam(s string) (Nospam, error) {
+	var b [4]byte
+	if err := decodeHex(b[:], strings.TrimSpace(s), "nospam"); err != nil {
+		return 0, err
+	}
+	return Nospam(binary.BigEndian.Uint32(b[:])), nil
+}
+
+func (this Nospam) String() string {
+	return fmt.Sprintf("%08X", uint32(this))
+}
+
+func (this Nospam) MarshalText() ([]byte, error) {
+	return []byte(this.String()), nil
+}
+
+func (this *Nospam) UnmarshalText(text []byte) error {
+	nospam, err := ParseNospam(string(text))
+	if err == nil {
+		*this = nospam
+	}
+	return err
+}
+
+// NewAddress builds the address for pk and nospam, with its checksum.
+func NewAddress(pk PublicKey, nospam Nospam) Address {
+	var addr Address
+	copy(addr[:], pk[:])
+	binary.BigEndian.PutUint32(addr[PUBLIC_KEY_SIZE:], uint32(nospam))
+	sum := addr.computeChecksum()
+	copy(addr[PUBLIC_KEY_SIZE+4:], sum[:])
+	return addr
+}
+
+// ParseAddress parses an any-case hex address, optionally as a "tox:" URI,
+// and verifies its checksum.
+func ParseAddress(s string) (Address, error) {
+	var addr Address
+	s = strings.TrimSpace(s)
+	if len(s) >= 4 && strings.EqualFold(s[:4], "tox:") {
+		var err error
+		if s, err = parseURI(s); err != nil {
+			return addr, err
+		}
+	}
+	if err := decodeHex(addr[:], s, "address"); err != nil {
+		return addr, err
+	}
+	if !addr.Valid() {
+		return addr, ErrAddressChecksum
+	}
+	return addr, nil
+}
+
+// parseURI returns the address part of tox:ADDRESS or tox://ADDRESS,
+// dropping any query or fragment.
+func parseURI(uri string) (string, error) {
+	s := strings.TrimPrefix(uri[4:], "//")
+	if pos := strings.IndexAny(s, "?#"); pos >= 0 {
+		s = s[:pos]
+	}
+	s = strings.TrimSuffix(s, "/")
+	if s == "" {
+		return "", fmt.Errorf("invalid tox uri: %q", uri)
+	}
+	return s, nil
+}
+
+func (this Address) PublicKey() PublicKey {
+	var pk PublicKey
+	copy(pk[:], this[:PUBLIC_KEY_SIZE])
+	return pk
+}
+
+func (this Address) Nospam() Nospam {
+	return Nospam(binary.BigEndian.Uint32(this[PUBLIC_KEY_SIZE:]))
+}
+
+func (this Address) Checksum() uint16 {
+	return binary.BigEndian.Uint16(this[PUBLIC_KEY_SIZE+4:])
+}
+
+// the checksum xors the key and nospam bytes pairwise, as toxcore does
+func (this Address) computeChecksum() (sum [2]byte) {
+	for i := 0; i < PUBLIC_KEY_SIZE+4; i++ {
+		sum[i%2] ^= this[i]
+	}
+	return
+}
+
+// Valid reports whether the checksum matches the key and nospam.
+func (this Address) Valid() bool {
+	sum := this.computeChecksum()
+	return sum[0] == this[PUBLIC_KEY_SIZE+4] && sum[1] == this[PUBLIC_KEY_SIZE+5]
+}
+
+func (this Address) String() string {
+	return strings.ToUpper(hex.EncodeToString(this[:]))
+}
+
+// URI returns the address as a "tox:" URI.
+func (this Address) URI() string {
+	return "tox:" + this.String()
+}
+
+func (this Address) MarshalText() ([]byte, error) {
+	return []byte(this.String()), nil
+}
+
+func (this *Address) UnmarshalText(text []byte) error {
+	addr, err := ParseAddress(string(text))
+	if err == nil {
+		*this = addr
+	}
+	return err
+}
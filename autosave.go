@@ -0,0 +1,224 @@
+package tox
+
+/*
+#include <tox/tox.h>
+
+typedef const uint8_t acuint8_t;
+void callbackFriendNameWrapperForC(Tox *, uint32_t, acuint8_t*, size_t, void*);
+void callbackFriendStatusMessageWrapperForC(Tox *, uint32_t, acuint8_t*, size_t, void*);
+void callbackFriendStatusWrapperForC(Tox *, uint32_t, Tox_User_Status, void*);
+void callbackFriendConnectionStatusWrapperForC(Tox *, uint32_t, Tox_Connection, void*);
+void callbackConferenceTitleWrapperForC(Tox*, uint32_t, uint32_t, acuint8_t*, size_t, void*);
+void callbackConferencePeerNameWrapperForC(Tox*, uint32_t, uint32_t, acuint8_t*, size_t, void*);
+void callbackConferencePeerListChangedWrapperForC(Tox*, uint32_t, void*);
+*/
+import "C"
+import (
+	"sync"
+	"time"
+)
+
+// at most this many delays pass before a constantly changing instance saves
+const autosaveMaxDelays = 10
+
+type autosaver struct {
+	store   SaveStore
+	delay   time.Duration
+	onError func(error)
+
+	mu        sync.Mutex
+	dirty     bool
+	firstMark time.Time
+	lastMark  time.Time
+}
+
+func (this *autosaver) mark(now time.Time) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if !this.dirty {
+		this.dirty = true
+		this.firstMark = now
+	}
+	this.lastMark = now
+}
+
+// due is true once delay passed without changes, or changes kept coming for
+// autosaveMaxDelays delays.
+func (this *autosaver) due(now time.Time) bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.dirty && (now.Sub(this.lastMark) >= this.delay ||
+		now.Sub(this.firstMark) >= autosaveMaxDelays*this.delay)
+}
+
+// take clears the dirty flag and reports whether it was set.
+func (this *autosaver) take() bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	dirty := this.dirty
+	this.dirty = false
+	return dirty
+}
+
+// save marks the instance dirty again on failure, so it is retried.
+func (this *autosaver) save(data []byte) error {
+	err := this.store.Save(data)
+	if err != nil {
+		this.mark(time.Now())
+	}
+	return err
+}
+
+func (this *autosaver) report(err error) {
+	if err != nil && this.onError != nil {
+		this.onError(err)
+	}
+}
+
+// EnableAutosave saves the instance to store after changes to its friends,
+// self info or conferences, made through this API or by incoming events.
+// The save happens in Iterate once delay passed without further changes, and
+// on Kill. Failed saves are passed to onError, which may be nil, and retried.
+// The first save comes one delay after enabling, so changes made before are
+// saved too.
+func (this *Tox) EnableAutosave(store SaveStore, delay time.Duration, onError func(error)) error {
+	this.lock()
+	defer this.unlock()
+	if this.toxcore == nil {
+		return ErrKilled
+	}
+	this.autosave = &autosaver{store: store, delay: delay, onError: onError}
+	this.autosave.mark(time.Now())
+	this.enableAutosaveCallbacks()
+	return nil
+}
+
+// DisableAutosave stops autosaving, after saving pending changes.
+func (this *Tox) DisableAutosave() error {
+	err := this.FlushAutosave()
+	this.lock()
+	defer this.unlock()
+	if this.autosave == nil {
+		return err
+	}
+	this.autosave = nil
+	if this.toxcore != nil {
+		this.releaseCallbacks(this.autosaveEvents())
+	}
+	return err
+}
+
+// MarkDirty schedules an autosave for changes the instance can't see, e.g.
+// after loading friends from elsewhere.
+func (this *Tox) MarkDirty() {
+	this.lock()
+	defer this.unlock()
+	this.markDirty()
+}
+
+// FlushAutosave saves pending changes now.
+func (this *Tox) FlushAutosave() error {
+	this.lock()
+	as := this.autosave
+	if as == nil || !as.take() {
+		this.unlock()
+		return nil
+	}
+	if this.toxcore == nil {
+		this.unlock()
+		return ErrKilled
+	}
+	data := this.GetSavedata()
+	this.unlock()
+	return as.save(data)
+}
+
+// autosaveEvents are the events that change the savedata, their wrappers mark
+// it dirty. The C callbacks stay registered while autosave is on.
+func (this *Tox) autosaveEvents() []cEvent {
+	return []cEvent{
+		{this.cb_friend_names, func(on bool) {
+			cb := (*C.tox_friend_name_cb)(C.callbackFriendNameWrapperForC)
+			if !on {
+				cb = nil
+			}
+			C.tox_callback_friend_name(this.toxcore, cb)
+		}},
+		{this.cb_friend_status_messages, func(on bool) {
+			cb := (*C.tox_friend_status_message_cb)(C.callbackFriendStatusMessageWrapperForC)
+			if !on {
+				cb = nil
+			}
+			C.tox_callback_friend_status_message(this.toxcore, cb)
+		}},
+		{this.cb_friend_statuss, func(on bool) {
+			cb := (*C.tox_friend_status_cb)(C.callbackFriendStatusWrapperForC)
+			if !on {
+				cb = nil
+			}
+			C.tox_callback_friend_status(this.toxcore, cb)
+		}},
+		{this.cb_friend_connection_statuss, func(on bool) {
+			cb := (*C.tox_friend_connection_status_cb)(C.callbackFriendConnectionStatusWrapperForC)
+			if !on {
+				cb = nil
+			}
+			C.tox_callback_friend_connection_status(this.toxcore, cb)
+		}},
+		{this.cb_conference_titles, func(on bool) {
+			cb := (*C.tox_conference_title_cb)(C.callbackConferenceTitleWrapperForC)
+			if !on {
+				cb = nil
+			}
+			C.tox_callback_conference_title(this.toxcore, cb)
+		}},
+		{this.cb_conference_peer_names, func(on bool) {
+			cb := (*C.tox_conference_peer_name_cb)(C.callbackConferencePeerNameWrapperForC)
+			if !on {
+				cb = nil
+			}
+			C.tox_callback_conference_peer_name(this.toxcore, cb)
+		}},
+		{this.cb_conference_peer_list_changeds, func(on bool) {
+			cb := (*C.tox_conference_peer_list_changed_cb)(C.callbackConferencePeerListChangedWrapperForC)
+			if !on {
+				cb = nil
+			}
+			C.tox_callback_conference_peer_list_changed(this.toxcore, cb)
+		}},
+	}
+}
+
+func (this *Tox) enableAutosaveCallbacks() {
+	for _, ev := range this.autosaveEvents() {
+		ev.setc(true)
+	}
+}
+
+// markDirty is called with the instance locked.
+func (this *Tox) markDirty() {
+	if as := this.autosave; as != nil {
+		as.mark(time.Now())
+	}
+}
+
+func (this *Tox) autosaveIterate() {
+	this.lock()
+	as := this.autosave
+	this.unlock()
+	if as != nil && as.due(time.Now()) {
+		as.report(this.FlushAutosave())
+	}
+}
+
+// autosaveKill is called with the instance locked, before it is killed. It
+// returns the save for Kill to run once it released the lock, so the store
+// and onError may use the instance.
+func (this *Tox) autosaveKill() func() {
+	as := this.autosave
+	if as == nil || !as.take() {
+		return func() {}
+	}
+	data := this.GetSavedata()
+	return func() { as.report(as.save(data)) }
+}
@@ -0,0 +1,367 @@
+package tox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrNoNodes = errors.New("no bootstrap nodes")
+
+// NodeInfo is a node of a nodes.tox.chat style list, e.g.
+// https://nodes.tox.chat/json. An ipv6 of "-" means none.
+type NodeInfo struct {
+	Ipv4       string `json:"ipv4"`
+	Ipv6       string `json:"ipv6"`
+	Port       int    `json:"port"`
+	TcpPorts   []int  `json:"tcp_ports"`
+	PublicKey  string `json:"public_key"`
+	Maintainer string `json:"maintainer"`
+	Location   string `json:"location"`
+	StatusUdp  bool   `json:"status_udp"`
+	StatusTcp  bool   `json:"status_tcp"`
+	LastPing   int64  `json:"last_ping"`
+}
+
+// NodeList is the nodes.tox.chat JSON format.
+type NodeList struct {
+	LastScan    int64      `json:"last_scan"`
+	LastRefresh int64      `json:"last_refresh"`
+	Nodes       []NodeInfo `json:"nodes"`
+}
+
+// ReadNodeList decodes a node list. Nodes with a bad public key are dropped.
+func ReadNodeList(r io.Reader) (*NodeList, error) {
+	list := &NodeList{}
+	if err := json.NewDecoder(r).Decode(list); err != nil {
+		return nil, fmt.Errorf("node list: %w", err)
+	}
+	nodes := list.Nodes[:0]
+	for _, node := range list.Nodes {
+		if _, err := ParsePublicKey(node.PublicKey); err == nil && node.Port > 0 && node.Port <= 0xffff {
+			nodes = append(nodes, node)
+		}
+	}
+	list.Nodes = nodes
+	return list, nil
+}
+
+func LoadNodeList(path string) (*NodeList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadNodeList(f)
+}
+
+// down is true for nodes the list saw offline, lists without status keep
+// all nodes
+func (this *NodeInfo) down() bool {
+	return !this.StatusUdp && !this.StatusTcp && this.LastPing != 0
+}
+
+// relay is true for nodes with TCP ports the list didn't see down
+func (this *NodeInfo) relay() bool {
+	return len(this.TcpPorts) > 0 && (this.StatusTcp || this.LastPing == 0)
+}
+
+// Bootstrapper bootstraps an instance to a random subset of its nodes, and
+// again every Interval while the instance stays offline. Nodes of the
+// attempt that brought the instance online, that took at least one call, are
+// kept in the cache at CachePath, and preferred on the next start.
+type Bootstrapper struct {
+	Count     int           // nodes per attempt, 4 if 0
+	Interval  time.Duration // between attempts while offline, 10s if 0
+	CachePath string        // optional, in the node list format
+
+	t         *Tox
+	mu        sync.Mutex
+	rand      *rand.Rand
+	nodes     []NodeInfo
+	cached    []NodeInfo
+	tried     []NodeInfo
+	worked    []NodeInfo // the tried nodes that took a call
+	lastTry   time.Time
+	wasOnline bool
+}
+
+// at most this many working nodes are cached
+const bootstrapCacheSize = 16
+
+func NewBootstrapper(t *Tox) *Bootstrapper {
+	return &Bootstrapper{t: t, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (this *Bootstrapper) AddNodes(nodes ...NodeInfo) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	for _, node := range nodes {
+		if !node.down() {
+			this.nodes = append(this.nodes, node)
+		}
+	}
+}
+
+// AddBootNodes adds nodes given as addr, port and key, e.g. from a Config.
+// They are used for UDP and as TCP relay on the same port.
+func (this *Bootstrapper) AddBootNodes(nodes ...BootNode) {
+	for _, node := range nodes {
+		this.AddNodes(NodeInfo{Ipv4: node.Addr, Port: node.Port, TcpPorts: []int{node.Port},
+			PublicKey: node.Pubkey, StatusUdp: true, StatusTcp: true})
+	}
+}
+
+// Load adds the nodes of a node list.
+func (this *Bootstrapper) Load(r io.Reader) error {
+	list, err := ReadNodeList(r)
+	if err != nil {
+		return err
+	}
+	this.AddNodes(list.Nodes...)
+	return nil
+}
+
+func (this *Bootstrapper) LoadFile(path string) error {
+	list, err := LoadNodeList(path)
+	if err != nil {
+		return err
+	}
+	this.AddNodes(list.Nodes...)
+	return nil
+}
+
+// Nodes returns the known nodes, the cached ones first.
+func (this *Bootstrapper) Nodes() []NodeInfo {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return append(append([]NodeInfo(nil), this.cached...), this.nodes...)
+}
+
+// LoadCache reads the nodes at CachePath that worked before, if any.
+func (this *Bootstrapper) LoadCache() error {
+	if this.CachePath == "" {
+		return nil
+	}
+	list, err := LoadNodeList(this.CachePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	this.mu.Lock()
+	this.cached = list.Nodes
+	this.mu.Unlock()
+	return nil
+}
+
+// Start loads the cache, bootstraps, and keeps bootstrapping from Iterate
+// while the instance is offline.
+func (this *Bootstrapper) Start() error {
+	if err := this.LoadCache(); err != nil {
+		return err
+	}
+	t := this.t
+	t.lock()
+	t.bootstrapper = this
+	t.unlock()
+
+	_, err := this.Bootstrap()
+	return err
+}
+
+func (this *Bootstrapper) Stop() {
+	t := this.t
+	t.lock()
+	if t.bootstrapper == this {
+		t.bootstrapper = nil
+	}
+	t.unlock()
+}
+
+// Bootstrap bootstraps to Count random nodes, half of them from the cache
+// if possible, over UDP and to their TCP relays. It returns how many nodes
+// took at least one call, and the last error if none did.
+func (this *Bootstrapper) Bootstrap() (int, error) {
+	return this.bootstrap(true)
+}
+
+// BootstrapRelays is Bootstrap without UDP, for when it is blocked. It only
+// adds TCP relays, of twice Count nodes that serve TCP.
+func (this *Bootstrapper) BootstrapRelays() (int, error) {
+	return this.bootstrap(false)
+}
+
+func (this *Bootstrapper) bootstrap(udp bool) (int, error) {
+	nodes := this.pick(udp)
+	if len(nodes) == 0 {
+		return 0, ErrNoNodes
+	}
+
+	ipv6 := this.t.opts == nil || this.t.opts.Ipv6_enabled
+	var lastErr error
+	var worked []NodeInfo
+	for _, node := range nodes {
+		pk, err := ParsePublicKey(node.PublicKey)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var addrs []string
+		if node.Ipv4 != "" && node.Ipv4 != "-" {
+			addrs = append(addrs, node.Ipv4)
+		}
+		if ipv6 && node.Ipv6 != "" && node.Ipv6 != "-" {
+			addrs = append(addrs, node.Ipv6)
+		}
+
+		ok := false
+		for _, addr := range addrs {
+			if udp && (node.StatusUdp || node.LastPing == 0) {
+				if _, err := this.t.BootstrapKey(addr, uint16(node.Port), pk); err != nil {
+					lastErr = fmt.Errorf("%s:%d: %w", addr, node.Port, err)
+				} else {
+					ok = true
+				}
+			}
+			for _, port := range node.TcpPorts {
+				if _, err := this.t.AddTcpRelayKey(addr, uint16(port), pk); err != nil {
+					lastErr = fmt.Errorf("%s:%d: %w", addr, port, err)
+				} else {
+					ok = true
+				}
+			}
+		}
+		if ok {
+			worked = append(worked, node)
+		}
+	}
+
+	this.mu.Lock()
+	this.tried = nodes
+	this.worked = worked
+	this.lastTry = time.Now()
+	this.mu.Unlock()
+	if len(worked) == 0 {
+		if lastErr == nil {
+			lastErr = ErrNoNodes
+		}
+		return 0, lastErr
+	}
+	return len(worked), nil
+}
+
+func (this *Bootstrapper) pick(udp bool) []NodeInfo {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	count := this.Count
+	if count <= 0 {
+		count = 4
+	}
+	if !udp {
+		count *= 2
+	}
+	cached := this.shuffled(this.cached, !udp)
+	if len(cached) > (count+1)/2 {
+		cached = cached[:(count+1)/2]
+	}
+	picked := append([]NodeInfo(nil), cached...)
+	for _, node := range this.shuffled(this.nodes, !udp) {
+		if len(picked) >= count {
+			break
+		}
+		if !containsNode(picked, node.PublicKey) {
+			picked = append(picked, node)
+		}
+	}
+	return picked
+}
+
+// shuffled copies nodes in random order, only the relays if relays is set.
+func (this *Bootstrapper) shuffled(nodes []NodeInfo, relays bool) []NodeInfo {
+	var copied []NodeInfo
+	for _, node := range nodes {
+		if !relays || node.relay() {
+			copied = append(copied, node)
+		}
+	}
+	this.rand.Shuffle(len(copied), func(i, j int) { copied[i], copied[j] = copied[j], copied[i] })
+	return copied
+}
+
+func containsNode(nodes []NodeInfo, pubkey string) bool {
+	for _, node := range nodes {
+		if node.PublicKey == pubkey {
+			return true
+		}
+	}
+	return false
+}
+
+// iterate is called after every Iterate, without the instance locked.
+func (this *Bootstrapper) iterate(online bool, now time.Time) {
+	this.mu.Lock()
+	wasOnline := this.wasOnline
+	this.wasOnline = online
+	retry := !online && now.Sub(this.lastTry) >= this.interval()
+	this.mu.Unlock()
+
+	if online && !wasOnline {
+		this.remember()
+	}
+	if retry {
+		this.Bootstrap()
+	}
+}
+
+func (this *Bootstrapper) interval() time.Duration {
+	if this.Interval <= 0 {
+		return 10 * time.Second
+	}
+	return this.Interval
+}
+
+// remember moves the nodes of the last attempt that took a call to the front
+// of the cache.
+func (this *Bootstrapper) remember() {
+	this.mu.Lock()
+	var cached []NodeInfo
+	for _, nodes := range [][]NodeInfo{this.worked, this.cached} {
+		for _, node := range nodes {
+			if len(cached) < bootstrapCacheSize && !containsNode(cached, node.PublicKey) {
+				cached = append(cached, node)
+			}
+		}
+	}
+	this.cached = cached
+	this.mu.Unlock()
+
+	if this.CachePath != "" {
+		data, err := json.MarshalIndent(&NodeList{LastRefresh: time.Now().Unix(), Nodes: cached}, "", "  ")
+		if err == nil {
+			err = writeFileAtomic(this.CachePath, data)
+		}
+		if err != nil {
+			log.Println("bootstrap cache:", err)
+		}
+	}
+}
+
+func (this *Tox) bootstrapIterate() {
+	this.lock()
+	b := this.bootstrapper
+	if b == nil || this.toxcore == nil {
+		this.unlock()
+		return
+	}
+	online := this.SelfGetConnectionStatus() != CONNECTION_NONE
+	this.unlock()
+	b.iterate(online, time.Now())
+}
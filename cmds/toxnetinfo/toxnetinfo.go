@@ -0,0 +1,171 @@
+package main
+
+//  network diagnostics of a running bot, or of a profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TokTok/go-toxcore-c"
+)
+
+func init() {
+	log.SetFlags(log.Flags() ^ log.Ldate ^ log.Ltime)
+}
+
+var format = "table"
+var wait = 30 * time.Second
+var nodesFile string
+var url string
+
+func printHelp() {
+	log.Println("Usage: toxnetinfo [options] -url <url>")
+	log.Println("       toxnetinfo [options] -savedata <tsfile>")
+	log.Println("With -url, prints the network state a running bot serves with")
+	log.Println("tox.NetworkInfoHandler, e.g. toxecho -netinfo.")
+	log.Println("With -savedata, runs the profile offline from any bot until it is online or")
+	log.Println("-wait passed, and prints the state of that instance. The profile is not saved.")
+	log.Println("Stop the bot using it first, or use a copy, so it isn't online twice.")
+	log.Println("For help: /path/to/toxnetinfo -h")
+}
+
+func main() {
+	cfg, err := tox.LoadConfig("")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	cfg.BindFlags(flag.CommandLine)
+	flag.StringVar(&format, "format", format, "output format: json or table")
+	flag.DurationVar(&wait, "wait", wait, "time to wait for the connection")
+	flag.StringVar(&nodesFile, "nodes", nodesFile, "node list in the nodes.tox.chat JSON format")
+	flag.StringVar(&url, "url", url, "NetworkInfoHandler of a running bot")
+	flag.Parse()
+	if (url == "") == (cfg.Savedata == "") || flag.NArg() != 0 {
+		printHelp()
+		flag.Usage()
+		os.Exit(2)
+	}
+	if format != "json" && format != "table" {
+		log.Fatalln("unknown format:", format)
+	}
+
+	var info *tox.NetworkInfo
+	if url != "" {
+		info, err = fetch(url)
+	} else {
+		info, err = probe(cfg)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(info)
+	} else {
+		err = writeTable(os.Stdout, info, time.Now())
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// fetch queries a running bot.
+func fetch(url string) (*tox.NetworkInfo, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s: %s %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+	info := &tox.NetworkInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	return info, nil
+}
+
+// probe runs a separate instance of the profile, so it only shows what that
+// instance reaches, not the bot.
+func probe(cfg *tox.Config) (*tox.NetworkInfo, error) {
+	if _, err := os.Stat(cfg.Savedata); err != nil {
+		return nil, err
+	}
+	profile, err := cfg.OpenProfile()
+	if err != nil {
+		return nil, err
+	}
+	profile.Close()
+	t, err := tox.NewToxWithError(profile.Options)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Kill()
+
+	boot := tox.NewBootstrapper(t)
+	boot.AddBootNodes(cfg.Bootstrap...)
+	if nodesFile != "" {
+		if err := boot.LoadFile(nodesFile); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := boot.Bootstrap(); err != nil {
+		log.Println("bootstrap:", err)
+	}
+
+	start := time.Now()
+	for time.Since(start) < wait && t.SelfGetConnectionStatus() == tox.CONNECTION_NONE {
+		t.Iterate()
+		time.Sleep(time.Duration(t.IterationInterval()) * time.Millisecond)
+	}
+	return t.NetworkInfo()
+}
+
+func writeTable(w io.Writer, info *tox.NetworkInfo, now time.Time) error {
+	since := func(tm time.Time) string { return now.Sub(tm).Round(time.Second).String() }
+	port := func(p uint16) string {
+		if p == 0 {
+			return "-"
+		}
+		return fmt.Sprint(p)
+	}
+	proxy := info.ProxyType
+	if info.ProxyHost != "" {
+		proxy = fmt.Sprintf("%s %s:%d", info.ProxyType, info.ProxyHost, info.ProxyPort)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "address\t%s\n", info.Address)
+	fmt.Fprintf(tw, "dht id\t%s\n", info.DhtId)
+	fmt.Fprintf(tw, "udp port\t%s\n", port(info.UdpPort))
+	fmt.Fprintf(tw, "tcp port\t%s\n", port(info.TcpPort))
+	fmt.Fprintf(tw, "connection\t%s, for %s\n", info.Connection, since(info.ConnectionSince))
+	fmt.Fprintf(tw, "ipv6\t%v\n", info.Ipv6)
+	fmt.Fprintf(tw, "udp\t%v\n", info.Udp)
+	fmt.Fprintf(tw, "local discovery\t%v\n", info.LocalDiscovery)
+	fmt.Fprintf(tw, "hole punching\t%v\n", info.HolePunching)
+	fmt.Fprintf(tw, "proxy\t%s\n", proxy)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FRIEND\tPUBLIC KEY\tCONNECTION\tFOR")
+	for _, f := range info.Friends {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", f.Number, f.PublicKey, f.Connection, since(f.Since))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,238 @@
+package main
+
+//  bootstrap node health prober
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TokTok/go-toxcore-c"
+)
+
+func init() {
+	log.SetFlags(log.Flags() ^ log.Ldate ^ log.Ltime)
+}
+
+var format = "table"
+var timeout = 30 * time.Second
+var parallel = 8
+var probeIpv6 = false
+var probeTcp = true
+
+func printHelp() {
+	log.Println("Usage: toxprobe [options] <nodes file>")
+	log.Println("The nodes file is a nodes.tox.chat JSON list, or `addr port pubkey` lines;")
+	log.Println("- reads it from stdin.")
+	log.Println("For help: /path/to/toxprobe -h")
+}
+
+// probeResult is one transport of one node. Seconds is the time until the
+// probe instance was online.
+type probeResult struct {
+	Addr      string  `json:"addr"`
+	Port      int     `json:"port"`
+	PublicKey string  `json:"public_key"`
+	Transport string  `json:"transport"`
+	Online    bool    `json:"online"`
+	Seconds   float64 `json:"seconds,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type probe struct {
+	addr string
+	port int
+	pk   tox.PublicKey
+	tcp  bool
+}
+
+func main() {
+	flag.StringVar(&format, "format", format, "output format: json or table")
+	flag.DurationVar(&timeout, "timeout", timeout, "time a node gets to bring us online")
+	flag.IntVar(&parallel, "parallel", parallel, "nodes probed at once")
+	flag.BoolVar(&probeIpv6, "ipv6", probeIpv6, "also probe the IPv6 addresses of JSON lists")
+	flag.BoolVar(&probeTcp, "tcp", probeTcp, "also probe the TCP relay ports")
+	flag.Parse()
+	if flag.NArg() != 1 || parallel < 1 {
+		printHelp()
+		flag.Usage()
+		os.Exit(2)
+	}
+	if format != "json" && format != "table" {
+		log.Fatalln("unknown format:", format)
+	}
+
+	var r io.Reader = os.Stdin
+	if flag.Arg(0) != "-" {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		r = f
+	}
+	nodes, err := readNodes(r)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	probes := probesOf(nodes)
+	results := make([]probeResult, len(probes))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := range probes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			results[i] = run(probes[i])
+		}(i)
+	}
+	wg.Wait()
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(results)
+	} else {
+		err = writeTable(os.Stdout, results)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// readNodes takes a JSON node list or `addr port pubkey` lines, where a
+// node's port is used for UDP and TCP.
+func readNodes(r io.Reader) ([]tox.NodeInfo, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		list, err := tox.ReadNodeList(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return list.Nodes, nil
+	}
+
+	var nodes []tox.NodeInfo
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; sc.Scan(); lineno++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: want addr port pubkey", lineno)
+		}
+		port, err := strconv.ParseUint(fields[1], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad port %q", lineno, fields[1])
+		}
+		if _, err := tox.ParsePublicKey(fields[2]); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		nodes = append(nodes, tox.NodeInfo{Ipv4: fields[0], Port: int(port), TcpPorts: []int{int(port)},
+			PublicKey: fields[2]})
+	}
+	return nodes, sc.Err()
+}
+
+func probesOf(nodes []tox.NodeInfo) []probe {
+	var probes []probe
+	for _, node := range nodes {
+		pk, err := tox.ParsePublicKey(node.PublicKey)
+		if err != nil {
+			continue
+		}
+		addrs := []string{node.Ipv4}
+		if probeIpv6 && node.Ipv6 != "" && node.Ipv6 != "-" {
+			addrs = append(addrs, node.Ipv6)
+		}
+		for _, addr := range addrs {
+			if addr == "" || addr == "-" {
+				continue
+			}
+			probes = append(probes, probe{addr, node.Port, pk, false})
+			if !probeTcp {
+				continue
+			}
+			for _, port := range node.TcpPorts {
+				probes = append(probes, probe{addr, port, pk, true})
+			}
+		}
+	}
+	return probes
+}
+
+// run bootstraps a throwaway instance to the node alone and waits until it
+// is online. TCP probes disable UDP, so the relay is the only way in.
+func run(p probe) probeResult {
+	res := probeResult{Addr: p.addr, Port: p.port, PublicKey: p.pk.String(), Transport: "udp"}
+	if p.tcp {
+		res.Transport = "tcp"
+	}
+
+	opt := tox.NewToxOptions(
+		tox.WithIPv6(probeIpv6),
+		tox.WithUDP(!p.tcp),
+		tox.WithLocalDiscovery(false),
+		tox.WithHolePunching(false),
+	)
+	t, err := tox.NewToxWithError(opt)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer t.Kill()
+
+	start := time.Now()
+	if p.tcp {
+		_, err = t.AddTcpRelayKey(p.addr, uint16(p.port), p.pk)
+	} else {
+		_, err = t.BootstrapKey(p.addr, uint16(p.port), p.pk)
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	for time.Since(start) < timeout {
+		t.Iterate()
+		if t.SelfGetConnectionStatus() != tox.CONNECTION_NONE {
+			res.Online = true
+			res.Seconds = time.Since(start).Seconds()
+			return res
+		}
+		time.Sleep(time.Duration(t.IterationInterval()) * time.Millisecond)
+	}
+	res.Error = "timeout"
+	return res
+}
+
+func writeTable(w io.Writer, results []probeResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDR\tPORT\tTRANSPORT\tONLINE\tTIME\tPUBLIC KEY\tERROR")
+	for _, res := range results {
+		tm := "-"
+		if res.Online {
+			tm = fmt.Sprintf("%.1fs", res.Seconds)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%v\t%s\t%s\t%s\n",
+			res.Addr, res.Port, res.Transport, res.Online, tm, res.PublicKey, res.Error)
+	}
+	return tw.Flush()
+}
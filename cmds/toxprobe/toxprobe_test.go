@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TokTok/go-toxcore-c"
+)
+
+const testKey = "F404ABAA1C99A9D37D61AB54898F56793E1DEF8BD46B1038B9D822E8460FAB67"
+
+func TestReadNodes(t *testing.T) {
+	json := `{"nodes": [
+		{"ipv4": "1.2.3.4", "ipv6": "::1", "port": 33445, "tcp_ports": [443, 3389], "public_key": "` + testKey + `"},
+		{"ipv4": "5.6.7.8", "port": 33445, "public_key": "bad"}
+	]}`
+	nodes, err := readNodes(strings.NewReader(json))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Ipv6 != "::1" || len(nodes[0].TcpPorts) != 2 {
+		t.Errorf("must json nodes %+v", nodes)
+	}
+
+	lines := "# comment\n\n1.2.3.4 33445 " + testKey + "\n  # indented\nexample.org 443 " + testKey + "\n"
+	nodes, err = readNodes(strings.NewReader(lines))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[1].Ipv4 != "example.org" || nodes[1].Port != 443 ||
+		len(nodes[1].TcpPorts) != 1 || nodes[1].TcpPorts[0] != 443 {
+		t.Errorf("must line nodes %+v", nodes)
+	}
+
+	for _, bad := range []string{
+		"1.2.3.4 port " + testKey,
+		"1.2.3.4 65536 " + testKey,
+		"1.2.3.4 33445 abcd",
+		"1.2.3.4 33445",
+	} {
+		if _, err := readNodes(strings.NewReader("# ok\n" + bad)); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Error("must fail", bad, err)
+		}
+	}
+}
+
+func TestProbesOf(t *testing.T) {
+	defer func(ipv6, tcp bool) { probeIpv6, probeTcp = ipv6, tcp }(probeIpv6, probeTcp)
+	nodes := []tox.NodeInfo{
+		{Ipv4: "1.2.3.4", Ipv6: "::1", Port: 33445, TcpPorts: []int{443, 3389}, PublicKey: testKey},
+		{Ipv4: "-", Ipv6: "-", Port: 33445, PublicKey: testKey},
+		{Ipv4: "5.6.7.8", Port: 33445, PublicKey: "bad"},
+	}
+
+	for _, c := range []struct {
+		ipv6, tcp bool
+		want      []string
+	}{
+		{false, false, []string{"udp 1.2.3.4:33445"}},
+		{false, true, []string{"udp 1.2.3.4:33445", "tcp 1.2.3.4:443", "tcp 1.2.3.4:3389"}},
+		{true, false, []string{"udp 1.2.3.4:33445", "udp ::1:33445"}},
+		{true, true, []string{"udp 1.2.3.4:33445", "tcp 1.2.3.4:443", "tcp 1.2.3.4:3389",
+			"udp ::1:33445", "tcp ::1:443", "tcp ::1:3389"}},
+	} {
+		probeIpv6, probeTcp = c.ipv6, c.tcp
+		var got []string
+		for _, p := range probesOf(nodes) {
+			transport := "udp"
+			if p.tcp {
+				transport = "tcp"
+			}
+			got = append(got, transport+" "+p.addr+":"+strconv.Itoa(p.port))
+			if p.pk.String() != testKey {
+				t.Error("must key", p.pk)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("ipv6=%v tcp=%v: got %v, want %v", c.ipv6, c.tcp, got, c.want)
+		}
+	}
+}
+
+// TestRun probes a node on 127.0.0.1.
+func TestRun(t *testing.T) {
+	defer func(d time.Duration) { timeout = d }(timeout)
+	timeout = 30 * time.Second
+
+	node := tox.NewTox(tox.NewToxOptions(tox.WithIPv6(false), tox.WithLocalDiscovery(false)))
+	if node == nil {
+		t.Fatal("must node")
+	}
+	defer node.Kill()
+	// read before the node is iterated, it isn't ThreadSafe
+	port, err := node.SelfGetUdpPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dhtid, err := tox.ParsePublicKey(node.SelfGetDhtId())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Duration(node.IterationInterval()) * time.Millisecond):
+				node.Iterate()
+			}
+		}
+	}()
+	defer func() { close(done); <-stopped }()
+
+	res := run(probe{"127.0.0.1", int(port), dhtid, false})
+	if !res.Online || res.Error != "" || res.Transport != "udp" || res.PublicKey != dhtid.String() {
+		t.Errorf("must online %+v", res)
+	}
+
+	timeout = 2 * time.Second
+	res = run(probe{"127.0.0.1", 1, dhtid, false})
+	if res.Online || res.Error != "timeout" {
+		t.Errorf("must timeout %+v", res)
+	}
+}
@@ -3,10 +3,16 @@ package main
 //  tox save data decrypt/encrypt
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
+	"strings"
 
 	"github.com/TokTok/go-toxcore-c"
 )
@@ -15,131 +21,205 @@ func init() {
 	log.SetFlags(log.Flags() ^ log.Ldate ^ log.Ltime)
 }
 
-var tsfile string = "tox_save.tox"
 var pass string
+var passfile string
+var newpassfile string
 var tofile string = "./tsdec.bin"
 
-var crypt_mode string // enc/dec
-var decrypt_mode = false
-var encrypt_mode = false
-
 func printHelp() {
-	log.Println("For help: /path/to/tsdec [options] <tsfile> -h")
+	fmt.Fprintln(os.Stderr, `Usage: tsdec [options] <command> <tsfile>
+       tsdec [options] <tsfile>
+
+Commands:
+  decrypt  write the decrypted tsfile to -tofile
+  encrypt  write the encrypted tsfile to -tofile
+  passwd   change the passphrase of an encrypted tsfile, in place unless -tofile is given
+  verify   check the passphrase of an encrypted tsfile, writes nothing
+
+Without a command, an encrypted tsfile is decrypted and a plain one encrypted.
+A tsfile or -tofile of "-" means stdin or stdout. Passphrases are read from
+-passfile/-newpassfile, or prompted for on the terminal.
+
+Options:`)
+	flag.PrintDefaults()
 }
 
 func main() {
-	// flag.StringVar(&tsfile, "tsfile", "", "tox save data file")
-	flag.StringVar(&pass, "pass", pass, "tox save data password")
+	flag.StringVar(&pass, "pass", pass, "tox save data password (deprecated, visible in ps, use -passfile)")
+	flag.StringVar(&passfile, "passfile", passfile, "read the passphrase from this file")
+	flag.StringVar(&newpassfile, "newpassfile", newpassfile, "read the new passphrase for passwd from this file")
 	flag.StringVar(&tofile, "tofile", tofile, "result file")
-
+	flag.Usage = printHelp
 	flag.Parse()
-	// log.Println(flag.Args())
-	if len(flag.Args()) < 1 {
-		printHelp()
+
+	args := flag.Args()
+	cmd := ""
+	if len(args) == 2 {
+		cmd, args = args[0], args[1:]
+	}
+	if len(args) != 1 {
 		flag.Usage()
-		return
+		os.Exit(2)
 	}
-	tsfile = flag.Arg(0)
+	tsfile := args[0]
+	tofileSet := false
+	flag.Visit(func(f *flag.Flag) { tofileSet = tofileSet || f.Name == "tofile" })
 
-	data, err := ioutil.ReadFile(tsfile)
+	data, err := readInput(tsfile)
 	if err != nil {
-		log.Println(err)
-		return
+		log.Fatalln(err)
+	}
+	if len(data) == 0 {
+		log.Fatalln("empty tox save data:", tsfile)
 	}
-
 	isencrypt := tox.IsDataEncrypted(data)
-	log.Println("Is encrypt: ", isencrypt)
-	if isencrypt && pass == "" {
-		log.Println("Need -pass argument.")
-		flag.Usage()
-		return
+	if cmd == "" {
+		cmd = "encrypt"
+		if isencrypt {
+			cmd = "decrypt"
+		}
 	}
 
-	if isencrypt {
-		ok, err, salt := tox.GetSalt(data)
-		if err != nil {
-			log.Println(ok, err, len(salt), salt)
-		}
-		pkey, err := tox.DeriveWithSalt([]byte(pass), salt)
-		defer pkey.Free()
-		if err != nil {
-			log.Println(err)
+	switch cmd {
+	case "decrypt", "verify", "passwd":
+		if !isencrypt {
+			log.Fatalln("not encrypted:", tsfile)
 		}
-		ok, err, datad := pkey.Decrypt(data)
-		if err != nil {
-			// log.Println(ok, err, len(datad), datad[0:32])
-			log.Println("Decrypt error, check your -pass:", err)
-			return
+	case "encrypt":
+		if isencrypt {
+			log.Fatalln("already encrypted:", tsfile)
 		}
+	default:
+		log.Println("unknown command:", cmd)
+		flag.Usage()
+		os.Exit(2)
+	}
 
-		data = datad
-	}
-
-	opts := tox.NewToxOptions()
-	opts.Savedata_type = tox.SAVEDATA_TYPE_TOX_SAVE
-	opts.Savedata_data = data
-	t := tox.NewTox(opts)
-	fnums := t.SelfGetFriendList()
-	log.Println(fnums, t)
-	log.Println("Self Name:", t.SelfGetName())
-	log.Println("Self ID:", t.SelfGetAddress())
-	mystmsg, err := t.SelfGetStatusMessage()
-	log.Println("Status:", mystmsg)
-	log.Println("------------------------------------------")
-	log.Println("Friend Count:", len(fnums))
-
-	if tox.FileExist(tofile) {
-		log.Println(os.ErrExist, tofile)
-		// return
-	}
-
-	if isencrypt { // do decrypt
-		log.Println("Decrypting...")
-		err := t.WriteSavedata(tofile)
-		if err != nil {
-			log.Println(err)
-		} else {
-			log.Println("Save decrypted data OK: ", tofile)
+	var out []byte
+	switch cmd {
+	case "decrypt", "verify":
+		out, err = decrypt(data, oldPassphrase())
+		if err == nil && cmd == "verify" {
+			log.Println("Passphrase OK:", tsfile)
+			return
 		}
-	} else { // do encrypt
-		log.Println("Encrypting...")
-
-		// first time, there is no salt
-		salt := make([]byte, tox.PASS_KEY_LENGTH)
-		_, err, salt := tox.GetSalt(data)
-		if err != nil {
-			log.Println(err, "GetSalt")
-			// return
+	case "encrypt":
+		passphrase := []byte(pass)
+		if pass == "" {
+			passphrase = newPassphrase(passfile)
 		}
-		_ = salt
-
-		// pakey, err = tox.DeriveWithSalt([]byte(pass), salt)
-		pakey, err := tox.Derive([]byte(pass))
-		defer pakey.Free()
-		if err != nil {
-			log.Println(err)
-			return
+		out, err = encrypt(data, passphrase)
+	case "passwd":
+		out, err = decrypt(data, oldPassphrase())
+		if err == nil {
+			out, err = encrypt(out, newPassphrase(newpassfile))
 		}
-		_, err, encdata := pakey.Encrypt(data)
-		if err != nil {
-			log.Println(err)
-			return
+		if !tofileSet {
+			tofile = tsfile
 		}
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := writeOutput(tofile, out); err != nil {
+		log.Fatalln(err)
+	}
+	if tofile != "-" {
+		log.Println("Save OK:", tofile)
+	}
+}
 
-		err = ioutil.WriteFile(tofile, encdata, 0755)
-		if err != nil {
-			log.Println(err)
-		} else {
-			log.Println("Save encrypted data OK: ", tofile)
-		}
+func encrypt(data []byte, passphrase []byte) ([]byte, error) {
+	// a fresh salt per encryption, like tox_pass_encrypt does
+	pkey, err := tox.Derive(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer pkey.Free()
+	_, err, encdata := pkey.Encrypt(data)
+	return encdata, err
+}
+
+func decrypt(data []byte, passphrase []byte) ([]byte, error) {
+	plain, err := tox.PassDecrypt(data, passphrase)
+	if errors.Is(err, tox.ErrDecryptionFailed) {
+		return nil, fmt.Errorf("wrong passphrase: %w", err)
+	}
+	return plain, err
+}
+
+func oldPassphrase() []byte {
+	if pass != "" {
+		return []byte(pass)
+	}
+	if passfile != "" {
+		return mustPassphrase(tox.ReadPassphraseFile(passfile))
+	}
+	return mustPassphrase(promptPassphrase("Passphrase: "))
+}
+
+// newPassphrase prompts twice when there is no file to read it from.
+func newPassphrase(file string) []byte {
+	if file != "" {
+		return mustPassphrase(tox.ReadPassphraseFile(file))
+	}
+	p1 := mustPassphrase(promptPassphrase("New passphrase: "))
+	p2 := mustPassphrase(promptPassphrase("Repeat new passphrase: "))
+	if !bytes.Equal(p1, p2) {
+		log.Fatalln("passphrases do not match")
+	}
+	return p1
+}
+
+func mustPassphrase(p []byte, err error) []byte {
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(p) == 0 {
+		log.Fatalln("empty passphrase")
 	}
+	return p
 }
 
-func encrypt() {
+// promptPassphrase reads from the terminal with echo off, so it also works
+// when stdin carries the save data.
+func promptPassphrase(prompt string) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("no terminal to prompt for the passphrase, use -passfile: %w", err)
+	}
+	defer tty.Close()
 
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = tty
+		return cmd.Run()
+	}
+	fmt.Fprint(tty, prompt)
+	if err := stty("-echo"); err != nil {
+		return nil, err
+	}
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	stty("echo")
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
 }
 
-// @param pt plain tox
-func decrypt(pt *tox.Tox) {
+func readInput(file string) ([]byte, error) {
+	if file == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(file)
+}
 
+func writeOutput(file string, data []byte) error {
+	if file == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	// atomic, so readers never see a partial profile
+	return (&tox.FileStore{Path: file}).Save(data)
 }
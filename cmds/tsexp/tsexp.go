@@ -3,13 +3,20 @@ package main
 //  tox save data explorer
 
 import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/TokTok/go-toxcore-c"
+	"github.com/TokTok/go-toxcore-c/savedata"
 )
 
 func init() {
@@ -18,14 +25,51 @@ func init() {
 
 var tsfile string
 var pass string
+var format = "table"
+var outfile string
 
 func printHelp() {
+	log.Println("Usage: tsexp [options] <tsfile>")
+	log.Println("       tsexp [options] repair <tsfile>")
+	log.Println("       tsexp [options] diff <old tsfile> <new tsfile>")
 	log.Println("For help: /path/to/rsexp -h")
 }
 
+type selfInfo struct {
+	Address       tox.Address `json:"address"`
+	Nospam        tox.Nospam  `json:"nospam"`
+	Name          string      `json:"name"`
+	StatusMessage string      `json:"status_message"`
+}
+
+type friendInfo struct {
+	Number           uint32             `json:"number"`
+	PublicKey        tox.PublicKey      `json:"public_key"`
+	Name             string             `json:"name"`
+	StatusMessage    string             `json:"status_message"`
+	LastOnline       *time.Time         `json:"last_online"`
+	ConnectionStatus tox.ConnectionType `json:"connection_status"`
+}
+
+type conferenceInfo struct {
+	Number uint32            `json:"number"`
+	ID     string            `json:"id"`
+	Type   tox.GroupchatType `json:"type"`
+	Title  string            `json:"title"`
+	Peers  int               `json:"peers"`
+}
+
+type profileInfo struct {
+	Self        selfInfo         `json:"self"`
+	Friends     []friendInfo     `json:"friends"`
+	Conferences []conferenceInfo `json:"conferences"`
+}
+
 func main() {
 	// flag.StringVar(&tsfile, "tsfile", "", "tox save data file")
 	flag.StringVar(&pass, "pass", pass, "tox save data password")
+	flag.StringVar(&format, "format", format, "output format: json, csv or table")
+	flag.StringVar(&outfile, "out", outfile, "repair: result file, default <tsfile>.repaired")
 	flag.Parse()
 	// log.Println(flag.Args())
 	if len(flag.Args()) < 1 {
@@ -34,67 +78,222 @@ func main() {
 		return
 	}
 	tsfile = flag.Arg(0)
+	if tsfile == "repair" && flag.NArg() == 2 {
+		repair(flag.Arg(1))
+		return
+	}
+	if tsfile == "diff" && flag.NArg() == 3 {
+		diff(flag.Arg(1), flag.Arg(2))
+		return
+	}
+
+	var write func(io.Writer, *profileInfo) error
+	switch format {
+	case "json":
+		write = writeJSON
+	case "csv":
+		write = writeCSV
+	case "table":
+		write = writeTable
+	default:
+		log.Fatalln("unknown format:", format)
+	}
 
-	data, err := ioutil.ReadFile(tsfile)
+	sd := load(tsfile)
+	for _, sec := range sd.Unknown {
+		log.Printf("unknown section type %d, %d bytes", sec.Type, len(sec.Data))
+	}
+	if err := write(os.Stdout, newProfileInfo(sd)); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// load decrypts tsfile with -pass if needed and parses it.
+func load(tsfile string) *savedata.Savedata {
+	if _, err := os.Stat(tsfile); err != nil {
+		log.Fatalln(err)
+	}
+	profile, err := tox.OpenProfile(tsfile, []byte(pass))
 	if err != nil {
-		log.Println(err)
-		return
+		log.Fatalln("Open error, check your -pass:", err)
+	}
+	profile.Close()
+
+	sd, err := savedata.Parse(profile.Options.Savedata_data)
+	if err != nil {
+		log.Fatalln(tsfile+":", err, "(try: tsexp repair)")
+	}
+	return sd
+}
+
+// diff prints the changes from afile to bfile, and exits with 1 if there
+// are any, like diff(1).
+func diff(afile, bfile string) {
+	changes := savedata.Diff(load(afile), load(bfile))
+	if format == "json" {
+		if changes == nil {
+			changes = []savedata.Change{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(changes); err != nil {
+			log.Fatalln(err)
+		}
+	} else {
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+// repair writes what savedata.Repair salvages from tsfile to -out, encrypted
+// again if tsfile was.
+func repair(tsfile string) {
+	profile, err := tox.OpenProfile(tsfile, []byte(pass))
+	if err != nil {
+		log.Fatalln("Open error, check your -pass:", err)
+	}
+	encrypted := profile.Encrypted()
+	profile.Close()
+
+	sd, problems, err := savedata.Repair(profile.Options.Savedata_data)
+	for _, problem := range problems {
+		log.Println("lost:", problem)
+	}
+	if err != nil {
+		log.Fatalln(err)
 	}
-	isencrypt := tox.IsDataEncrypted(data)
-	log.Println("Is encrypt: ", isencrypt)
-	if isencrypt {
-		ok, err, salt := tox.GetSalt(data)
-		if err != nil {
-			log.Println(ok, err, len(salt), salt)
+	data, err := sd.Marshal()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if encrypted {
+		if data, err = tox.PassEncrypt(data, []byte(pass)); err != nil {
+			log.Fatalln(err)
 		}
-		pkey, err := tox.DeriveWithSalt([]byte(pass), salt)
-		defer pkey.Free()
-		if err != nil {
-			log.Println(err)
+	}
+
+	if outfile == "" {
+		outfile = tsfile + ".repaired"
+	}
+	if err := (&tox.FileStore{Path: outfile}).Save(data); err != nil {
+		log.Fatalln(err)
+	}
+	log.Printf("Repaired: %d friends, %d conferences kept, %d problems, saved to %s",
+		len(sd.Friends), len(sd.Conferences), len(problems), outfile)
+}
+
+func newProfileInfo(sd *savedata.Savedata) *profileInfo {
+	info := &profileInfo{
+		Self: selfInfo{
+			Address:       tox.NewAddress(tox.PublicKey(sd.PublicKey), tox.Nospam(sd.Nospam)),
+			Nospam:        tox.Nospam(sd.Nospam),
+			Name:          sd.Name,
+			StatusMessage: sd.StatusMessage,
+		},
+		Friends:     []friendInfo{},
+		Conferences: []conferenceInfo{},
+	}
+	for i, f := range sd.Friends {
+		fi := friendInfo{
+			Number:        uint32(i),
+			PublicKey:     tox.PublicKey(f.PublicKey),
+			Name:          f.Name,
+			StatusMessage: f.StatusMessage,
+			// a profile on disk is never connected
+			ConnectionStatus: tox.CONNECTION_NONE,
 		}
-		ok, err, datad := pkey.Decrypt(data)
-		if err != nil {
-			// log.Println(ok, err, len(datad), datad[0:32])
-			log.Println("Decrypt error, check your -pass:", err)
-			return
+		if !f.LastSeenTime().IsZero() {
+			tm := f.LastSeenTime().UTC()
+			fi.LastOnline = &tm
 		}
+		info.Friends = append(info.Friends, fi)
+	}
+	for i, conf := range sd.Conferences {
+		info.Conferences = append(info.Conferences, conferenceInfo{
+			Number: uint32(i),
+			ID:     strings.ToUpper(hex.EncodeToString(conf.ID[:])),
+			Type:   tox.GroupchatType(conf.Type),
+			Title:  conf.Title,
+			Peers:  len(conf.Peers),
+		})
+	}
+	return info
+}
 
-		data = datad
-	}
-
-	opts := tox.NewToxOptions()
-	opts.Savedata_type = tox.SAVEDATA_TYPE_TOX_SAVE
-	opts.Savedata_data = data
-	t := tox.NewTox(opts)
-	fnums := t.SelfGetFriendList()
-	log.Println("Self Name:", t.SelfGetName())
-	log.Println("Self ID:", t.SelfGetAddress())
-	mystmsg, err := t.SelfGetStatusMessage()
-	log.Println("Status:", mystmsg)
-	log.Println("------------------------------------------")
-	log.Println("Friend Count:", len(fnums))
-
-	if len(fnums) > 0 {
-		log.Println("num\tname\tID\tseen\tstatus\tstmsg")
-	}
-	for i := 0; i < len(fnums); i++ {
-		pubkey, err := t.FriendGetPublicKey(fnums[i])
-		tm, err := t.FriendGetLastOnline(fnums[i])
-		fname, err := t.FriendGetName(fnums[i])
-		stmsg, err := t.FriendGetStatusMessage(fnums[i])
-		status, err := t.FriendGetConnectionStatus(fnums[i])
-		if err != nil {
-			log.Println("wtf", i)
-		} else {
-			otm := time.Unix(int64(tm), 0)
-			log.Println(fmt.Sprintf("Friend %d: ", fnums[i]),
-				fname, pubkey, otm, tox.ConnStatusString(status), stmsg)
+func lastOnline(tm *time.Time) string {
+	if tm == nil {
+		return "never"
+	}
+	return tm.Format(time.RFC3339)
+}
+
+func writeJSON(w io.Writer, info *profileInfo) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(info)
+}
+
+// writeCSV writes self, friends and conferences as three tables, each with
+// its own header line and separated by an empty line.
+func writeCSV(w io.Writer, info *profileInfo) error {
+	tables := [][][]string{
+		{
+			{"address", "nospam", "name", "status_message"},
+			{info.Self.Address.String(), info.Self.Nospam.String(), info.Self.Name, info.Self.StatusMessage},
+		},
+		{{"number", "public_key", "name", "status_message", "last_online", "connection_status"}},
+		{{"number", "id", "type", "title", "peers"}},
+	}
+	for _, f := range info.Friends {
+		tables[1] = append(tables[1], []string{fmt.Sprint(f.Number), f.PublicKey.String(), f.Name,
+			f.StatusMessage, lastOnline(f.LastOnline), f.ConnectionStatus.String()})
+	}
+	for _, c := range info.Conferences {
+		tables[2] = append(tables[2], []string{fmt.Sprint(c.Number), c.ID, c.Type.String(), c.Title, fmt.Sprint(c.Peers)})
+	}
+
+	for i, table := range tables {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(table); err != nil {
+			return err
 		}
 	}
-	if len(fnums) > 20 {
-		log.Println("Friend Count:", len(fnums))
+	return nil
+}
+
+func writeTable(w io.Writer, info *profileInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Self Name:\t"+info.Self.Name)
+	fmt.Fprintln(tw, "Self ID:\t"+info.Self.Address.String())
+	fmt.Fprintln(tw, "Nospam:\t"+info.Self.Nospam.String())
+	fmt.Fprintln(tw, "Status:\t"+info.Self.StatusMessage)
+	fmt.Fprintln(tw)
+
+	fmt.Fprintf(tw, "Friend Count: %d\n", len(info.Friends))
+	if len(info.Friends) > 0 {
+		fmt.Fprintln(tw, "NUM\tNAME\tPUBLIC KEY\tLAST ONLINE\tSTATUS\tSTATUS MESSAGE")
+	}
+	for _, f := range info.Friends {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", f.Number, f.Name, f.PublicKey,
+			lastOnline(f.LastOnline), f.ConnectionStatus, f.StatusMessage)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintf(tw, "Conference Count: %d\n", len(info.Conferences))
+	if len(info.Conferences) > 0 {
+		fmt.Fprintln(tw, "NUM\tTYPE\tPEERS\tID\tTITLE")
 	}
-	if len(fnums) > 0 {
-		log.Println()
+	for _, c := range info.Conferences {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", c.Number, c.Type, c.Peers, c.ID, c.Title)
 	}
+	return tw.Flush()
 }
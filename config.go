@@ -0,0 +1,489 @@
+package tox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config is the deployment side of ToxOptions: network settings, the profile
+// and its passphrase file, and bootstrap nodes. It is read from a JSON or
+// TOML file, then TOX_* environment variables, then command line flags, each
+// overriding the previous one.
+type Config struct {
+	Ipv6           bool       `json:"ipv6"`
+	Udp            bool       `json:"udp"`
+	LocalDiscovery bool       `json:"local_discovery"`
+	HolePunching   bool       `json:"hole_punching"`
+	ProxyType      string     `json:"proxy_type"` // none, http or socks5
+	ProxyHost      string     `json:"proxy_host"`
+	ProxyPort      uint16     `json:"proxy_port"`
+	StartPort      uint16     `json:"start_port"`
+	EndPort        uint16     `json:"end_port"`
+	TcpPort        uint16     `json:"tcp_port"`
+	Savedata       string     `json:"savedata"` // profile path
+	PassphraseFile string     `json:"passphrase_file"`
+	Bootstrap      []BootNode `json:"bootstrap"`
+}
+
+// the keys of config files, TOX_<KEY> in the environment and -<key> flags
+// with dashes
+var configKeys = []string{
+	"ipv6", "udp", "local_discovery", "hole_punching",
+	"proxy_type", "proxy_host", "proxy_port",
+	"start_port", "end_port", "tcp_port",
+	"savedata", "passphrase_file", "bootstrap",
+}
+
+var configUsage = map[string]string{
+	"ipv6":            "enable IPv6",
+	"udp":             "enable UDP, disabled with a proxy",
+	"local_discovery": "enable LAN discovery",
+	"hole_punching":   "enable UDP hole punching",
+	"proxy_type":      "proxy type: none, http or socks5",
+	"proxy_host":      "proxy host",
+	"proxy_port":      "proxy port",
+	"start_port":      "first UDP port to bind, 0 for the default range",
+	"end_port":        "last UDP port to bind",
+	"tcp_port":        "TCP relay port, 0 to disable",
+	"savedata":        "profile path",
+	"passphrase_file": "file with the profile passphrase",
+	"bootstrap":       "bootstrap node as addr:port:pubkey, comma separated, may repeat",
+}
+
+var proxyTypeNames = map[string]int{
+	"none":   PROXY_TYPE_NONE,
+	"http":   PROXY_TYPE_HTTP,
+	"socks5": PROXY_TYPE_SOCKS5,
+}
+
+// NewConfig returns a Config with toxcore's default options.
+func NewConfig() *Config {
+	opt := NewToxOptions()
+	return &Config{
+		Ipv6:           opt.Ipv6_enabled,
+		Udp:            opt.Udp_enabled,
+		LocalDiscovery: opt.Local_discovery_enabled,
+		HolePunching:   opt.Hole_punching_enabled,
+		ProxyType:      "none",
+		StartPort:      opt.Start_port,
+		EndPort:        opt.End_port,
+		TcpPort:        opt.Tcp_port,
+	}
+}
+
+// LoadConfig reads the config file at path, or at $TOX_CONFIG if path is
+// empty, over the defaults, then the environment. Without either file it
+// only reads the environment.
+func LoadConfig(path string) (*Config, error) {
+	this := NewConfig()
+	if path == "" {
+		path = os.Getenv("TOX_CONFIG")
+	}
+	if path != "" {
+		if err := this.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := this.LoadEnv(); err != nil {
+		return nil, err
+	}
+	return this, nil
+}
+
+// LoadFile reads a .json or .toml file. Keys missing from the file keep
+// their value, unknown keys are an error.
+func (this *Config) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = this.loadJSON(data)
+	case ".toml":
+		err = this.loadTOML(data)
+	default:
+		return fmt.Errorf("%s: unknown config format, want .json or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (this *Config) loadJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(this); err != nil {
+		return err
+	}
+	this.ProxyType = strings.ToLower(this.ProxyType)
+	if _, ok := proxyTypeNames[this.ProxyType]; !ok {
+		return fmt.Errorf("proxy_type: unknown proxy type %q", this.ProxyType)
+	}
+	return nil
+}
+
+// loadTOML reads the subset of TOML a flat config needs: key = value lines
+// with strings, integers and booleans, and [[bootstrap]] tables with addr,
+// port and pubkey keys.
+func (this *Config) loadTOML(data []byte) error {
+	var node *BootNode
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; sc.Scan(); lineno++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line == "[[bootstrap]]" {
+			this.Bootstrap = append(this.Bootstrap, BootNode{})
+			node = &this.Bootstrap[len(this.Bootstrap)-1]
+			continue
+		}
+		if line[0] == '[' {
+			return fmt.Errorf("line %d: unsupported table %s", lineno, line)
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return fmt.Errorf("line %d: want key = value", lineno)
+		}
+		key := strings.TrimSpace(line[:eq])
+		value, err := tomlValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return fmt.Errorf("line %d: %s: %w", lineno, key, err)
+		}
+
+		if node != nil {
+			err = setBootNode(node, key, value)
+		} else {
+			err = this.Set(key, value)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineno, err)
+		}
+	}
+	return sc.Err()
+}
+
+// tomlValue returns a string value unquoted, other values as they are, both
+// without a trailing comment.
+func tomlValue(s string) (string, error) {
+	if strings.HasPrefix(s, "'") {
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 || !tomlComment(s[end+2:]) {
+			return "", errors.New("bad literal string")
+		}
+		return s[1 : end+1], nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		for end := 1; end < len(s); end++ {
+			if s[end] == '\\' {
+				end++
+			} else if s[end] == '"' {
+				if !tomlComment(s[end+1:]) {
+					break
+				}
+				return strconv.Unquote(s[:end+1])
+			}
+		}
+		return "", errors.New("bad string")
+	}
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if s == "" {
+		return "", errors.New("missing value")
+	}
+	return s, nil
+}
+
+func tomlComment(rest string) bool {
+	rest = strings.TrimSpace(rest)
+	return rest == "" || rest[0] == '#'
+}
+
+func setBootNode(node *BootNode, key, value string) error {
+	switch key {
+	case "addr":
+		node.Addr = value
+	case "port":
+		port, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return fmt.Errorf("bootstrap port: %w", err)
+		}
+		node.Port = int(port)
+	case "pubkey":
+		node.Pubkey = value
+	default:
+		return fmt.Errorf("unknown bootstrap key %q", key)
+	}
+	return nil
+}
+
+// LoadEnv reads the TOX_<KEY> variables that are set, e.g. TOX_UDP=false or
+// TOX_PROXY_TYPE=socks5.
+func (this *Config) LoadEnv() error {
+	for _, key := range configKeys {
+		name := "TOX_" + strings.ToUpper(key)
+		if value, ok := os.LookupEnv(name); ok {
+			if err := this.Set(key, value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Set sets key from its string form, as found in the environment. Bootstrap
+// values are comma separated addr:port:pubkey nodes, they replace the list.
+func (this *Config) Set(key, value string) error {
+	var err error
+	switch key {
+	case "ipv6":
+		this.Ipv6, err = strconv.ParseBool(value)
+	case "udp":
+		this.Udp, err = strconv.ParseBool(value)
+	case "local_discovery":
+		this.LocalDiscovery, err = strconv.ParseBool(value)
+	case "hole_punching":
+		this.HolePunching, err = strconv.ParseBool(value)
+	case "proxy_type":
+		value = strings.ToLower(value)
+		if _, ok := proxyTypeNames[value]; !ok {
+			return fmt.Errorf("proxy_type: unknown proxy type %q", value)
+		}
+		this.ProxyType = value
+	case "proxy_host":
+		this.ProxyHost = value
+	case "proxy_port":
+		err = parsePort(value, &this.ProxyPort)
+	case "start_port":
+		err = parsePort(value, &this.StartPort)
+	case "end_port":
+		err = parsePort(value, &this.EndPort)
+	case "tcp_port":
+		err = parsePort(value, &this.TcpPort)
+	case "savedata":
+		this.Savedata = value
+	case "passphrase_file":
+		this.PassphraseFile = value
+	case "bootstrap":
+		var nodes []BootNode
+		for _, s := range strings.Split(value, ",") {
+			node, err := ParseBootNode(strings.TrimSpace(s))
+			if err != nil {
+				return fmt.Errorf("bootstrap: %w", err)
+			}
+			nodes = append(nodes, node)
+		}
+		this.Bootstrap = nodes
+	default:
+		return fmt.Errorf("unknown config key %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// get is the string form of key for flag defaults.
+func (this *Config) get(key string) string {
+	switch key {
+	case "ipv6":
+		return strconv.FormatBool(this.Ipv6)
+	case "udp":
+		return strconv.FormatBool(this.Udp)
+	case "local_discovery":
+		return strconv.FormatBool(this.LocalDiscovery)
+	case "hole_punching":
+		return strconv.FormatBool(this.HolePunching)
+	case "proxy_type":
+		return this.ProxyType
+	case "proxy_host":
+		return this.ProxyHost
+	case "proxy_port":
+		return strconv.Itoa(int(this.ProxyPort))
+	case "start_port":
+		return strconv.Itoa(int(this.StartPort))
+	case "end_port":
+		return strconv.Itoa(int(this.EndPort))
+	case "tcp_port":
+		return strconv.Itoa(int(this.TcpPort))
+	case "savedata":
+		return this.Savedata
+	case "passphrase_file":
+		return this.PassphraseFile
+	case "bootstrap":
+		nodes := make([]string, len(this.Bootstrap))
+		for i, node := range this.Bootstrap {
+			nodes[i] = node.String()
+		}
+		return strings.Join(nodes, ",")
+	}
+	return ""
+}
+
+func parsePort(value string, port *uint16) error {
+	n, err := strconv.ParseUint(value, 10, 16)
+	if err == nil {
+		*port = uint16(n)
+	}
+	return err
+}
+
+// configFlag is one Config key as a flag.Value.
+type configFlag struct {
+	config *Config
+	key    string
+	set    bool
+}
+
+func (this *configFlag) String() string {
+	if this.config == nil {
+		return ""
+	}
+	return this.config.get(this.key)
+}
+
+// Set replaces the bootstrap nodes of the file and environment on the first
+// -bootstrap, repeats add to it.
+func (this *configFlag) Set(value string) error {
+	if this.key == "bootstrap" && this.set {
+		nodes := this.config.Bootstrap
+		if err := this.config.Set(this.key, value); err != nil {
+			return err
+		}
+		this.config.Bootstrap = append(nodes, this.config.Bootstrap...)
+		return nil
+	}
+	this.set = true
+	return this.config.Set(this.key, value)
+}
+
+func (this *configFlag) IsBoolFlag() bool {
+	switch this.key {
+	case "ipv6", "udp", "local_discovery", "hole_punching":
+		return true
+	}
+	return false
+}
+
+// BindFlags adds a flag for every key to fs, named with dashes, e.g.
+// -proxy-type. Bind after loading the file and environment, so their values
+// show as the defaults and flags override them.
+func (this *Config) BindFlags(fs *flag.FlagSet) {
+	for _, key := range configKeys {
+		fs.Var(&configFlag{config: this, key: key}, strings.Replace(key, "_", "-", -1), configUsage[key])
+	}
+}
+
+// Options returns the network options of the config, without savedata.
+// A proxy disables UDP.
+func (this *Config) Options() (*ToxOptions, error) {
+	opt := NewToxOptions(
+		WithIPv6(this.Ipv6),
+		WithUDP(this.Udp),
+		WithLocalDiscovery(this.LocalDiscovery),
+		WithHolePunching(this.HolePunching),
+		WithPortRange(this.StartPort, this.EndPort),
+		WithTcpPort(this.TcpPort),
+	)
+	if ptype, ok := proxyTypeNames[this.ProxyType]; !ok {
+		return nil, fmt.Errorf("unknown proxy type %q", this.ProxyType)
+	} else if ptype != PROXY_TYPE_NONE {
+		WithProxy(ptype, this.ProxyHost, this.ProxyPort)(opt)
+	}
+	return opt, opt.Validate()
+}
+
+// ReadPassphraseFile drops one trailing line break, as editors add it, and
+// keeps any other, they may be part of the passphrase.
+func ReadPassphraseFile(path string) ([]byte, error) {
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p = bytes.TrimSuffix(p, []byte("\n"))
+	return bytes.TrimSuffix(p, []byte("\r")), nil
+}
+
+// OpenProfile opens the savedata profile with the passphrase from the
+// passphrase file, if any, and gives it the network options of the config.
+func (this *Config) OpenProfile() (*Profile, error) {
+	if this.Savedata == "" {
+		return nil, errors.New("no savedata path configured")
+	}
+	opt, err := this.Options()
+	if err != nil {
+		return nil, err
+	}
+	var passphrase []byte
+	if this.PassphraseFile != "" {
+		if passphrase, err = ReadPassphraseFile(this.PassphraseFile); err != nil {
+			return nil, err
+		}
+	}
+
+	p, err := OpenProfile(this.Savedata, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	opt.Savedata_type = p.Options.Savedata_type
+	opt.Savedata_data = p.Options.Savedata_data
+	p.Options = opt
+	return p, nil
+}
+
+// BootstrapTox bootstraps t from every configured node, and adds them as TCP
+// relays. It fails only if no node could be used either way, so with
+// PresetTor the nodes still serve as relays.
+func (this *Config) BootstrapTox(t *Tox) error {
+	var lastErr error
+	ok := false
+	for _, node := range this.Bootstrap {
+		_, err := t.Bootstrap(node.Addr, uint16(node.Port), node.Pubkey)
+		_, err2 := t.AddTcpRelay(node.Addr, uint16(node.Port), node.Pubkey)
+		if err == nil || err2 == nil {
+			ok = true
+		} else {
+			lastErr = fmt.Errorf("%s: %w", node, err2)
+		}
+	}
+	if !ok && lastErr != nil {
+		return lastErr
+	}
+	return nil
+}
+
+func (this BootNode) String() string {
+	return fmt.Sprintf("%s:%d:%s", this.Addr, this.Port, this.Pubkey)
+}
+
+// ParseBootNode parses addr:port:pubkey, addr may be an IPv6 address.
+func ParseBootNode(s string) (BootNode, error) {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return BootNode{}, fmt.Errorf("want addr:port:pubkey, got %q", s)
+	}
+	j := strings.LastIndexByte(s[:i], ':')
+	if j <= 0 {
+		return BootNode{}, fmt.Errorf("want addr:port:pubkey, got %q", s)
+	}
+	port, err := strconv.ParseUint(s[j+1:i], 10, 16)
+	if err != nil {
+		return BootNode{}, fmt.Errorf("bad port in %q", s)
+	}
+	if _, err := ParsePublicKey(s[i+1:]); err != nil {
+		return BootNode{}, err
+	}
+	return BootNode{strings.Trim(s[:j], "[]"), int(port), s[i+1:]}, nil
+}
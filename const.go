@@ -23,55 +23,68 @@ const (
 type UserStatus int
 
 const (
-	USER_STATUS_NONE = int(C.TOX_USER_STATUS_NONE)
-	USER_STATUS_AWAY = int(C.TOX_USER_STATUS_AWAY)
-	USER_STATUS_BUSY = int(C.TOX_USER_STATUS_BUSY)
+	USER_STATUS_NONE = UserStatus(C.TOX_USER_STATUS_NONE)
+	USER_STATUS_AWAY = UserStatus(C.TOX_USER_STATUS_AWAY)
+	USER_STATUS_BUSY = UserStatus(C.TOX_USER_STATUS_BUSY)
 )
 
 type ConnectionType int
 
 const (
-	CONNECTION_NONE = int(C.TOX_CONNECTION_NONE)
-	CONNECTION_TCP  = int(C.TOX_CONNECTION_TCP)
-	CONNECTION_UDP  = int(C.TOX_CONNECTION_UDP)
+	CONNECTION_NONE = ConnectionType(C.TOX_CONNECTION_NONE)
+	CONNECTION_TCP  = ConnectionType(C.TOX_CONNECTION_TCP)
+	CONNECTION_UDP  = ConnectionType(C.TOX_CONNECTION_UDP)
 )
 
 type FileControlType int
 
 const (
-	FILE_CONTROL_RESUME = int(C.TOX_FILE_CONTROL_RESUME)
-	FILE_CONTROL_PAUSE  = int(C.TOX_FILE_CONTROL_PAUSE)
-	FILE_CONTROL_CANCEL = int(C.TOX_FILE_CONTROL_CANCEL)
+	FILE_CONTROL_RESUME = FileControlType(C.TOX_FILE_CONTROL_RESUME)
+	FILE_CONTROL_PAUSE  = FileControlType(C.TOX_FILE_CONTROL_PAUSE)
+	FILE_CONTROL_CANCEL = FileControlType(C.TOX_FILE_CONTROL_CANCEL)
 )
 
 type FileKind uint32
 
 const (
-	FILE_KIND_DATA   = uint32(C.TOX_FILE_KIND_DATA)
-	FILE_KIND_AVATAR = uint32(C.TOX_FILE_KIND_AVATAR)
+	FILE_KIND_DATA   = FileKind(C.TOX_FILE_KIND_DATA)
+	FILE_KIND_AVATAR = FileKind(C.TOX_FILE_KIND_AVATAR)
 )
 
 type GroupchatType int
 
 const (
-	CONFERENCE_TYPE_TEXT = uint8(C.TOX_CONFERENCE_TYPE_TEXT)
-	CONFERENCE_TYPE_AV   = uint8(C.TOX_CONFERENCE_TYPE_AV)
+	CONFERENCE_TYPE_TEXT = GroupchatType(C.TOX_CONFERENCE_TYPE_TEXT)
+	CONFERENCE_TYPE_AV   = GroupchatType(C.TOX_CONFERENCE_TYPE_AV)
 )
 
 type CallControlType int
 
 const (
-	FRIEND_CALL_STATE_ERROR       = int(C.TOXAV_FRIEND_CALL_STATE_ERROR)
-	FRIEND_CALL_STATE_FINISHED    = int(C.TOXAV_FRIEND_CALL_STATE_FINISHED)
-	FRIEND_CALL_STATE_SENDING_A   = int(C.TOXAV_FRIEND_CALL_STATE_SENDING_A)
-	FRIEND_CALL_STATE_SENDING_V   = int(C.TOXAV_FRIEND_CALL_STATE_SENDING_V)
-	FRIEND_CALL_STATE_ACCEPTING_A = int(C.TOXAV_FRIEND_CALL_STATE_ACCEPTING_A)
-	FRIEND_CALL_STATE_ACCEPTING_V = int(C.TOXAV_FRIEND_CALL_STATE_ACCEPTING_V)
+	CALL_CONTROL_RESUME       = CallControlType(C.TOXAV_CALL_CONTROL_RESUME)
+	CALL_CONTROL_PAUSE        = CallControlType(C.TOXAV_CALL_CONTROL_PAUSE)
+	CALL_CONTROL_CANCEL       = CallControlType(C.TOXAV_CALL_CONTROL_CANCEL)
+	CALL_CONTROL_MUTE_AUDIO   = CallControlType(C.TOXAV_CALL_CONTROL_MUTE_AUDIO)
+	CALL_CONTROL_UNMUTE_AUDIO = CallControlType(C.TOXAV_CALL_CONTROL_UNMUTE_AUDIO)
+	CALL_CONTROL_HIDE_VIDEO   = CallControlType(C.TOXAV_CALL_CONTROL_HIDE_VIDEO)
+	CALL_CONTROL_SHOW_VIDEO   = CallControlType(C.TOXAV_CALL_CONTROL_SHOW_VIDEO)
+)
+
+// CallState is a bit set of FRIEND_CALL_STATE_* flags.
+type CallState uint32
+
+const (
+	FRIEND_CALL_STATE_ERROR       = CallState(C.TOXAV_FRIEND_CALL_STATE_ERROR)
+	FRIEND_CALL_STATE_FINISHED    = CallState(C.TOXAV_FRIEND_CALL_STATE_FINISHED)
+	FRIEND_CALL_STATE_SENDING_A   = CallState(C.TOXAV_FRIEND_CALL_STATE_SENDING_A)
+	FRIEND_CALL_STATE_SENDING_V   = CallState(C.TOXAV_FRIEND_CALL_STATE_SENDING_V)
+	FRIEND_CALL_STATE_ACCEPTING_A = CallState(C.TOXAV_FRIEND_CALL_STATE_ACCEPTING_A)
+	FRIEND_CALL_STATE_ACCEPTING_V = CallState(C.TOXAV_FRIEND_CALL_STATE_ACCEPTING_V)
 )
 
 type MessageType int
 
 const (
-	MESSAGE_TYPE_NORMAL = int(C.TOX_MESSAGE_TYPE_NORMAL)
-	MESSAGE_TYPE_ACTION = int(C.TOX_MESSAGE_TYPE_ACTION)
+	MESSAGE_TYPE_NORMAL = MessageType(C.TOX_MESSAGE_TYPE_NORMAL)
+	MESSAGE_TYPE_ACTION = MessageType(C.TOX_MESSAGE_TYPE_ACTION)
 )
@@ -1,6 +1,8 @@
 package tox
 /*
 #include "tox/tox.h"
+#include "tox/toxav.h"
+#include "tox/toxencryptsave.h"
 */
 import "C"
 
@@ -348,3 +350,175 @@ const ERR_GET_PORT_OK = int(C.TOX_ERR_GET_PORT_OK) // 0
 func init(){_ERR_GET_PORTS[ERR_GET_PORT_OK] = "TE00: The function returned successfully."}
 const ERR_GET_PORT_NOT_BOUND = int(C.TOX_ERR_GET_PORT_NOT_BOUND) // 1
 func init(){_ERR_GET_PORTS[ERR_GET_PORT_NOT_BOUND] = "TE01: The instance was not bound to any port."}
+
+var _AV_ERR_NEWS = make(map[int]string)
+func init(){_AV_ERR_NEWS[-1] = "TE-1: _AV_ERR_NEW"}
+const AV_ERR_NEW_OK = int(C.TOXAV_ERR_NEW_OK) // 0
+func init(){_AV_ERR_NEWS[AV_ERR_NEW_OK] = "TE00: The function returned successfully."}
+const AV_ERR_NEW_NULL = int(C.TOXAV_ERR_NEW_NULL) // 1
+func init(){_AV_ERR_NEWS[AV_ERR_NEW_NULL] = "TE01: One of the arguments to the function was NULL when it was not expected."}
+const AV_ERR_NEW_MALLOC = int(C.TOXAV_ERR_NEW_MALLOC) // 2
+func init(){_AV_ERR_NEWS[AV_ERR_NEW_MALLOC] = "TE02: Memory allocation failure while trying to allocate structures required for the A/V session."}
+const AV_ERR_NEW_MULTIPLE = int(C.TOXAV_ERR_NEW_MULTIPLE) // 3
+func init(){_AV_ERR_NEWS[AV_ERR_NEW_MULTIPLE] = "TE03: Attempted to create a second session for the same Tox instance."}
+
+var _AV_ERR_CALLS = make(map[int]string)
+func init(){_AV_ERR_CALLS[-1] = "TE-1: _AV_ERR_CALL"}
+const AV_ERR_CALL_OK = int(C.TOXAV_ERR_CALL_OK) // 0
+func init(){_AV_ERR_CALLS[AV_ERR_CALL_OK] = "TE00: The function returned successfully."}
+const AV_ERR_CALL_MALLOC = int(C.TOXAV_ERR_CALL_MALLOC) // 1
+func init(){_AV_ERR_CALLS[AV_ERR_CALL_MALLOC] = "TE01: A resource allocation error occurred while trying to create the structures required for the call."}
+const AV_ERR_CALL_SYNC = int(C.TOXAV_ERR_CALL_SYNC) // 2
+func init(){_AV_ERR_CALLS[AV_ERR_CALL_SYNC] = "TE02: Synchronization error occurred."}
+const AV_ERR_CALL_FRIEND_NOT_FOUND = int(C.TOXAV_ERR_CALL_FRIEND_NOT_FOUND) // 3
+func init(){_AV_ERR_CALLS[AV_ERR_CALL_FRIEND_NOT_FOUND] = "TE03: The friend number did not designate a valid friend."}
+const AV_ERR_CALL_FRIEND_NOT_CONNECTED = int(C.TOXAV_ERR_CALL_FRIEND_NOT_CONNECTED) // 4
+func init(){_AV_ERR_CALLS[AV_ERR_CALL_FRIEND_NOT_CONNECTED] = "TE04: The friend was valid, but not currently connected."}
+const AV_ERR_CALL_FRIEND_ALREADY_IN_CALL = int(C.TOXAV_ERR_CALL_FRIEND_ALREADY_IN_CALL) // 5
+func init(){_AV_ERR_CALLS[AV_ERR_CALL_FRIEND_ALREADY_IN_CALL] = "TE05: Attempted to call a friend while already in an audio or video call with them."}
+const AV_ERR_CALL_INVALID_BIT_RATE = int(C.TOXAV_ERR_CALL_INVALID_BIT_RATE) // 6
+func init(){_AV_ERR_CALLS[AV_ERR_CALL_INVALID_BIT_RATE] = "TE06: Audio or video bit rate is invalid."}
+
+var _AV_ERR_ANSWERS = make(map[int]string)
+func init(){_AV_ERR_ANSWERS[-1] = "TE-1: _AV_ERR_ANSWER"}
+const AV_ERR_ANSWER_OK = int(C.TOXAV_ERR_ANSWER_OK) // 0
+func init(){_AV_ERR_ANSWERS[AV_ERR_ANSWER_OK] = "TE00: The function returned successfully."}
+const AV_ERR_ANSWER_SYNC = int(C.TOXAV_ERR_ANSWER_SYNC) // 1
+func init(){_AV_ERR_ANSWERS[AV_ERR_ANSWER_SYNC] = "TE01: Synchronization error occurred."}
+const AV_ERR_ANSWER_CODEC_INITIALIZATION = int(C.TOXAV_ERR_ANSWER_CODEC_INITIALIZATION) // 2
+func init(){_AV_ERR_ANSWERS[AV_ERR_ANSWER_CODEC_INITIALIZATION] = "TE02: Failed to initialize codecs for call session. Note that codec initiation will fail if there is no receive callback registered for either audio or video."}
+const AV_ERR_ANSWER_FRIEND_NOT_FOUND = int(C.TOXAV_ERR_ANSWER_FRIEND_NOT_FOUND) // 3
+func init(){_AV_ERR_ANSWERS[AV_ERR_ANSWER_FRIEND_NOT_FOUND] = "TE03: The friend number did not designate a valid friend."}
+const AV_ERR_ANSWER_FRIEND_NOT_CALLING = int(C.TOXAV_ERR_ANSWER_FRIEND_NOT_CALLING) // 4
+func init(){_AV_ERR_ANSWERS[AV_ERR_ANSWER_FRIEND_NOT_CALLING] = "TE04: The friend was valid, but they are not currently trying to initiate a call. This is also returned if this client is already in a call with the friend."}
+const AV_ERR_ANSWER_INVALID_BIT_RATE = int(C.TOXAV_ERR_ANSWER_INVALID_BIT_RATE) // 5
+func init(){_AV_ERR_ANSWERS[AV_ERR_ANSWER_INVALID_BIT_RATE] = "TE05: Audio or video bit rate is invalid."}
+
+var _AV_ERR_CALL_CONTROLS = make(map[int]string)
+func init(){_AV_ERR_CALL_CONTROLS[-1] = "TE-1: _AV_ERR_CALL_CONTROL"}
+const AV_ERR_CALL_CONTROL_OK = int(C.TOXAV_ERR_CALL_CONTROL_OK) // 0
+func init(){_AV_ERR_CALL_CONTROLS[AV_ERR_CALL_CONTROL_OK] = "TE00: The function returned successfully."}
+const AV_ERR_CALL_CONTROL_SYNC = int(C.TOXAV_ERR_CALL_CONTROL_SYNC) // 1
+func init(){_AV_ERR_CALL_CONTROLS[AV_ERR_CALL_CONTROL_SYNC] = "TE01: Synchronization error occurred."}
+const AV_ERR_CALL_CONTROL_FRIEND_NOT_FOUND = int(C.TOXAV_ERR_CALL_CONTROL_FRIEND_NOT_FOUND) // 2
+func init(){_AV_ERR_CALL_CONTROLS[AV_ERR_CALL_CONTROL_FRIEND_NOT_FOUND] = "TE02: The friend_number passed did not designate a valid friend."}
+const AV_ERR_CALL_CONTROL_FRIEND_NOT_IN_CALL = int(C.TOXAV_ERR_CALL_CONTROL_FRIEND_NOT_IN_CALL) // 3
+func init(){_AV_ERR_CALL_CONTROLS[AV_ERR_CALL_CONTROL_FRIEND_NOT_IN_CALL] = "TE03: This client is currently not in a call with the friend. Before the call is answered, only CANCEL is a valid control."}
+const AV_ERR_CALL_CONTROL_INVALID_TRANSITION = int(C.TOXAV_ERR_CALL_CONTROL_INVALID_TRANSITION) // 4
+func init(){_AV_ERR_CALL_CONTROLS[AV_ERR_CALL_CONTROL_INVALID_TRANSITION] = "TE04: Happens if user tried to pause an already paused call or if trying to resume a call that is not paused."}
+
+var _AV_ERR_BIT_RATE_SETS = make(map[int]string)
+func init(){_AV_ERR_BIT_RATE_SETS[-1] = "TE-1: _AV_ERR_BIT_RATE_SET"}
+const AV_ERR_BIT_RATE_SET_OK = int(C.TOXAV_ERR_BIT_RATE_SET_OK) // 0
+func init(){_AV_ERR_BIT_RATE_SETS[AV_ERR_BIT_RATE_SET_OK] = "TE00: The function returned successfully."}
+const AV_ERR_BIT_RATE_SET_SYNC = int(C.TOXAV_ERR_BIT_RATE_SET_SYNC) // 1
+func init(){_AV_ERR_BIT_RATE_SETS[AV_ERR_BIT_RATE_SET_SYNC] = "TE01: Synchronization error occurred."}
+const AV_ERR_BIT_RATE_SET_INVALID_BIT_RATE = int(C.TOXAV_ERR_BIT_RATE_SET_INVALID_BIT_RATE) // 2
+func init(){_AV_ERR_BIT_RATE_SETS[AV_ERR_BIT_RATE_SET_INVALID_BIT_RATE] = "TE02: The bit rate passed was not one of the supported values."}
+const AV_ERR_BIT_RATE_SET_FRIEND_NOT_FOUND = int(C.TOXAV_ERR_BIT_RATE_SET_FRIEND_NOT_FOUND) // 3
+func init(){_AV_ERR_BIT_RATE_SETS[AV_ERR_BIT_RATE_SET_FRIEND_NOT_FOUND] = "TE03: The friend_number passed did not designate a valid friend."}
+const AV_ERR_BIT_RATE_SET_FRIEND_NOT_IN_CALL = int(C.TOXAV_ERR_BIT_RATE_SET_FRIEND_NOT_IN_CALL) // 4
+func init(){_AV_ERR_BIT_RATE_SETS[AV_ERR_BIT_RATE_SET_FRIEND_NOT_IN_CALL] = "TE04: This client is currently not in a call with the friend."}
+
+var _AV_ERR_SEND_FRAMES = make(map[int]string)
+func init(){_AV_ERR_SEND_FRAMES[-1] = "TE-1: _AV_ERR_SEND_FRAME"}
+const AV_ERR_SEND_FRAME_OK = int(C.TOXAV_ERR_SEND_FRAME_OK) // 0
+func init(){_AV_ERR_SEND_FRAMES[AV_ERR_SEND_FRAME_OK] = "TE00: The function returned successfully."}
+const AV_ERR_SEND_FRAME_NULL = int(C.TOXAV_ERR_SEND_FRAME_NULL) // 1
+func init(){_AV_ERR_SEND_FRAMES[AV_ERR_SEND_FRAME_NULL] = "TE01: In case of video, one of Y, U, or V was NULL. In case of audio, the samples data pointer was NULL."}
+const AV_ERR_SEND_FRAME_FRIEND_NOT_FOUND = int(C.TOXAV_ERR_SEND_FRAME_FRIEND_NOT_FOUND) // 2
+func init(){_AV_ERR_SEND_FRAMES[AV_ERR_SEND_FRAME_FRIEND_NOT_FOUND] = "TE02: The friend_number passed did not designate a valid friend."}
+const AV_ERR_SEND_FRAME_FRIEND_NOT_IN_CALL = int(C.TOXAV_ERR_SEND_FRAME_FRIEND_NOT_IN_CALL) // 3
+func init(){_AV_ERR_SEND_FRAMES[AV_ERR_SEND_FRAME_FRIEND_NOT_IN_CALL] = "TE03: This client is currently not in a call with the friend."}
+const AV_ERR_SEND_FRAME_SYNC = int(C.TOXAV_ERR_SEND_FRAME_SYNC) // 4
+func init(){_AV_ERR_SEND_FRAMES[AV_ERR_SEND_FRAME_SYNC] = "TE04: Synchronization error occurred."}
+const AV_ERR_SEND_FRAME_INVALID = int(C.TOXAV_ERR_SEND_FRAME_INVALID) // 5
+func init(){_AV_ERR_SEND_FRAMES[AV_ERR_SEND_FRAME_INVALID] = "TE05: One of the frame parameters was invalid. E.g. the resolution may be too small or too large, or the audio sampling rate may be unsupported."}
+const AV_ERR_SEND_FRAME_PAYLOAD_TYPE_DISABLED = int(C.TOXAV_ERR_SEND_FRAME_PAYLOAD_TYPE_DISABLED) // 6
+func init(){_AV_ERR_SEND_FRAMES[AV_ERR_SEND_FRAME_PAYLOAD_TYPE_DISABLED] = "TE06: Either friend turned off audio or video receiving or we turned off sending for the said payload."}
+const AV_ERR_SEND_FRAME_RTP_FAILED = int(C.TOXAV_ERR_SEND_FRAME_RTP_FAILED) // 7
+func init(){_AV_ERR_SEND_FRAMES[AV_ERR_SEND_FRAME_RTP_FAILED] = "TE07: Failed to push frame through rtp interface."}
+
+var _ERR_KEY_DERIVATIONS = make(map[int]string)
+func init(){_ERR_KEY_DERIVATIONS[-1] = "TE-1: _ERR_KEY_DERIVATION"}
+const ERR_KEY_DERIVATION_OK = int(C.TOX_ERR_KEY_DERIVATION_OK) // 0
+func init(){_ERR_KEY_DERIVATIONS[ERR_KEY_DERIVATION_OK] = "TE00: The function returned successfully."}
+const ERR_KEY_DERIVATION_NULL = int(C.TOX_ERR_KEY_DERIVATION_NULL) // 1
+func init(){_ERR_KEY_DERIVATIONS[ERR_KEY_DERIVATION_NULL] = "TE01: One of the arguments to the function was NULL when it was not expected."}
+const ERR_KEY_DERIVATION_FAILED = int(C.TOX_ERR_KEY_DERIVATION_FAILED) // 2
+func init(){_ERR_KEY_DERIVATIONS[ERR_KEY_DERIVATION_FAILED] = "TE02: The crypto lib was unable to derive a key from the given passphrase, which is usually a lack of memory issue."}
+
+var _ERR_ENCRYPTIONS = make(map[int]string)
+func init(){_ERR_ENCRYPTIONS[-1] = "TE-1: _ERR_ENCRYPTION"}
+const ERR_ENCRYPTION_OK = int(C.TOX_ERR_ENCRYPTION_OK) // 0
+func init(){_ERR_ENCRYPTIONS[ERR_ENCRYPTION_OK] = "TE00: The function returned successfully."}
+const ERR_ENCRYPTION_NULL = int(C.TOX_ERR_ENCRYPTION_NULL) // 1
+func init(){_ERR_ENCRYPTIONS[ERR_ENCRYPTION_NULL] = "TE01: One of the arguments to the function was NULL when it was not expected."}
+const ERR_ENCRYPTION_KEY_DERIVATION_FAILED = int(C.TOX_ERR_ENCRYPTION_KEY_DERIVATION_FAILED) // 2
+func init(){_ERR_ENCRYPTIONS[ERR_ENCRYPTION_KEY_DERIVATION_FAILED] = "TE02: The crypto lib was unable to derive a key from the given passphrase, which is usually a lack of memory issue. The functions accepting keys do not produce this error."}
+const ERR_ENCRYPTION_FAILED = int(C.TOX_ERR_ENCRYPTION_FAILED) // 3
+func init(){_ERR_ENCRYPTIONS[ERR_ENCRYPTION_FAILED] = "TE03: The encryption itself failed."}
+
+var _ERR_DECRYPTIONS = make(map[int]string)
+func init(){_ERR_DECRYPTIONS[-1] = "TE-1: _ERR_DECRYPTION"}
+const ERR_DECRYPTION_OK = int(C.TOX_ERR_DECRYPTION_OK) // 0
+func init(){_ERR_DECRYPTIONS[ERR_DECRYPTION_OK] = "TE00: The function returned successfully."}
+const ERR_DECRYPTION_NULL = int(C.TOX_ERR_DECRYPTION_NULL) // 1
+func init(){_ERR_DECRYPTIONS[ERR_DECRYPTION_NULL] = "TE01: One of the arguments to the function was NULL when it was not expected."}
+const ERR_DECRYPTION_INVALID_LENGTH = int(C.TOX_ERR_DECRYPTION_INVALID_LENGTH) // 2
+func init(){_ERR_DECRYPTIONS[ERR_DECRYPTION_INVALID_LENGTH] = "TE02: The input data was shorter than TOX_PASS_ENCRYPTION_EXTRA_LENGTH bytes"}
+const ERR_DECRYPTION_BAD_FORMAT = int(C.TOX_ERR_DECRYPTION_BAD_FORMAT) // 3
+func init(){_ERR_DECRYPTIONS[ERR_DECRYPTION_BAD_FORMAT] = "TE03: The input data is missing the magic number (i.e. wasn't created by this module, or is corrupted)."}
+const ERR_DECRYPTION_KEY_DERIVATION_FAILED = int(C.TOX_ERR_DECRYPTION_KEY_DERIVATION_FAILED) // 4
+func init(){_ERR_DECRYPTIONS[ERR_DECRYPTION_KEY_DERIVATION_FAILED] = "TE04: The crypto lib was unable to derive a key from the given passphrase, which is usually a lack of memory issue. The functions accepting keys do not produce this error."}
+const ERR_DECRYPTION_FAILED = int(C.TOX_ERR_DECRYPTION_FAILED) // 5
+func init(){_ERR_DECRYPTIONS[ERR_DECRYPTION_FAILED] = "TE05: The encrypted byte array could not be decrypted. Either the data was corrupted or the password/key was incorrect."}
+
+var _ERR_GET_SALTS = make(map[int]string)
+func init(){_ERR_GET_SALTS[-1] = "TE-1: _ERR_GET_SALT"}
+const ERR_GET_SALT_OK = int(C.TOX_ERR_GET_SALT_OK) // 0
+func init(){_ERR_GET_SALTS[ERR_GET_SALT_OK] = "TE00: The function returned successfully."}
+const ERR_GET_SALT_NULL = int(C.TOX_ERR_GET_SALT_NULL) // 1
+func init(){_ERR_GET_SALTS[ERR_GET_SALT_NULL] = "TE01: One of the arguments to the function was NULL when it was not expected."}
+const ERR_GET_SALT_BAD_FORMAT = int(C.TOX_ERR_GET_SALT_BAD_FORMAT) // 2
+func init(){_ERR_GET_SALTS[ERR_GET_SALT_BAD_FORMAT] = "TE02: The input data is missing the magic number (i.e. wasn't created by this module, or is corrupted)."}
+
+func errOptionsNewText(code int) string { return errtext(_ERR_OPTIONS_NEWS, code) }
+func errNewText(code int) string { return errtext(_ERR_NEWS, code) }
+func errBootstrapText(code int) string { return errtext(_ERR_BOOTSTRAPS, code) }
+func errSetInfoText(code int) string { return errtext(_ERR_SET_INFOS, code) }
+func errFriendAddText(code int) string { return errtext(_ERR_FRIEND_ADDS, code) }
+func errFriendDeleteText(code int) string { return errtext(_ERR_FRIEND_DELETES, code) }
+func errFriendByPublicKeyText(code int) string { return errtext(_ERR_FRIEND_BY_PUBLIC_KEYS, code) }
+func errFriendGetPublicKeyText(code int) string { return errtext(_ERR_FRIEND_GET_PUBLIC_KEYS, code) }
+func errFriendGetLastOnlineText(code int) string { return errtext(_ERR_FRIEND_GET_LAST_ONLINES, code) }
+func errFriendQueryText(code int) string { return errtext(_ERR_FRIEND_QUERYS, code) }
+func errSetTypingText(code int) string { return errtext(_ERR_SET_TYPINGS, code) }
+func errFriendSendMessageText(code int) string { return errtext(_ERR_FRIEND_SEND_MESSAGES, code) }
+func errFileControlText(code int) string { return errtext(_ERR_FILE_CONTROLS, code) }
+func errFileSeekText(code int) string { return errtext(_ERR_FILE_SEEKS, code) }
+func errFileGetText(code int) string { return errtext(_ERR_FILE_GETS, code) }
+func errFileSendText(code int) string { return errtext(_ERR_FILE_SENDS, code) }
+func errFileSendChunkText(code int) string { return errtext(_ERR_FILE_SEND_CHUNKS, code) }
+func errConferenceNewText(code int) string { return errtext(_ERR_CONFERENCE_NEWS, code) }
+func errConferenceDeleteText(code int) string { return errtext(_ERR_CONFERENCE_DELETES, code) }
+func errConferencePeerQueryText(code int) string { return errtext(_ERR_CONFERENCE_PEER_QUERYS, code) }
+func errConferenceInviteText(code int) string { return errtext(_ERR_CONFERENCE_INVITES, code) }
+func errConferenceJoinText(code int) string { return errtext(_ERR_CONFERENCE_JOINS, code) }
+func errConferenceSendMessageText(code int) string { return errtext(_ERR_CONFERENCE_SEND_MESSAGES, code) }
+func errConferenceTitleText(code int) string { return errtext(_ERR_CONFERENCE_TITLES, code) }
+func errConferenceGetTypeText(code int) string { return errtext(_ERR_CONFERENCE_GET_TYPES, code) }
+func errConferenceByIdText(code int) string { return errtext(_ERR_CONFERENCE_BY_IDS, code) }
+func errConferenceByUidText(code int) string { return errtext(_ERR_CONFERENCE_BY_UIDS, code) }
+func errFriendCustomPacketText(code int) string { return errtext(_ERR_FRIEND_CUSTOM_PACKETS, code) }
+func errGetPortText(code int) string { return errtext(_ERR_GET_PORTS, code) }
+func avErrNewText(code int) string { return errtext(_AV_ERR_NEWS, code) }
+func avErrCallText(code int) string { return errtext(_AV_ERR_CALLS, code) }
+func avErrAnswerText(code int) string { return errtext(_AV_ERR_ANSWERS, code) }
+func avErrCallControlText(code int) string { return errtext(_AV_ERR_CALL_CONTROLS, code) }
+func avErrBitRateSetText(code int) string { return errtext(_AV_ERR_BIT_RATE_SETS, code) }
+func avErrSendFrameText(code int) string { return errtext(_AV_ERR_SEND_FRAMES, code) }
+func errKeyDerivationText(code int) string { return errtext(_ERR_KEY_DERIVATIONS, code) }
+func errEncryptionText(code int) string { return errtext(_ERR_ENCRYPTIONS, code) }
+func errDecryptionText(code int) string { return errtext(_ERR_DECRYPTIONS, code) }
+func errGetSaltText(code int) string { return errtext(_ERR_GET_SALTS, code) }
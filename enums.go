@@ -0,0 +1,241 @@
+package tox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Text forms of the enum types in const.go are the constant names, e.g.
+// "CONNECTION_UDP". UnmarshalText also takes them without the common prefix
+// and in any case, e.g. "udp".
+
+type enumName struct {
+	value int
+	name  string
+}
+
+var userStatusNames = []enumName{
+	{int(USER_STATUS_NONE), "USER_STATUS_NONE"},
+	{int(USER_STATUS_AWAY), "USER_STATUS_AWAY"},
+	{int(USER_STATUS_BUSY), "USER_STATUS_BUSY"},
+}
+
+var connectionTypeNames = []enumName{
+	{int(CONNECTION_NONE), "CONNECTION_NONE"},
+	{int(CONNECTION_TCP), "CONNECTION_TCP"},
+	{int(CONNECTION_UDP), "CONNECTION_UDP"},
+}
+
+var fileControlTypeNames = []enumName{
+	{int(FILE_CONTROL_RESUME), "FILE_CONTROL_RESUME"},
+	{int(FILE_CONTROL_PAUSE), "FILE_CONTROL_PAUSE"},
+	{int(FILE_CONTROL_CANCEL), "FILE_CONTROL_CANCEL"},
+}
+
+var fileKindNames = []enumName{
+	{int(FILE_KIND_DATA), "FILE_KIND_DATA"},
+	{int(FILE_KIND_AVATAR), "FILE_KIND_AVATAR"},
+}
+
+var groupchatTypeNames = []enumName{
+	{int(CONFERENCE_TYPE_TEXT), "CONFERENCE_TYPE_TEXT"},
+	{int(CONFERENCE_TYPE_AV), "CONFERENCE_TYPE_AV"},
+}
+
+var callControlTypeNames = []enumName{
+	{int(CALL_CONTROL_RESUME), "CALL_CONTROL_RESUME"},
+	{int(CALL_CONTROL_PAUSE), "CALL_CONTROL_PAUSE"},
+	{int(CALL_CONTROL_CANCEL), "CALL_CONTROL_CANCEL"},
+	{int(CALL_CONTROL_MUTE_AUDIO), "CALL_CONTROL_MUTE_AUDIO"},
+	{int(CALL_CONTROL_UNMUTE_AUDIO), "CALL_CONTROL_UNMUTE_AUDIO"},
+	{int(CALL_CONTROL_HIDE_VIDEO), "CALL_CONTROL_HIDE_VIDEO"},
+	{int(CALL_CONTROL_SHOW_VIDEO), "CALL_CONTROL_SHOW_VIDEO"},
+}
+
+var callStateNames = []enumName{
+	{int(FRIEND_CALL_STATE_ERROR), "FRIEND_CALL_STATE_ERROR"},
+	{int(FRIEND_CALL_STATE_FINISHED), "FRIEND_CALL_STATE_FINISHED"},
+	{int(FRIEND_CALL_STATE_SENDING_A), "FRIEND_CALL_STATE_SENDING_A"},
+	{int(FRIEND_CALL_STATE_SENDING_V), "FRIEND_CALL_STATE_SENDING_V"},
+	{int(FRIEND_CALL_STATE_ACCEPTING_A), "FRIEND_CALL_STATE_ACCEPTING_A"},
+	{int(FRIEND_CALL_STATE_ACCEPTING_V), "FRIEND_CALL_STATE_ACCEPTING_V"},
+}
+
+var messageTypeNames = []enumName{
+	{int(MESSAGE_TYPE_NORMAL), "MESSAGE_TYPE_NORMAL"},
+	{int(MESSAGE_TYPE_ACTION), "MESSAGE_TYPE_ACTION"},
+}
+
+func enumLookup(names []enumName, value int) (string, bool) {
+	for _, n := range names {
+		if n.value == value {
+			return n.name, true
+		}
+	}
+	return "", false
+}
+
+func enumString(names []enumName, typ string, value int) string {
+	if name, ok := enumLookup(names, value); ok {
+		return name
+	}
+	return fmt.Sprintf("%s(%d)", typ, value)
+}
+
+func enumMarshal(names []enumName, typ string, value int) ([]byte, error) {
+	if name, ok := enumLookup(names, value); ok {
+		return []byte(name), nil
+	}
+	return nil, fmt.Errorf("invalid %s: %d", typ, value)
+}
+
+func enumParse(names []enumName, prefix string, typ string, text []byte) (int, error) {
+	s := strings.ToUpper(strings.TrimSpace(string(text)))
+	for _, n := range names {
+		if s == n.name || prefix+s == n.name {
+			return n.value, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid %s: %q", typ, text)
+}
+
+func (this UserStatus) String() string {
+	return enumString(userStatusNames, "UserStatus", int(this))
+}
+func (this UserStatus) MarshalText() ([]byte, error) {
+	return enumMarshal(userStatusNames, "UserStatus", int(this))
+}
+func (this *UserStatus) UnmarshalText(text []byte) error {
+	v, err := enumParse(userStatusNames, "USER_STATUS_", "UserStatus", text)
+	if err == nil {
+		*this = UserStatus(v)
+	}
+	return err
+}
+
+func (this ConnectionType) String() string {
+	return enumString(connectionTypeNames, "ConnectionType", int(this))
+}
+func (this ConnectionType) MarshalText() ([]byte, error) {
+	return enumMarshal(connectionTypeNames, "ConnectionType", int(this))
+}
+func (this *ConnectionType) UnmarshalText(text []byte) error {
+	v, err := enumParse(connectionTypeNames, "CONNECTION_", "ConnectionType", text)
+	if err == nil {
+		*this = ConnectionType(v)
+	}
+	return err
+}
+
+func (this FileControlType) String() string {
+	return enumString(fileControlTypeNames, "FileControlType", int(this))
+}
+func (this FileControlType) MarshalText() ([]byte, error) {
+	return enumMarshal(fileControlTypeNames, "FileControlType", int(this))
+}
+func (this *FileControlType) UnmarshalText(text []byte) error {
+	v, err := enumParse(fileControlTypeNames, "FILE_CONTROL_", "FileControlType", text)
+	if err == nil {
+		*this = FileControlType(v)
+	}
+	return err
+}
+
+func (this FileKind) String() string {
+	return enumString(fileKindNames, "FileKind", int(this))
+}
+func (this FileKind) MarshalText() ([]byte, error) {
+	return enumMarshal(fileKindNames, "FileKind", int(this))
+}
+func (this *FileKind) UnmarshalText(text []byte) error {
+	v, err := enumParse(fileKindNames, "FILE_KIND_", "FileKind", text)
+	if err == nil {
+		*this = FileKind(v)
+	}
+	return err
+}
+
+func (this GroupchatType) String() string {
+	return enumString(groupchatTypeNames, "GroupchatType", int(this))
+}
+func (this GroupchatType) MarshalText() ([]byte, error) {
+	return enumMarshal(groupchatTypeNames, "GroupchatType", int(this))
+}
+func (this *GroupchatType) UnmarshalText(text []byte) error {
+	v, err := enumParse(groupchatTypeNames, "CONFERENCE_TYPE_", "GroupchatType", text)
+	if err == nil {
+		*this = GroupchatType(v)
+	}
+	return err
+}
+
+func (this CallControlType) String() string {
+	return enumString(callControlTypeNames, "CallControlType", int(this))
+}
+func (this CallControlType) MarshalText() ([]byte, error) {
+	return enumMarshal(callControlTypeNames, "CallControlType", int(this))
+}
+func (this *CallControlType) UnmarshalText(text []byte) error {
+	v, err := enumParse(callControlTypeNames, "CALL_CONTROL_", "CallControlType", text)
+	if err == nil {
+		*this = CallControlType(v)
+	}
+	return err
+}
+
+func (this MessageType) String() string {
+	return enumString(messageTypeNames, "MessageType", int(this))
+}
+func (this MessageType) MarshalText() ([]byte, error) {
+	return enumMarshal(messageTypeNames, "MessageType", int(this))
+}
+func (this *MessageType) UnmarshalText(text []byte) error {
+	v, err := enumParse(messageTypeNames, "MESSAGE_TYPE_", "MessageType", text)
+	if err == nil {
+		*this = MessageType(v)
+	}
+	return err
+}
+
+// CallState is a bit set, its text form joins the set flags with "|".
+// The empty state is "FRIEND_CALL_STATE_NONE".
+func (this CallState) String() string {
+	if this == 0 {
+		return "FRIEND_CALL_STATE_NONE"
+	}
+	var names []string
+	rest := this
+	for _, n := range callStateNames {
+		if this&CallState(n.value) != 0 {
+			names = append(names, n.name)
+			rest &^= CallState(n.value)
+		}
+	}
+	if rest != 0 {
+		names = append(names, fmt.Sprintf("CallState(%d)", uint32(rest)))
+	}
+	return strings.Join(names, "|")
+}
+func (this CallState) MarshalText() ([]byte, error) {
+	s := this.String()
+	if strings.Contains(s, "CallState(") {
+		return nil, fmt.Errorf("invalid CallState: %d", uint32(this))
+	}
+	return []byte(s), nil
+}
+func (this *CallState) UnmarshalText(text []byte) error {
+	var state CallState
+	for _, field := range strings.Split(string(text), "|") {
+		field = strings.ToUpper(strings.TrimSpace(field))
+		if field == "FRIEND_CALL_STATE_NONE" || field == "NONE" {
+			continue
+		}
+		v, err := enumParse(callStateNames, "FRIEND_CALL_STATE_", "CallState", []byte(field))
+		if err != nil {
+			return err
+		}
+		state |= CallState(v)
+	}
+	*this = state
+	return nil
+}
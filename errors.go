@@ -0,0 +1,689 @@
+package tox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Every toxcore error enum has its own error type carrying the raw enum value
+// in Code. Match a specific code with errors.Is against the Err* values, or
+// get at the code with errors.As.
+
+// errtext looks code up in one of the const_auto.go tables and strips the
+// "TEnn: " prefix. Returns "" for unknown codes.
+func errtext(descs map[int]string, code int) string {
+	desc, ok := descs[code]
+	if !ok || code < 0 {
+		return ""
+	}
+	if pos := strings.Index(desc, ": "); pos >= 0 {
+		desc = desc[pos+2:]
+	}
+	return desc
+}
+
+// the checks done before calling into toxcore, and the legacy calls that
+// don't report a code
+var (
+	ErrInvalidMessageType = errors.New("invalid message type")
+	ErrAVGroupChatJoin    = errors.New("join av group chat failed")
+)
+
+func errdesc(kind string, text string, code int) string {
+	if text == "" {
+		return fmt.Sprintf("%s: error %d", kind, code)
+	}
+	return kind + ": " + text
+}
+
+// OptionsNewError wraps a TOX_ERR_OPTIONS_NEW code.
+type OptionsNewError struct{ Code int }
+
+func (e *OptionsNewError) Error() string {
+	return errdesc("options new", errOptionsNewText(e.Code), e.Code)
+}
+func (e *OptionsNewError) Is(target error) bool {
+	t, ok := target.(*OptionsNewError)
+	return ok && t.Code == e.Code
+}
+
+var ErrOptionsNewMalloc = &OptionsNewError{ERR_OPTIONS_NEW_MALLOC}
+
+// ToxNewError wraps a TOX_ERR_NEW code.
+type ToxNewError struct{ Code int }
+
+func (e *ToxNewError) Error() string { return errdesc("tox new", errNewText(e.Code), e.Code) }
+func (e *ToxNewError) Is(target error) bool {
+	t, ok := target.(*ToxNewError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrNewNull          = &ToxNewError{ERR_NEW_NULL}
+	ErrNewMalloc        = &ToxNewError{ERR_NEW_MALLOC}
+	ErrNewPortAlloc     = &ToxNewError{ERR_NEW_PORT_ALLOC}
+	ErrNewProxyBadType  = &ToxNewError{ERR_NEW_PROXY_BAD_TYPE}
+	ErrNewProxyBadHost  = &ToxNewError{ERR_NEW_PROXY_BAD_HOST}
+	ErrNewProxyBadPort  = &ToxNewError{ERR_NEW_PROXY_BAD_PORT}
+	ErrNewProxyNotFound = &ToxNewError{ERR_NEW_PROXY_NOT_FOUND}
+	ErrNewLoadEncrypted = &ToxNewError{ERR_NEW_LOAD_ENCRYPTED}
+	ErrNewLoadBadFormat = &ToxNewError{ERR_NEW_LOAD_BAD_FORMAT}
+)
+
+// BootstrapError wraps a TOX_ERR_BOOTSTRAP code.
+type BootstrapError struct{ Code int }
+
+func (e *BootstrapError) Error() string {
+	return errdesc("bootstrap", errBootstrapText(e.Code), e.Code)
+}
+func (e *BootstrapError) Is(target error) bool {
+	t, ok := target.(*BootstrapError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrBootstrapNull    = &BootstrapError{ERR_BOOTSTRAP_NULL}
+	ErrBootstrapBadHost = &BootstrapError{ERR_BOOTSTRAP_BAD_HOST}
+	ErrBootstrapBadPort = &BootstrapError{ERR_BOOTSTRAP_BAD_PORT}
+)
+
+// SetInfoError wraps a TOX_ERR_SET_INFO code.
+type SetInfoError struct{ Code int }
+
+func (e *SetInfoError) Error() string { return errdesc("set info", errSetInfoText(e.Code), e.Code) }
+func (e *SetInfoError) Is(target error) bool {
+	t, ok := target.(*SetInfoError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrSetInfoNull    = &SetInfoError{ERR_SET_INFO_NULL}
+	ErrSetInfoTooLong = &SetInfoError{ERR_SET_INFO_TOO_LONG}
+)
+
+// FriendAddError wraps a TOX_ERR_FRIEND_ADD code.
+type FriendAddError struct{ Code int }
+
+func (e *FriendAddError) Error() string {
+	return errdesc("friend add", errFriendAddText(e.Code), e.Code)
+}
+func (e *FriendAddError) Is(target error) bool {
+	t, ok := target.(*FriendAddError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFriendAddNull         = &FriendAddError{ERR_FRIEND_ADD_NULL}
+	ErrFriendAddTooLong      = &FriendAddError{ERR_FRIEND_ADD_TOO_LONG}
+	ErrFriendAddNoMessage    = &FriendAddError{ERR_FRIEND_ADD_NO_MESSAGE}
+	ErrFriendAddOwnKey       = &FriendAddError{ERR_FRIEND_ADD_OWN_KEY}
+	ErrFriendAddAlreadySent  = &FriendAddError{ERR_FRIEND_ADD_ALREADY_SENT}
+	ErrFriendAddBadChecksum  = &FriendAddError{ERR_FRIEND_ADD_BAD_CHECKSUM}
+	ErrFriendAddSetNewNospam = &FriendAddError{ERR_FRIEND_ADD_SET_NEW_NOSPAM}
+	ErrFriendAddMalloc       = &FriendAddError{ERR_FRIEND_ADD_MALLOC}
+)
+
+// FriendDeleteError wraps a TOX_ERR_FRIEND_DELETE code.
+type FriendDeleteError struct{ Code int }
+
+func (e *FriendDeleteError) Error() string {
+	return errdesc("friend delete", errFriendDeleteText(e.Code), e.Code)
+}
+func (e *FriendDeleteError) Is(target error) bool {
+	t, ok := target.(*FriendDeleteError)
+	return ok && t.Code == e.Code
+}
+
+var ErrFriendDeleteFriendNotFound = &FriendDeleteError{ERR_FRIEND_DELETE_FRIEND_NOT_FOUND}
+
+// FriendByPublicKeyError wraps a TOX_ERR_FRIEND_BY_PUBLIC_KEY code.
+type FriendByPublicKeyError struct{ Code int }
+
+func (e *FriendByPublicKeyError) Error() string {
+	return errdesc("friend by public key", errFriendByPublicKeyText(e.Code), e.Code)
+}
+func (e *FriendByPublicKeyError) Is(target error) bool {
+	t, ok := target.(*FriendByPublicKeyError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFriendByPublicKeyNull     = &FriendByPublicKeyError{ERR_FRIEND_BY_PUBLIC_KEY_NULL}
+	ErrFriendByPublicKeyNotFound = &FriendByPublicKeyError{ERR_FRIEND_BY_PUBLIC_KEY_NOT_FOUND}
+)
+
+// FriendGetPublicKeyError wraps a TOX_ERR_FRIEND_GET_PUBLIC_KEY code.
+type FriendGetPublicKeyError struct{ Code int }
+
+func (e *FriendGetPublicKeyError) Error() string {
+	return errdesc("friend get public key", errFriendGetPublicKeyText(e.Code), e.Code)
+}
+func (e *FriendGetPublicKeyError) Is(target error) bool {
+	t, ok := target.(*FriendGetPublicKeyError)
+	return ok && t.Code == e.Code
+}
+
+var ErrFriendGetPublicKeyFriendNotFound = &FriendGetPublicKeyError{ERR_FRIEND_GET_PUBLIC_KEY_FRIEND_NOT_FOUND}
+
+// FriendGetLastOnlineError wraps a TOX_ERR_FRIEND_GET_LAST_ONLINE code.
+type FriendGetLastOnlineError struct{ Code int }
+
+func (e *FriendGetLastOnlineError) Error() string {
+	return errdesc("friend get last online", errFriendGetLastOnlineText(e.Code), e.Code)
+}
+func (e *FriendGetLastOnlineError) Is(target error) bool {
+	t, ok := target.(*FriendGetLastOnlineError)
+	return ok && t.Code == e.Code
+}
+
+var ErrFriendGetLastOnlineFriendNotFound = &FriendGetLastOnlineError{ERR_FRIEND_GET_LAST_ONLINE_FRIEND_NOT_FOUND}
+
+// FriendQueryError wraps a TOX_ERR_FRIEND_QUERY code.
+type FriendQueryError struct{ Code int }
+
+func (e *FriendQueryError) Error() string {
+	return errdesc("friend query", errFriendQueryText(e.Code), e.Code)
+}
+func (e *FriendQueryError) Is(target error) bool {
+	t, ok := target.(*FriendQueryError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFriendQueryNull           = &FriendQueryError{ERR_FRIEND_QUERY_NULL}
+	ErrFriendQueryFriendNotFound = &FriendQueryError{ERR_FRIEND_QUERY_FRIEND_NOT_FOUND}
+)
+
+// SetTypingError wraps a TOX_ERR_SET_TYPING code.
+type SetTypingError struct{ Code int }
+
+func (e *SetTypingError) Error() string {
+	return errdesc("set typing", errSetTypingText(e.Code), e.Code)
+}
+func (e *SetTypingError) Is(target error) bool {
+	t, ok := target.(*SetTypingError)
+	return ok && t.Code == e.Code
+}
+
+var ErrSetTypingFriendNotFound = &SetTypingError{ERR_SET_TYPING_FRIEND_NOT_FOUND}
+
+// FriendSendMessageError wraps a TOX_ERR_FRIEND_SEND_MESSAGE code.
+type FriendSendMessageError struct{ Code int }
+
+func (e *FriendSendMessageError) Error() string {
+	return errdesc("friend send message", errFriendSendMessageText(e.Code), e.Code)
+}
+func (e *FriendSendMessageError) Is(target error) bool {
+	t, ok := target.(*FriendSendMessageError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFriendSendMessageNull               = &FriendSendMessageError{ERR_FRIEND_SEND_MESSAGE_NULL}
+	ErrFriendSendMessageFriendNotFound     = &FriendSendMessageError{ERR_FRIEND_SEND_MESSAGE_FRIEND_NOT_FOUND}
+	ErrFriendSendMessageFriendNotConnected = &FriendSendMessageError{ERR_FRIEND_SEND_MESSAGE_FRIEND_NOT_CONNECTED}
+	ErrFriendSendMessageSendq              = &FriendSendMessageError{ERR_FRIEND_SEND_MESSAGE_SENDQ}
+	ErrFriendSendMessageTooLong            = &FriendSendMessageError{ERR_FRIEND_SEND_MESSAGE_TOO_LONG}
+	ErrFriendSendMessageEmpty              = &FriendSendMessageError{ERR_FRIEND_SEND_MESSAGE_EMPTY}
+)
+
+// FileControlError wraps a TOX_ERR_FILE_CONTROL code.
+type FileControlError struct{ Code int }
+
+func (e *FileControlError) Error() string {
+	return errdesc("file control", errFileControlText(e.Code), e.Code)
+}
+func (e *FileControlError) Is(target error) bool {
+	t, ok := target.(*FileControlError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFileControlFriendNotFound     = &FileControlError{ERR_FILE_CONTROL_FRIEND_NOT_FOUND}
+	ErrFileControlFriendNotConnected = &FileControlError{ERR_FILE_CONTROL_FRIEND_NOT_CONNECTED}
+	ErrFileControlNotFound           = &FileControlError{ERR_FILE_CONTROL_NOT_FOUND}
+	ErrFileControlNotPaused          = &FileControlError{ERR_FILE_CONTROL_NOT_PAUSED}
+	ErrFileControlDenied             = &FileControlError{ERR_FILE_CONTROL_DENIED}
+	ErrFileControlAlreadyPaused      = &FileControlError{ERR_FILE_CONTROL_ALREADY_PAUSED}
+	ErrFileControlSendq              = &FileControlError{ERR_FILE_CONTROL_SENDQ}
+)
+
+// FileSeekError wraps a TOX_ERR_FILE_SEEK code.
+type FileSeekError struct{ Code int }
+
+func (e *FileSeekError) Error() string { return errdesc("file seek", errFileSeekText(e.Code), e.Code) }
+func (e *FileSeekError) Is(target error) bool {
+	t, ok := target.(*FileSeekError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFileSeekFriendNotFound     = &FileSeekError{ERR_FILE_SEEK_FRIEND_NOT_FOUND}
+	ErrFileSeekFriendNotConnected = &FileSeekError{ERR_FILE_SEEK_FRIEND_NOT_CONNECTED}
+	ErrFileSeekNotFound           = &FileSeekError{ERR_FILE_SEEK_NOT_FOUND}
+	ErrFileSeekDenied             = &FileSeekError{ERR_FILE_SEEK_DENIED}
+	ErrFileSeekInvalidPosition    = &FileSeekError{ERR_FILE_SEEK_INVALID_POSITION}
+	ErrFileSeekSendq              = &FileSeekError{ERR_FILE_SEEK_SENDQ}
+)
+
+// FileGetError wraps a TOX_ERR_FILE_GET code.
+type FileGetError struct{ Code int }
+
+func (e *FileGetError) Error() string { return errdesc("file get", errFileGetText(e.Code), e.Code) }
+func (e *FileGetError) Is(target error) bool {
+	t, ok := target.(*FileGetError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFileGetNull           = &FileGetError{ERR_FILE_GET_NULL}
+	ErrFileGetFriendNotFound = &FileGetError{ERR_FILE_GET_FRIEND_NOT_FOUND}
+	ErrFileGetNotFound       = &FileGetError{ERR_FILE_GET_NOT_FOUND}
+)
+
+// FileSendError wraps a TOX_ERR_FILE_SEND code.
+type FileSendError struct{ Code int }
+
+func (e *FileSendError) Error() string { return errdesc("file send", errFileSendText(e.Code), e.Code) }
+func (e *FileSendError) Is(target error) bool {
+	t, ok := target.(*FileSendError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFileSendNull               = &FileSendError{ERR_FILE_SEND_NULL}
+	ErrFileSendFriendNotFound     = &FileSendError{ERR_FILE_SEND_FRIEND_NOT_FOUND}
+	ErrFileSendFriendNotConnected = &FileSendError{ERR_FILE_SEND_FRIEND_NOT_CONNECTED}
+	ErrFileSendNameTooLong        = &FileSendError{ERR_FILE_SEND_NAME_TOO_LONG}
+	ErrFileSendTooMany            = &FileSendError{ERR_FILE_SEND_TOO_MANY}
+)
+
+// FileSendChunkError wraps a TOX_ERR_FILE_SEND_CHUNK code.
+type FileSendChunkError struct{ Code int }
+
+func (e *FileSendChunkError) Error() string {
+	return errdesc("file send chunk", errFileSendChunkText(e.Code), e.Code)
+}
+func (e *FileSendChunkError) Is(target error) bool {
+	t, ok := target.(*FileSendChunkError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFileSendChunkNull               = &FileSendChunkError{ERR_FILE_SEND_CHUNK_NULL}
+	ErrFileSendChunkFriendNotFound     = &FileSendChunkError{ERR_FILE_SEND_CHUNK_FRIEND_NOT_FOUND}
+	ErrFileSendChunkFriendNotConnected = &FileSendChunkError{ERR_FILE_SEND_CHUNK_FRIEND_NOT_CONNECTED}
+	ErrFileSendChunkNotFound           = &FileSendChunkError{ERR_FILE_SEND_CHUNK_NOT_FOUND}
+	ErrFileSendChunkNotTransferring    = &FileSendChunkError{ERR_FILE_SEND_CHUNK_NOT_TRANSFERRING}
+	ErrFileSendChunkInvalidLength      = &FileSendChunkError{ERR_FILE_SEND_CHUNK_INVALID_LENGTH}
+	ErrFileSendChunkSendq              = &FileSendChunkError{ERR_FILE_SEND_CHUNK_SENDQ}
+	ErrFileSendChunkWrongPosition      = &FileSendChunkError{ERR_FILE_SEND_CHUNK_WRONG_POSITION}
+)
+
+// ConferenceNewError wraps a TOX_ERR_CONFERENCE_NEW code.
+type ConferenceNewError struct{ Code int }
+
+func (e *ConferenceNewError) Error() string {
+	return errdesc("conference new", errConferenceNewText(e.Code), e.Code)
+}
+func (e *ConferenceNewError) Is(target error) bool {
+	t, ok := target.(*ConferenceNewError)
+	return ok && t.Code == e.Code
+}
+
+var ErrConferenceNewInit = &ConferenceNewError{ERR_CONFERENCE_NEW_INIT}
+
+// ConferenceDeleteError wraps a TOX_ERR_CONFERENCE_DELETE code.
+type ConferenceDeleteError struct{ Code int }
+
+func (e *ConferenceDeleteError) Error() string {
+	return errdesc("conference delete", errConferenceDeleteText(e.Code), e.Code)
+}
+func (e *ConferenceDeleteError) Is(target error) bool {
+	t, ok := target.(*ConferenceDeleteError)
+	return ok && t.Code == e.Code
+}
+
+var ErrConferenceDeleteConferenceNotFound = &ConferenceDeleteError{ERR_CONFERENCE_DELETE_CONFERENCE_NOT_FOUND}
+
+// ConferencePeerQueryError wraps a TOX_ERR_CONFERENCE_PEER_QUERY code.
+type ConferencePeerQueryError struct{ Code int }
+
+func (e *ConferencePeerQueryError) Error() string {
+	return errdesc("conference peer query", errConferencePeerQueryText(e.Code), e.Code)
+}
+func (e *ConferencePeerQueryError) Is(target error) bool {
+	t, ok := target.(*ConferencePeerQueryError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrConferencePeerQueryConferenceNotFound = &ConferencePeerQueryError{ERR_CONFERENCE_PEER_QUERY_CONFERENCE_NOT_FOUND}
+	ErrConferencePeerQueryPeerNotFound       = &ConferencePeerQueryError{ERR_CONFERENCE_PEER_QUERY_PEER_NOT_FOUND}
+	ErrConferencePeerQueryNoConnection       = &ConferencePeerQueryError{ERR_CONFERENCE_PEER_QUERY_NO_CONNECTION}
+)
+
+// ConferenceInviteError wraps a TOX_ERR_CONFERENCE_INVITE code.
+type ConferenceInviteError struct{ Code int }
+
+func (e *ConferenceInviteError) Error() string {
+	return errdesc("conference invite", errConferenceInviteText(e.Code), e.Code)
+}
+func (e *ConferenceInviteError) Is(target error) bool {
+	t, ok := target.(*ConferenceInviteError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrConferenceInviteConferenceNotFound = &ConferenceInviteError{ERR_CONFERENCE_INVITE_CONFERENCE_NOT_FOUND}
+	ErrConferenceInviteFailSend           = &ConferenceInviteError{ERR_CONFERENCE_INVITE_FAIL_SEND}
+	ErrConferenceInviteNoConnection       = &ConferenceInviteError{ERR_CONFERENCE_INVITE_NO_CONNECTION}
+)
+
+// ConferenceJoinError wraps a TOX_ERR_CONFERENCE_JOIN code.
+type ConferenceJoinError struct{ Code int }
+
+func (e *ConferenceJoinError) Error() string {
+	return errdesc("conference join", errConferenceJoinText(e.Code), e.Code)
+}
+func (e *ConferenceJoinError) Is(target error) bool {
+	t, ok := target.(*ConferenceJoinError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrConferenceJoinInvalidLength  = &ConferenceJoinError{ERR_CONFERENCE_JOIN_INVALID_LENGTH}
+	ErrConferenceJoinWrongType      = &ConferenceJoinError{ERR_CONFERENCE_JOIN_WRONG_TYPE}
+	ErrConferenceJoinFriendNotFound = &ConferenceJoinError{ERR_CONFERENCE_JOIN_FRIEND_NOT_FOUND}
+	ErrConferenceJoinDuplicate      = &ConferenceJoinError{ERR_CONFERENCE_JOIN_DUPLICATE}
+	ErrConferenceJoinInitFail       = &ConferenceJoinError{ERR_CONFERENCE_JOIN_INIT_FAIL}
+	ErrConferenceJoinFailSend       = &ConferenceJoinError{ERR_CONFERENCE_JOIN_FAIL_SEND}
+)
+
+// ConferenceSendMessageError wraps a TOX_ERR_CONFERENCE_SEND_MESSAGE code.
+type ConferenceSendMessageError struct{ Code int }
+
+func (e *ConferenceSendMessageError) Error() string {
+	return errdesc("conference send message", errConferenceSendMessageText(e.Code), e.Code)
+}
+func (e *ConferenceSendMessageError) Is(target error) bool {
+	t, ok := target.(*ConferenceSendMessageError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrConferenceSendMessageConferenceNotFound = &ConferenceSendMessageError{ERR_CONFERENCE_SEND_MESSAGE_CONFERENCE_NOT_FOUND}
+	ErrConferenceSendMessageTooLong            = &ConferenceSendMessageError{ERR_CONFERENCE_SEND_MESSAGE_TOO_LONG}
+	ErrConferenceSendMessageNoConnection       = &ConferenceSendMessageError{ERR_CONFERENCE_SEND_MESSAGE_NO_CONNECTION}
+	ErrConferenceSendMessageFailSend           = &ConferenceSendMessageError{ERR_CONFERENCE_SEND_MESSAGE_FAIL_SEND}
+)
+
+// ConferenceTitleError wraps a TOX_ERR_CONFERENCE_TITLE code.
+type ConferenceTitleError struct{ Code int }
+
+func (e *ConferenceTitleError) Error() string {
+	return errdesc("conference title", errConferenceTitleText(e.Code), e.Code)
+}
+func (e *ConferenceTitleError) Is(target error) bool {
+	t, ok := target.(*ConferenceTitleError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrConferenceTitleConferenceNotFound = &ConferenceTitleError{ERR_CONFERENCE_TITLE_CONFERENCE_NOT_FOUND}
+	ErrConferenceTitleInvalidLength      = &ConferenceTitleError{ERR_CONFERENCE_TITLE_INVALID_LENGTH}
+	ErrConferenceTitleFailSend           = &ConferenceTitleError{ERR_CONFERENCE_TITLE_FAIL_SEND}
+)
+
+// ConferenceGetTypeError wraps a TOX_ERR_CONFERENCE_GET_TYPE code.
+type ConferenceGetTypeError struct{ Code int }
+
+func (e *ConferenceGetTypeError) Error() string {
+	return errdesc("conference get type", errConferenceGetTypeText(e.Code), e.Code)
+}
+func (e *ConferenceGetTypeError) Is(target error) bool {
+	t, ok := target.(*ConferenceGetTypeError)
+	return ok && t.Code == e.Code
+}
+
+var ErrConferenceGetTypeConferenceNotFound = &ConferenceGetTypeError{ERR_CONFERENCE_GET_TYPE_CONFERENCE_NOT_FOUND}
+
+// ConferenceByIdError wraps a TOX_ERR_CONFERENCE_BY_ID code.
+type ConferenceByIdError struct{ Code int }
+
+func (e *ConferenceByIdError) Error() string {
+	return errdesc("conference by id", errConferenceByIdText(e.Code), e.Code)
+}
+func (e *ConferenceByIdError) Is(target error) bool {
+	t, ok := target.(*ConferenceByIdError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrConferenceByIdNull     = &ConferenceByIdError{ERR_CONFERENCE_BY_ID_NULL}
+	ErrConferenceByIdNotFound = &ConferenceByIdError{ERR_CONFERENCE_BY_ID_NOT_FOUND}
+)
+
+// ConferenceByUidError wraps a TOX_ERR_CONFERENCE_BY_UID code.
+type ConferenceByUidError struct{ Code int }
+
+func (e *ConferenceByUidError) Error() string {
+	return errdesc("conference by uid", errConferenceByUidText(e.Code), e.Code)
+}
+func (e *ConferenceByUidError) Is(target error) bool {
+	t, ok := target.(*ConferenceByUidError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrConferenceByUidNull     = &ConferenceByUidError{ERR_CONFERENCE_BY_UID_NULL}
+	ErrConferenceByUidNotFound = &ConferenceByUidError{ERR_CONFERENCE_BY_UID_NOT_FOUND}
+)
+
+// FriendCustomPacketError wraps a TOX_ERR_FRIEND_CUSTOM_PACKET code.
+type FriendCustomPacketError struct{ Code int }
+
+func (e *FriendCustomPacketError) Error() string {
+	return errdesc("friend custom packet", errFriendCustomPacketText(e.Code), e.Code)
+}
+func (e *FriendCustomPacketError) Is(target error) bool {
+	t, ok := target.(*FriendCustomPacketError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrFriendCustomPacketNull               = &FriendCustomPacketError{ERR_FRIEND_CUSTOM_PACKET_NULL}
+	ErrFriendCustomPacketFriendNotFound     = &FriendCustomPacketError{ERR_FRIEND_CUSTOM_PACKET_FRIEND_NOT_FOUND}
+	ErrFriendCustomPacketFriendNotConnected = &FriendCustomPacketError{ERR_FRIEND_CUSTOM_PACKET_FRIEND_NOT_CONNECTED}
+	ErrFriendCustomPacketInvalid            = &FriendCustomPacketError{ERR_FRIEND_CUSTOM_PACKET_INVALID}
+	ErrFriendCustomPacketEmpty              = &FriendCustomPacketError{ERR_FRIEND_CUSTOM_PACKET_EMPTY}
+	ErrFriendCustomPacketTooLong            = &FriendCustomPacketError{ERR_FRIEND_CUSTOM_PACKET_TOO_LONG}
+	ErrFriendCustomPacketSendq              = &FriendCustomPacketError{ERR_FRIEND_CUSTOM_PACKET_SENDQ}
+)
+
+// GetPortError wraps a TOX_ERR_GET_PORT code.
+type GetPortError struct{ Code int }
+
+func (e *GetPortError) Error() string { return errdesc("get port", errGetPortText(e.Code), e.Code) }
+func (e *GetPortError) Is(target error) bool {
+	t, ok := target.(*GetPortError)
+	return ok && t.Code == e.Code
+}
+
+var ErrGetPortNotBound = &GetPortError{ERR_GET_PORT_NOT_BOUND}
+
+// AVNewError wraps a TOXAV_ERR_NEW code.
+type AVNewError struct{ Code int }
+
+func (e *AVNewError) Error() string { return errdesc("toxav new", avErrNewText(e.Code), e.Code) }
+func (e *AVNewError) Is(target error) bool {
+	t, ok := target.(*AVNewError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrAVNewNull     = &AVNewError{AV_ERR_NEW_NULL}
+	ErrAVNewMalloc   = &AVNewError{AV_ERR_NEW_MALLOC}
+	ErrAVNewMultiple = &AVNewError{AV_ERR_NEW_MULTIPLE}
+)
+
+// AVCallError wraps a TOXAV_ERR_CALL code.
+type AVCallError struct{ Code int }
+
+func (e *AVCallError) Error() string { return errdesc("toxav call", avErrCallText(e.Code), e.Code) }
+func (e *AVCallError) Is(target error) bool {
+	t, ok := target.(*AVCallError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrAVCallMalloc              = &AVCallError{AV_ERR_CALL_MALLOC}
+	ErrAVCallSync                = &AVCallError{AV_ERR_CALL_SYNC}
+	ErrAVCallFriendNotFound      = &AVCallError{AV_ERR_CALL_FRIEND_NOT_FOUND}
+	ErrAVCallFriendNotConnected  = &AVCallError{AV_ERR_CALL_FRIEND_NOT_CONNECTED}
+	ErrAVCallFriendAlreadyInCall = &AVCallError{AV_ERR_CALL_FRIEND_ALREADY_IN_CALL}
+	ErrAVCallInvalidBitRate      = &AVCallError{AV_ERR_CALL_INVALID_BIT_RATE}
+)
+
+// AVAnswerError wraps a TOXAV_ERR_ANSWER code.
+type AVAnswerError struct{ Code int }
+
+func (e *AVAnswerError) Error() string {
+	return errdesc("toxav answer", avErrAnswerText(e.Code), e.Code)
+}
+func (e *AVAnswerError) Is(target error) bool {
+	t, ok := target.(*AVAnswerError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrAVAnswerSync                = &AVAnswerError{AV_ERR_ANSWER_SYNC}
+	ErrAVAnswerCodecInitialization = &AVAnswerError{AV_ERR_ANSWER_CODEC_INITIALIZATION}
+	ErrAVAnswerFriendNotFound      = &AVAnswerError{AV_ERR_ANSWER_FRIEND_NOT_FOUND}
+	ErrAVAnswerFriendNotCalling    = &AVAnswerError{AV_ERR_ANSWER_FRIEND_NOT_CALLING}
+	ErrAVAnswerInvalidBitRate      = &AVAnswerError{AV_ERR_ANSWER_INVALID_BIT_RATE}
+)
+
+// AVCallControlError wraps a TOXAV_ERR_CALL_CONTROL code.
+type AVCallControlError struct{ Code int }
+
+func (e *AVCallControlError) Error() string {
+	return errdesc("toxav call control", avErrCallControlText(e.Code), e.Code)
+}
+func (e *AVCallControlError) Is(target error) bool {
+	t, ok := target.(*AVCallControlError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrAVCallControlSync              = &AVCallControlError{AV_ERR_CALL_CONTROL_SYNC}
+	ErrAVCallControlFriendNotFound    = &AVCallControlError{AV_ERR_CALL_CONTROL_FRIEND_NOT_FOUND}
+	ErrAVCallControlFriendNotInCall   = &AVCallControlError{AV_ERR_CALL_CONTROL_FRIEND_NOT_IN_CALL}
+	ErrAVCallControlInvalidTransition = &AVCallControlError{AV_ERR_CALL_CONTROL_INVALID_TRANSITION}
+)
+
+// AVBitRateSetError wraps a TOXAV_ERR_BIT_RATE_SET code.
+type AVBitRateSetError struct{ Code int }
+
+func (e *AVBitRateSetError) Error() string {
+	return errdesc("toxav bit rate set", avErrBitRateSetText(e.Code), e.Code)
+}
+func (e *AVBitRateSetError) Is(target error) bool {
+	t, ok := target.(*AVBitRateSetError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrAVBitRateSetSync            = &AVBitRateSetError{AV_ERR_BIT_RATE_SET_SYNC}
+	ErrAVBitRateSetInvalidBitRate  = &AVBitRateSetError{AV_ERR_BIT_RATE_SET_INVALID_BIT_RATE}
+	ErrAVBitRateSetFriendNotFound  = &AVBitRateSetError{AV_ERR_BIT_RATE_SET_FRIEND_NOT_FOUND}
+	ErrAVBitRateSetFriendNotInCall = &AVBitRateSetError{AV_ERR_BIT_RATE_SET_FRIEND_NOT_IN_CALL}
+)
+
+// AVSendFrameError wraps a TOXAV_ERR_SEND_FRAME code.
+type AVSendFrameError struct{ Code int }
+
+func (e *AVSendFrameError) Error() string {
+	return errdesc("toxav send frame", avErrSendFrameText(e.Code), e.Code)
+}
+func (e *AVSendFrameError) Is(target error) bool {
+	t, ok := target.(*AVSendFrameError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrAVSendFrameNull                = &AVSendFrameError{AV_ERR_SEND_FRAME_NULL}
+	ErrAVSendFrameFriendNotFound      = &AVSendFrameError{AV_ERR_SEND_FRAME_FRIEND_NOT_FOUND}
+	ErrAVSendFrameFriendNotInCall     = &AVSendFrameError{AV_ERR_SEND_FRAME_FRIEND_NOT_IN_CALL}
+	ErrAVSendFrameSync                = &AVSendFrameError{AV_ERR_SEND_FRAME_SYNC}
+	ErrAVSendFrameInvalid             = &AVSendFrameError{AV_ERR_SEND_FRAME_INVALID}
+	ErrAVSendFramePayloadTypeDisabled = &AVSendFrameError{AV_ERR_SEND_FRAME_PAYLOAD_TYPE_DISABLED}
+	ErrAVSendFrameRtpFailed           = &AVSendFrameError{AV_ERR_SEND_FRAME_RTP_FAILED}
+)
+
+// KeyDerivationError wraps a TOX_ERR_KEY_DERIVATION code.
+type KeyDerivationError struct{ Code int }
+
+func (e *KeyDerivationError) Error() string {
+	return errdesc("key derivation", errKeyDerivationText(e.Code), e.Code)
+}
+func (e *KeyDerivationError) Is(target error) bool {
+	t, ok := target.(*KeyDerivationError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrKeyDerivationNull   = &KeyDerivationError{ERR_KEY_DERIVATION_NULL}
+	ErrKeyDerivationFailed = &KeyDerivationError{ERR_KEY_DERIVATION_FAILED}
+)
+
+// EncryptionError wraps a TOX_ERR_ENCRYPTION code.
+type EncryptionError struct{ Code int }
+
+func (e *EncryptionError) Error() string {
+	return errdesc("encryption", errEncryptionText(e.Code), e.Code)
+}
+func (e *EncryptionError) Is(target error) bool {
+	t, ok := target.(*EncryptionError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrEncryptionNull                = &EncryptionError{ERR_ENCRYPTION_NULL}
+	ErrEncryptionKeyDerivationFailed = &EncryptionError{ERR_ENCRYPTION_KEY_DERIVATION_FAILED}
+	ErrEncryptionFailed              = &EncryptionError{ERR_ENCRYPTION_FAILED}
+)
+
+// DecryptionError wraps a TOX_ERR_DECRYPTION code.
+type DecryptionError struct{ Code int }
+
+func (e *DecryptionError) Error() string {
+	return errdesc("decryption", errDecryptionText(e.Code), e.Code)
+}
+func (e *DecryptionError) Is(target error) bool {
+	t, ok := target.(*DecryptionError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrDecryptionNull                = &DecryptionError{ERR_DECRYPTION_NULL}
+	ErrDecryptionInvalidLength       = &DecryptionError{ERR_DECRYPTION_INVALID_LENGTH}
+	ErrDecryptionBadFormat           = &DecryptionError{ERR_DECRYPTION_BAD_FORMAT}
+	ErrDecryptionKeyDerivationFailed = &DecryptionError{ERR_DECRYPTION_KEY_DERIVATION_FAILED}
+	ErrDecryptionFailed              = &DecryptionError{ERR_DECRYPTION_FAILED}
+)
+
+// GetSaltError wraps a TOX_ERR_GET_SALT code.
+type GetSaltError struct{ Code int }
+
+func (e *GetSaltError) Error() string { return errdesc("get salt", errGetSaltText(e.Code), e.Code) }
+func (e *GetSaltError) Is(target error) bool {
+	t, ok := target.(*GetSaltError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrGetSaltNull      = &GetSaltError{ERR_GET_SALT_NULL}
+	ErrGetSaltBadFormat = &GetSaltError{ERR_GET_SALT_BAD_FORMAT}
+)
@@ -0,0 +1,339 @@
+package tox
+
+/*
+#include <tox/tox.h>
+
+typedef const uint8_t ecuint8_t;
+void callbackFriendRequestWrapperForC(Tox *, ecuint8_t *, ecuint8_t *, size_t, void*);
+void callbackFriendMessageWrapperForC(Tox *, uint32_t, Tox_Message_Type, ecuint8_t*, size_t, void*);
+void callbackFriendNameWrapperForC(Tox *, uint32_t, ecuint8_t*, size_t, void*);
+void callbackFriendStatusMessageWrapperForC(Tox *, uint32_t, ecuint8_t*, size_t, void*);
+void callbackFriendStatusWrapperForC(Tox *, uint32_t, Tox_User_Status, void*);
+void callbackFriendConnectionStatusWrapperForC(Tox *, uint32_t, Tox_Connection, void*);
+void callbackFriendTypingWrapperForC(Tox *, uint32_t, uint8_t, void*);
+void callbackFriendReadReceiptWrapperForC(Tox *, uint32_t, uint32_t, void*);
+void callbackFriendLossyPacketWrapperForC(Tox *, uint32_t, ecuint8_t*, size_t, void*);
+void callbackFriendLosslessPacketWrapperForC(Tox *, uint32_t, ecuint8_t*, size_t, void*);
+void callbackSelfConnectionStatusWrapperForC(Tox *, int, void*);
+void callbackFileRecvControlWrapperForC(Tox *, uint32_t, uint32_t, Tox_File_Control, void *);
+void callbackFileRecvWrapperForC(Tox *, uint32_t, uint32_t, uint32_t, uint64_t, ecuint8_t *, size_t, void *);
+void callbackFileRecvChunkWrapperForC(Tox *, uint32_t, uint32_t, uint64_t, ecuint8_t *, size_t, void *);
+void callbackFileChunkRequestWrapperForC(Tox *, uint32_t, uint32_t, uint64_t, size_t, void *);
+void callbackConferenceInviteWrapperForC(Tox*, uint32_t, Tox_Conference_Type, ecuint8_t *, size_t, void *);
+void callbackConferenceMessageWrapperForC(Tox *, uint32_t, uint32_t, Tox_Message_Type, ecuint8_t *, size_t, void *);
+void callbackConferenceTitleWrapperForC(Tox*, uint32_t, uint32_t, ecuint8_t*, size_t, void*);
+void callbackConferencePeerNameWrapperForC(Tox*, uint32_t, uint32_t, ecuint8_t*, size_t, void*);
+void callbackConferencePeerListChangedWrapperForC(Tox*, uint32_t, void*);
+
+// fix nouse compile warning
+static inline __attribute__((__unused__)) void fixnouseevents(void) {
+}
+
+*/
+import "C"
+import (
+	"errors"
+	"sync"
+)
+
+// Event is a value delivered on the channel returned by Tox.Events.
+// It is implemented only by the *Event types of this package, so a type
+// switch over them is exhaustive.
+type Event interface {
+	isEvent()
+}
+
+type FriendRequestEvent struct {
+	PublicKey string
+	Message   string
+}
+
+type FriendMessageEvent struct {
+	FriendNumber uint32
+	Type         MessageType
+	Message      string
+}
+
+type FriendNameEvent struct {
+	FriendNumber uint32
+	Name         string
+}
+
+type FriendStatusMessageEvent struct {
+	FriendNumber  uint32
+	StatusMessage string
+}
+
+type FriendStatusEvent struct {
+	FriendNumber uint32
+	Status       UserStatus
+}
+
+type FriendConnectionStatusEvent struct {
+	FriendNumber uint32
+	Status       ConnectionType
+}
+
+type FriendTypingEvent struct {
+	FriendNumber uint32
+	IsTyping     bool
+}
+
+type FriendReadReceiptEvent struct {
+	FriendNumber uint32
+	Receipt      uint32
+}
+
+type FriendLossyPacketEvent struct {
+	FriendNumber uint32
+	Data         []byte
+}
+
+type FriendLosslessPacketEvent struct {
+	FriendNumber uint32
+	Data         []byte
+}
+
+type SelfConnectionStatusEvent struct {
+	Status ConnectionType
+}
+
+type FileRecvControlEvent struct {
+	FriendNumber uint32
+	FileNumber   uint32
+	Control      FileControlType
+}
+
+type FileRecvEvent struct {
+	FriendNumber uint32
+	FileNumber   uint32
+	Kind         FileKind
+	FileSize     uint64
+	FileName     string
+}
+
+type FileRecvChunkEvent struct {
+	FriendNumber uint32
+	FileNumber   uint32
+	Position     uint64
+	Data         []byte
+}
+
+type FileChunkRequestEvent struct {
+	FriendNumber uint32
+	FileNumber   uint32
+	Position     uint64
+	Length       int
+}
+
+type ConferenceInviteEvent struct {
+	FriendNumber uint32
+	Type         GroupchatType
+	Cookie       string
+}
+
+type ConferenceMessageEvent struct {
+	GroupNumber uint32
+	PeerNumber  uint32
+	Type        MessageType
+	Message     string
+}
+
+type ConferenceTitleEvent struct {
+	GroupNumber uint32
+	PeerNumber  uint32
+	Title       string
+}
+
+type ConferencePeerNameEvent struct {
+	GroupNumber uint32
+	PeerNumber  uint32
+	Name        string
+}
+
+type ConferencePeerListChangedEvent struct {
+	GroupNumber uint32
+}
+
+func (FriendRequestEvent) isEvent()             {}
+func (FriendMessageEvent) isEvent()             {}
+func (FriendNameEvent) isEvent()                {}
+func (FriendStatusMessageEvent) isEvent()       {}
+func (FriendStatusEvent) isEvent()              {}
+func (FriendConnectionStatusEvent) isEvent()    {}
+func (FriendTypingEvent) isEvent()              {}
+func (FriendReadReceiptEvent) isEvent()         {}
+func (FriendLossyPacketEvent) isEvent()         {}
+func (FriendLosslessPacketEvent) isEvent()      {}
+func (SelfConnectionStatusEvent) isEvent()      {}
+func (FileRecvControlEvent) isEvent()           {}
+func (FileRecvEvent) isEvent()                  {}
+func (FileRecvChunkEvent) isEvent()             {}
+func (FileChunkRequestEvent) isEvent()          {}
+func (ConferenceInviteEvent) isEvent()          {}
+func (ConferenceMessageEvent) isEvent()         {}
+func (ConferenceTitleEvent) isEvent()           {}
+func (ConferencePeerNameEvent) isEvent()        {}
+func (ConferencePeerListChangedEvent) isEvent() {}
+
+// EventOverflowPolicy decides what happens when the consumer of Tox.Events
+// falls behind and the channel buffer is full.
+type EventOverflowPolicy int
+
+const (
+	// block the iterate loop until the consumer catches up
+	EVENT_OVERFLOW_BLOCK EventOverflowPolicy = iota
+	// discard the oldest buffered event to make room for the new one
+	EVENT_OVERFLOW_DROP_OLDEST
+	// close the channel, EventsErr then reports ErrEventOverflow
+	EVENT_OVERFLOW_ERROR
+)
+
+var ErrEventOverflow = errors.New("event stream overflow")
+
+type eventStream struct {
+	mu     sync.Mutex
+	ch     chan Event
+	done   chan struct{}
+	policy EventOverflowPolicy
+	closed bool
+	err    error
+}
+
+func newEventStream(bufferSize int, policy EventOverflowPolicy) *eventStream {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	// dropping or failing needs at least one slot to make sense
+	if bufferSize == 0 && policy != EVENT_OVERFLOW_BLOCK {
+		bufferSize = 1
+	}
+	return &eventStream{
+		ch:     make(chan Event, bufferSize),
+		done:   make(chan struct{}),
+		policy: policy,
+	}
+}
+
+func (this *eventStream) push(evt Event) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.closed {
+		return
+	}
+
+	switch this.policy {
+	case EVENT_OVERFLOW_DROP_OLDEST:
+		for {
+			select {
+			case this.ch <- evt:
+				return
+			default:
+			}
+			select {
+			case <-this.ch:
+			default:
+			}
+		}
+	case EVENT_OVERFLOW_ERROR:
+		select {
+		case this.ch <- evt:
+		default:
+			this.err = ErrEventOverflow
+			this.closeLocked()
+		}
+	default:
+		select {
+		case this.ch <- evt:
+		case <-this.done:
+		}
+	}
+}
+
+// close is called by Kill.
+func (this *eventStream) close() {
+	// wake up a blocked push before taking the lock it holds
+	select {
+	case <-this.done:
+	default:
+		close(this.done)
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if !this.closed {
+		this.err = ErrKilled
+	}
+	this.closeLocked()
+}
+
+func (this *eventStream) closeLocked() {
+	if this.closed {
+		return
+	}
+	this.closed = true
+	close(this.ch)
+}
+
+// Events returns a channel carrying every toxcore event as a typed Event,
+// as an alternative to registering one callback per event. Events are
+// queued during Iterate exactly like callbacks and are sent after it
+// released the lock, in the same order. The first call decides the buffer
+// size and the overflow policy; later calls return the same channel.
+// The channel is closed by Kill, or on overflow with EVENT_OVERFLOW_ERROR.
+// On a killed instance it is closed already.
+func (this *Tox) Events(bufferSize int, policy EventOverflowPolicy) <-chan Event {
+	this.lock()
+	defer this.unlock()
+
+	if this.evstream == nil {
+		this.evstream = newEventStream(bufferSize, policy)
+		if this.toxcore == nil {
+			this.evstream.close()
+		} else {
+			this.enableEventCallbacks()
+		}
+	}
+	return this.evstream.ch
+}
+
+// EventsErr returns why the Events channel was closed: ErrKilled, or
+// ErrEventOverflow. It is nil while the channel is open.
+func (this *Tox) EventsErr() error {
+	if this.evstream == nil {
+		return nil
+	}
+	this.evstream.mu.Lock()
+	defer this.evstream.mu.Unlock()
+	return this.evstream.err
+}
+
+func (this *Tox) putevt(evt Event) {
+	if this.evstream == nil {
+		return
+	}
+	evstream := this.evstream
+	this.putcbevts(func() { evstream.push(evt) })
+}
+
+func (this *Tox) enableEventCallbacks() {
+	C.tox_callback_friend_request(this.toxcore, (*C.tox_friend_request_cb)(C.callbackFriendRequestWrapperForC))
+	C.tox_callback_friend_message(this.toxcore, (*C.tox_friend_message_cb)(C.callbackFriendMessageWrapperForC))
+	C.tox_callback_friend_name(this.toxcore, (*C.tox_friend_name_cb)(C.callbackFriendNameWrapperForC))
+	C.tox_callback_friend_status_message(this.toxcore, (*C.tox_friend_status_message_cb)(C.callbackFriendStatusMessageWrapperForC))
+	C.tox_callback_friend_status(this.toxcore, (*C.tox_friend_status_cb)(C.callbackFriendStatusWrapperForC))
+	C.tox_callback_friend_connection_status(this.toxcore, (*C.tox_friend_connection_status_cb)(C.callbackFriendConnectionStatusWrapperForC))
+	C.tox_callback_friend_typing(this.toxcore, (*C.tox_friend_typing_cb)(C.callbackFriendTypingWrapperForC))
+	C.tox_callback_friend_read_receipt(this.toxcore, (*C.tox_friend_read_receipt_cb)(C.callbackFriendReadReceiptWrapperForC))
+	C.tox_callback_friend_lossy_packet(this.toxcore, (*C.tox_friend_lossy_packet_cb)(C.callbackFriendLossyPacketWrapperForC))
+	C.tox_callback_friend_lossless_packet(this.toxcore, (*C.tox_friend_lossless_packet_cb)(C.callbackFriendLosslessPacketWrapperForC))
+	C.tox_callback_self_connection_status(this.toxcore, (*C.tox_self_connection_status_cb)(C.callbackSelfConnectionStatusWrapperForC))
+	C.tox_callback_file_recv_control(this.toxcore, (*C.tox_file_recv_control_cb)(C.callbackFileRecvControlWrapperForC))
+	C.tox_callback_file_recv(this.toxcore, (*C.tox_file_recv_cb)(C.callbackFileRecvWrapperForC))
+	C.tox_callback_file_recv_chunk(this.toxcore, (*C.tox_file_recv_chunk_cb)(C.callbackFileRecvChunkWrapperForC))
+	C.tox_callback_file_chunk_request(this.toxcore, (*C.tox_file_chunk_request_cb)(C.callbackFileChunkRequestWrapperForC))
+	C.tox_callback_conference_invite(this.toxcore, (*C.tox_conference_invite_cb)(C.callbackConferenceInviteWrapperForC))
+	C.tox_callback_conference_title(this.toxcore, (*C.tox_conference_title_cb)(C.callbackConferenceTitleWrapperForC))
+	C.tox_callback_conference_peer_name(this.toxcore, (*C.tox_conference_peer_name_cb)(C.callbackConferencePeerNameWrapperForC))
+	C.tox_callback_conference_peer_list_changed(this.toxcore, (*C.tox_conference_peer_list_changed_cb)(C.callbackConferencePeerListChangedWrapperForC))
+	this.setConferenceMessageCallback(true)
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	// "os"
 	"math/rand"
+	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -17,8 +20,10 @@ func init() {
 	log.SetFlags(log.Flags() | log.Lshortfile)
 }
 
-var server = []interface{}{
-	"205.185.116.116", uint16(33445), "A179B09749AC826FF01F37A9613F6B57118AE014D4196A0E1105A98F93A54702",
+var server = tox.BootNode{
+	Addr:   "205.185.116.116",
+	Port:   33445,
+	Pubkey: "A179B09749AC826FF01F37A9613F6B57118AE014D4196A0E1105A98F93A54702",
 }
 var fname = "./toxecho.data"
 var debug = false
@@ -26,32 +31,73 @@ var nickPrefix = "EchoBot."
 var statusText = "Send me text, file, audio, video."
 
 func main() {
-	opt := tox.NewToxOptions()
-	if tox.FileExist(fname) {
-		data, err := ioutil.ReadFile(fname)
-		if err != nil {
-			log.Println(err)
-		} else {
-			opt.Savedata_data = data
-			opt.Savedata_type = tox.SAVEDATA_TYPE_TOX_SAVE
+	// defaults, then $TOX_CONFIG, TOX_* variables and flags
+	cfg := tox.NewConfig()
+	cfg.Savedata = fname
+	cfg.TcpPort = 33445
+	if path := os.Getenv("TOX_CONFIG"); path != "" {
+		if err := cfg.LoadFile(path); err != nil {
+			log.Fatalln(err)
 		}
 	}
-	opt.Tcp_port = 33445
-	var t *tox.Tox
-	for i := 0; i < 5; i++ {
-		t = tox.NewTox(opt)
-		if t == nil {
-			opt.Tcp_port += 1
-		} else {
-			break
+	if err := cfg.LoadEnv(); err != nil {
+		log.Fatalln(err)
+	}
+	cfg.BindFlags(flag.CommandLine)
+	flag.BoolVar(&debug, "debug", debug, "log events")
+	nodesFile := flag.String("nodes", "", "node list in the nodes.tox.chat JSON format")
+	netinfo := flag.String("netinfo", "", "serve the network state on this address for toxnetinfo -url, e.g. 127.0.0.1:8080")
+	flag.Parse()
+	if len(cfg.Bootstrap) == 0 {
+		cfg.Bootstrap = []tox.BootNode{server}
+	}
+
+	profile, err := cfg.OpenProfile()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer profile.Close()
+	opt := profile.Options
+	opt.PortFallback = &tox.PortFallback{Tries: 4, TcpStep: 1}
+	if *netinfo != "" {
+		opt.ThreadSafe = true
+	}
+	t, err := tox.NewToxWithError(opt)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if *netinfo != "" {
+		h, err := tox.NetworkInfoHandler(t)
+		if err != nil {
+			log.Fatalln(err)
 		}
+		go func() {
+			log.Println("netinfo:", http.ListenAndServe(*netinfo, h))
+		}()
 	}
 
-	r, err := t.Bootstrap(server[0].(string), server[1].(uint16), server[2].(string))
-	r2, err := t.AddTcpRelay(server[0].(string), server[1].(uint16), server[2].(string))
+	// the configured nodes, -nodes from https://nodes.tox.chat/json, and the
+	// nodes that worked last time
+	boot := tox.NewBootstrapper(t)
+	boot.AddBootNodes(cfg.Bootstrap...)
+	if *nodesFile != "" {
+		if err := boot.LoadFile(*nodesFile); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	boot.CachePath = fname + ".nodes"
+	if err := boot.LoadCache(); err != nil {
+		log.Println(err)
+	}
+	n, err := boot.Bootstrap()
 	if debug {
-		log.Println("bootstrap:", r, err, r2)
+		log.Println("bootstrap:", n, err)
+	}
+	wd := tox.NewWatchdog(t, boot)
+	wd.OnEvent = func(ev tox.NetworkEvent) {
+		log.Println("network:", ev)
 	}
+	wd.Start()
 
 	pubkey := t.SelfGetPublicKey()
 	seckey := t.SelfGetSecretKey()
@@ -85,9 +131,12 @@ func main() {
 		log.Println("savedata:", sz, t)
 		log.Println("savedata", len(sd), t)
 	}
-	err = t.WriteSavedata(fname)
-	if debug {
+	// saves the name and status set above, and every friend added below
+	err = t.EnableAutosave(profile, 2*time.Second, func(err error) {
 		log.Println("savedata write:", err)
+	})
+	if err != nil {
+		log.Fatalln(err)
 	}
 
 	// add friend norequest
@@ -105,7 +154,7 @@ func main() {
 	}
 
 	// callbacks
-	t.CallbackSelfConnectionStatus(func(t *tox.Tox, status int, userData interface{}) {
+	t.CallbackSelfConnectionStatus(func(t *tox.Tox, status tox.ConnectionType, userData interface{}) {
 		if debug {
 			log.Println("on self conn status:", status, userData)
 		}
@@ -116,9 +165,6 @@ func main() {
 		if debug {
 			log.Println("on friend request:", num, err)
 		}
-		if num < 100000 {
-			t.WriteSavedata(fname)
-		}
 	}, nil)
 	t.CallbackFriendMessage(func(t *tox.Tox, friendNumber uint32, message string, userData interface{}) {
 		if debug {
@@ -129,13 +175,13 @@ func main() {
 			log.Println(n, err)
 		}
 	}, nil)
-	t.CallbackFriendConnectionStatus(func(t *tox.Tox, friendNumber uint32, status int, userData interface{}) {
+	t.CallbackFriendConnectionStatus(func(t *tox.Tox, friendNumber uint32, status tox.ConnectionType, userData interface{}) {
 		if debug {
 			friendId, err := t.FriendGetPublicKey(friendNumber)
 			log.Println("on friend connection status:", friendNumber, status, friendId, err)
 		}
 	}, nil)
-	t.CallbackFriendStatus(func(t *tox.Tox, friendNumber uint32, status int, userData interface{}) {
+	t.CallbackFriendStatus(func(t *tox.Tox, friendNumber uint32, status tox.UserStatus, userData interface{}) {
 		if debug {
 			friendId, err := t.FriendGetPublicKey(friendNumber)
 			log.Println("on friend status:", friendNumber, status, friendId, err)
@@ -163,7 +209,7 @@ func main() {
 			if data, ok := sendDatas[reqkey]; ok {
 				r, err := t.FileSendChunk(friendNumber, fileNumber, pos, data)
 				if err != nil {
-					if err.Error() == "toxcore error: 7" || err.Error() == "toxcore error: 8" {
+					if errors.Is(err, tox.ErrFileSendChunkSendq) || errors.Is(err, tox.ErrFileSendChunkWrongPosition) {
 					} else {
 						log.Println("file send chunk error:", err, r, reqkey)
 					}
@@ -186,7 +232,7 @@ func main() {
 	}
 
 	t.CallbackFileRecvControl(func(t *tox.Tox, friendNumber uint32, fileNumber uint32,
-		control int, userData interface{}) {
+		control tox.FileControlType, userData interface{}) {
 		if debug {
 			friendId, err := t.FriendGetPublicKey(friendNumber)
 			log.Println("on recv file control:", friendNumber, fileNumber, control, friendId, err)
@@ -206,7 +252,7 @@ func main() {
 			}
 		}
 	}, nil)
-	t.CallbackFileRecv(func(t *tox.Tox, friendNumber uint32, fileNumber uint32, kind uint32,
+	t.CallbackFileRecv(func(t *tox.Tox, friendNumber uint32, fileNumber uint32, kind tox.FileKind,
 		fileSize uint64, fileName string, userData interface{}) {
 		if debug {
 			friendId, err := t.FriendGetPublicKey(friendNumber)
@@ -277,7 +323,7 @@ func main() {
 			log.Println(err, r)
 		}
 	}, nil)
-	av.CallbackCallState(func(av *tox.ToxAV, friendNumber uint32, state uint32, userData interface{}) {
+	av.CallbackCallState(func(av *tox.ToxAV, friendNumber uint32, state tox.CallState, userData interface{}) {
 		if debug {
 			log.Println("on call state:", friendNumber, state)
 		}
@@ -307,61 +353,24 @@ func main() {
 		}
 	}, nil)
 
-	// toxav loops
+	// toxav and toxcore loops
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	avdone := make(chan struct{})
 	go func() {
-		shutdown := false
-		loopc := 0
-		itval := 0
-		for !shutdown {
-			iv := av.IterationInterval()
-			if iv != itval {
-				// wtf
-				if iv-itval > 20 || itval-iv > 20 {
-					log.Println("av itval changed:", itval, iv, iv-itval, itval-iv)
-				}
-				itval = iv
-			}
-
-			av.Iterate()
-			loopc += 1
-			time.Sleep(1000 * 50 * time.Microsecond)
+		defer close(avdone)
+		if err := av.Run(ctx); err != nil && err != context.Canceled {
+			log.Println("av loop:", err)
 		}
-
-		av.Kill()
 	}()
 
-	// toxcore loops
-	shutdown := false
-	loopc := 0
-	itval := 0
-	for !shutdown {
-		iv := t.IterationInterval()
-		if iv != itval {
-			if debug {
-				if itval-iv > 20 || iv-itval > 20 {
-					log.Println("tox itval changed:", itval, iv)
-				}
-			}
-			itval = iv
-		}
-
-		t.Iterate()
-		status := t.SelfGetConnectionStatus()
-		if loopc%5500 == 0 {
-			if status == 0 {
-				if debug {
-					fmt.Print(".")
-				}
-			} else {
-				if debug {
-					fmt.Print(status, ",")
-				}
-			}
-		}
-		loopc += 1
-		time.Sleep(1000 * 50 * time.Microsecond)
+	if err := t.Run(ctx); err != nil {
+		log.Println("tox loop:", err)
 	}
 
+	cancel()
+	<-avdone
+	av.Kill()
 	t.Kill()
 }
 
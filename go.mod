@@ -1,6 +1,6 @@
 module github.com/TokTok/go-toxcore-c
 
-go 1.12
+go 1.13
 
 require (
 	github.com/sasha-s/go-deadlock v0.3.5
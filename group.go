@@ -22,14 +22,14 @@ static inline __attribute__((__unused__)) void fixnousetoxgroup(void) {
 import "C"
 import (
 	"encoding/hex"
-	"errors"
+	"fmt"
 	"math"
 	"strings"
 	"unsafe"
 )
 
 // conference callback type
-type cb_conference_invite_ftype func(this *Tox, friendNumber uint32, itype uint8, cookie string, userData interface{})
+type cb_conference_invite_ftype func(this *Tox, friendNumber uint32, itype GroupchatType, cookie string, userData interface{})
 type cb_conference_message_ftype func(this *Tox, groupNumber uint32, peerNumber uint32, message string, userData interface{})
 
 type cb_conference_action_ftype func(this *Tox, groupNumber uint32, peerNumber uint32, action string, userData interface{})
@@ -42,144 +42,148 @@ type cb_conference_peer_list_changed_ftype func(this *Tox, groupNumber uint32, u
 //export callbackConferenceInviteWrapperForC
 func callbackConferenceInviteWrapperForC(m *C.Tox, a0 C.uint32_t, a1 C.Tox_Conference_Type, a2 *C.gcuint8_t, a3 C.size_t, a4 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_conference_invites {
-		cbfn := *(*cb_conference_invite_ftype)(cbfni)
-		data := C.GoBytes((unsafe.Pointer)(a2), C.int(a3))
-		cookie := strings.ToUpper(hex.EncodeToString(data))
-		this.putcbevts(func() { cbfn(this, uint32(a0), uint8(a1), cookie, ud) })
+	data := C.GoBytes((unsafe.Pointer)(a2), C.int(a3))
+	cookie := strings.ToUpper(hex.EncodeToString(data))
+	for _, cbe := range this.cb_conference_invites {
+		cbfn, ud := *(*cb_conference_invite_ftype)(cbe.fn), cbe.ud
+		this.putcbevts(func() { cbfn(this, uint32(a0), GroupchatType(a1), cookie, ud) })
 	}
+	this.putevt(ConferenceInviteEvent{uint32(a0), GroupchatType(a1), cookie})
 }
 
 func (this *Tox) CallbackConferenceInvite(cbfn cb_conference_invite_ftype, userData interface{}) {
 	this.CallbackConferenceInviteAdd(cbfn, userData)
 }
-func (this *Tox) CallbackConferenceInviteAdd(cbfn cb_conference_invite_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_conference_invites[cbfnp]; ok {
-		return
-	}
-	this.cb_conference_invites[cbfnp] = userData
-
-	C.tox_callback_conference_invite(this.toxcore, (*C.tox_conference_invite_cb)(C.callbackConferenceInviteWrapperForC))
+func (this *Tox) CallbackConferenceInviteAdd(cbfn cb_conference_invite_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_conference_invites, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_conference_invite(this.toxcore, (*C.tox_conference_invite_cb)(C.callbackConferenceInviteWrapperForC))
+		} else {
+			C.tox_callback_conference_invite(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackConferenceMessageWrapperForC
 func callbackConferenceMessageWrapperForC(m *C.Tox, a0 C.uint32_t, a1 C.uint32_t, mtype C.Tox_Message_Type, a2 *C.gcuint8_t, a3 C.size_t, a4 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	if int(mtype) == MESSAGE_TYPE_NORMAL {
-		for cbfni, ud := range this.cb_conference_messages {
-			cbfn := *(*cb_conference_message_ftype)(cbfni)
-			message := C.GoStringN((*C.char)(unsafe.Pointer(a2)), C.int(a3))
+	message := C.GoStringN((*C.char)(unsafe.Pointer(a2)), C.int(a3))
+	if MessageType(mtype) == MESSAGE_TYPE_NORMAL {
+		for _, cbe := range this.cb_conference_messages {
+			cbfn, ud := *(*cb_conference_message_ftype)(cbe.fn), cbe.ud
 			this.putcbevts(func() { cbfn(this, uint32(a0), uint32(a1), message, ud) })
 		}
 	} else {
-		for cbfni, ud := range this.cb_conference_actions {
-			cbfn := *(*cb_conference_action_ftype)(cbfni)
-			message := C.GoStringN((*C.char)(unsafe.Pointer(a2)), C.int(a3))
+		for _, cbe := range this.cb_conference_actions {
+			cbfn, ud := *(*cb_conference_action_ftype)(cbe.fn), cbe.ud
 			this.putcbevts(func() { cbfn(this, uint32(a0), uint32(a1), message, ud) })
 		}
 	}
+	this.putevt(ConferenceMessageEvent{uint32(a0), uint32(a1), MessageType(mtype), message})
 }
 
 func (this *Tox) CallbackConferenceMessage(cbfn cb_conference_message_ftype, userData interface{}) {
 	this.CallbackConferenceMessageAdd(cbfn, userData)
 }
-func (this *Tox) CallbackConferenceMessageAdd(cbfn cb_conference_message_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_conference_messages[cbfnp]; ok {
-		return
-	}
-	this.cb_conference_messages[cbfnp] = userData
-
-	if !this.cb_conference_message_setted {
-		this.cb_conference_message_setted = true
-
-		C.tox_callback_conference_message(this.toxcore, (*C.tox_conference_message_cb)(C.callbackConferenceMessageWrapperForC))
-	}
+func (this *Tox) CallbackConferenceMessageAdd(cbfn cb_conference_message_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_conference_messages, unsafe.Pointer(&cbfn), userData, this.setConferenceMessageCallback)
 }
 
 func (this *Tox) CallbackConferenceAction(cbfn cb_conference_action_ftype, userData interface{}) {
 	this.CallbackConferenceActionAdd(cbfn, userData)
 }
-func (this *Tox) CallbackConferenceActionAdd(cbfn cb_conference_action_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_conference_actions[cbfnp]; ok {
+func (this *Tox) CallbackConferenceActionAdd(cbfn cb_conference_action_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_conference_actions, unsafe.Pointer(&cbfn), userData, this.setConferenceMessageCallback)
+}
+
+// message and action listeners share one C callback
+func (this *Tox) setConferenceMessageCallback(on bool) {
+	if on == this.cb_conference_message_setted {
 		return
 	}
-	this.cb_conference_actions[cbfnp] = userData
-
-	if !this.cb_conference_message_setted {
-		this.cb_conference_message_setted = true
+	if !on && (len(this.cb_conference_messages) > 0 || len(this.cb_conference_actions) > 0) {
+		return
+	}
+	this.cb_conference_message_setted = on
+	if on {
 		C.tox_callback_conference_message(this.toxcore, (*C.tox_conference_message_cb)(C.callbackConferenceMessageWrapperForC))
+	} else {
+		C.tox_callback_conference_message(this.toxcore, nil)
 	}
 }
 
 //export callbackConferenceTitleWrapperForC
 func callbackConferenceTitleWrapperForC(m *C.Tox, a0 C.uint32_t, a1 C.uint32_t, a2 *C.gcuint8_t, a3 C.size_t, a4 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_conference_titles {
-		cbfn := *(*cb_conference_title_ftype)(cbfni)
-		title := C.GoStringN((*C.char)((unsafe.Pointer)(a2)), C.int(a3))
+	this.markDirty()
+	title := C.GoStringN((*C.char)((unsafe.Pointer)(a2)), C.int(a3))
+	for _, cbe := range this.cb_conference_titles {
+		cbfn, ud := *(*cb_conference_title_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, uint32(a0), uint32(a1), title, ud) })
 	}
+	this.putevt(ConferenceTitleEvent{uint32(a0), uint32(a1), title})
 }
 
 func (this *Tox) CallbackConferenceTitle(cbfn cb_conference_title_ftype, userData interface{}) {
 	this.CallbackConferenceTitleAdd(cbfn, userData)
 }
-func (this *Tox) CallbackConferenceTitleAdd(cbfn cb_conference_title_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_conference_titles[cbfnp]; ok {
-		return
-	}
-	this.cb_conference_titles[cbfnp] = userData
-
-	C.tox_callback_conference_title(this.toxcore, (*C.tox_conference_title_cb)(C.callbackConferenceTitleWrapperForC))
+func (this *Tox) CallbackConferenceTitleAdd(cbfn cb_conference_title_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_conference_titles, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_conference_title(this.toxcore, (*C.tox_conference_title_cb)(C.callbackConferenceTitleWrapperForC))
+		} else {
+			C.tox_callback_conference_title(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackConferencePeerNameWrapperForC
 func callbackConferencePeerNameWrapperForC(m *C.Tox, a0 C.uint32_t, a1 C.uint32_t, a2 *C.gcuint8_t, a3 C.size_t, a4 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_conference_peer_names {
-		cbfn := *(*cb_conference_peer_name_ftype)(cbfni)
-		peer_name := C.GoStringN((*C.char)((unsafe.Pointer)(a2)), C.int(a3))
+	this.markDirty()
+	peer_name := C.GoStringN((*C.char)((unsafe.Pointer)(a2)), C.int(a3))
+	for _, cbe := range this.cb_conference_peer_names {
+		cbfn, ud := *(*cb_conference_peer_name_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, uint32(a0), uint32(a1), peer_name, ud) })
 	}
+	this.putevt(ConferencePeerNameEvent{uint32(a0), uint32(a1), peer_name})
 }
 
 func (this *Tox) CallbackConferencePeerName(cbfn cb_conference_peer_name_ftype, userData interface{}) {
 	this.CallbackConferencePeerNameAdd(cbfn, userData)
 }
-func (this *Tox) CallbackConferencePeerNameAdd(cbfn cb_conference_peer_name_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_conference_peer_names[cbfnp]; ok {
-		return
-	}
-	this.cb_conference_peer_names[cbfnp] = userData
-
-	C.tox_callback_conference_peer_name(this.toxcore, (*C.tox_conference_peer_name_cb)(C.callbackConferencePeerNameWrapperForC))
+func (this *Tox) CallbackConferencePeerNameAdd(cbfn cb_conference_peer_name_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_conference_peer_names, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_conference_peer_name(this.toxcore, (*C.tox_conference_peer_name_cb)(C.callbackConferencePeerNameWrapperForC))
+		} else {
+			C.tox_callback_conference_peer_name(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackConferencePeerListChangedWrapperForC
 func callbackConferencePeerListChangedWrapperForC(m *C.Tox, a0 C.uint32_t, a1 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_conference_peer_list_changeds {
-		cbfn := *(*cb_conference_peer_list_changed_ftype)(cbfni)
+	this.markDirty()
+	for _, cbe := range this.cb_conference_peer_list_changeds {
+		cbfn, ud := *(*cb_conference_peer_list_changed_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, uint32(a0), ud) })
 	}
+	this.putevt(ConferencePeerListChangedEvent{uint32(a0)})
 }
 
 func (this *Tox) CallbackConferencePeerListChanged(cbfn cb_conference_peer_list_changed_ftype, userData interface{}) {
 	this.CallbackConferencePeerListChangedAdd(cbfn, userData)
 }
-func (this *Tox) CallbackConferencePeerListChangedAdd(cbfn cb_conference_peer_list_changed_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_conference_peer_list_changeds[cbfnp]; ok {
-		return
-	}
-	this.cb_conference_peer_list_changeds[cbfnp] = userData
-
-	C.tox_callback_conference_peer_list_changed(this.toxcore, (*C.tox_conference_peer_list_changed_cb)(C.callbackConferencePeerListChangedWrapperForC))
+func (this *Tox) CallbackConferencePeerListChangedAdd(cbfn cb_conference_peer_list_changed_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_conference_peer_list_changeds, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_conference_peer_list_changed(this.toxcore, (*C.tox_conference_peer_list_changed_cb)(C.callbackConferencePeerListChangedWrapperForC))
+		} else {
+			C.tox_callback_conference_peer_list_changed(this.toxcore, nil)
+		}
+	})
 }
 
 // methods tox_conference_*
@@ -190,8 +194,9 @@ func (this *Tox) ConferenceNew() (uint32, error) {
 	var cerr C.Tox_Err_Conference_New
 	r := C.tox_conference_new(this.toxcore, &cerr)
 	if r == C.UINT32_MAX {
-		return uint32(r), toxerrf("add group chat failed: %d", cerr)
+		return uint32(r), &ConferenceNewError{int(cerr)}
 	}
+	this.markDirty()
 
 	if this.hooks.ConferenceNew != nil {
 		this.hooks.ConferenceNew(uint32(r))
@@ -207,11 +212,12 @@ func (this *Tox) ConferenceDelete(groupNumber uint32) (int, error) {
 	r := C.tox_conference_delete(this.toxcore, _gn, &cerr)
 	if bool(r) == false {
 		this.unlock()
-		return 1, toxerrf("delete group chat failed:%d", cerr)
+		return 1, &ConferenceDeleteError{int(cerr)}
 	}
 	if _, ok := this.cb_audios[groupNumber]; ok {
 		delete(this.cb_audios, groupNumber)
 	}
+	this.markDirty()
 	this.unlock()
 
 	if this.hooks.ConferenceDelete != nil {
@@ -229,7 +235,7 @@ func (this *Tox) ConferencePeerGetName(groupNumber uint32, peerNumber uint32) (s
 	var cerr C.Tox_Err_Conference_Peer_Query
 	r := C.tox_conference_peer_get_name(this.toxcore, _gn, _pn, (*C.uint8_t)(&_name[0]), &cerr)
 	if r == false {
-		return "", toxerrf("get peer name failed: %d", cerr)
+		return "", &ConferencePeerQueryError{int(cerr)}
 	}
 
 	return C.GoString((*C.char)(safeptr(_name[:]))), nil
@@ -243,7 +249,7 @@ func (this *Tox) ConferencePeerGetPublicKey(groupNumber uint32, peerNumber uint3
 	var cerr C.Tox_Err_Conference_Peer_Query
 	r := C.tox_conference_peer_get_public_key(this.toxcore, _gn, _pn, (*C.uint8_t)(&_pubkey[0]), &cerr)
 	if r == false {
-		return "", toxerrf("get pubkey failed: %d", cerr)
+		return "", &ConferencePeerQueryError{int(cerr)}
 	}
 
 	pubkey := strings.ToUpper(hex.EncodeToString(_pubkey[:]))
@@ -262,29 +268,26 @@ func (this *Tox) ConferenceInvite(friendNumber uint32, groupNumber uint32) (int,
 	// and the call will return true, but only strange thing accurs
 	// so just precheck the friendNumber and then go
 	if !this.FriendExists(friendNumber) {
-		return -1, toxerrf("friend not exists: %d", friendNumber)
+		return -1, &FriendQueryError{ERR_FRIEND_QUERY_FRIEND_NOT_FOUND}
 	}
 
 	var cerr C.Tox_Err_Conference_Invite
 	r := C.tox_conference_invite(this.toxcore, _fn, _gn, &cerr)
 	if r == false {
-		return 0, toxerrf("conference invite failed: %d", cerr)
+		return 0, &ConferenceInviteError{int(cerr)}
 	}
 	return 1, nil
 }
 
 func (this *Tox) ConferenceJoin(friendNumber uint32, cookie string) (uint32, error) {
 	if cookie == "" || len(cookie) < 20 {
-		return 0, errors.New("Invalid cookie:" + cookie)
+		return 0, &ConferenceJoinError{ERR_CONFERENCE_JOIN_INVALID_LENGTH}
 	}
 
 	data, err := hex.DecodeString(cookie)
-	if err != nil {
-
-	}
 	var datlen = len(data)
-	if data == nil || datlen < 10 {
-		return 0, errors.New("Invalid data: " + cookie)
+	if err != nil || data == nil || datlen < 10 {
+		return 0, &ConferenceJoinError{ERR_CONFERENCE_JOIN_INVALID_LENGTH}
 	}
 
 	this.lock()
@@ -295,9 +298,10 @@ func (this *Tox) ConferenceJoin(friendNumber uint32, cookie string) (uint32, err
 	r := C.tox_conference_join(this.toxcore, _fn, (*C.uint8_t)(&data[0]), _length, &cerr)
 	if r == C.UINT32_MAX {
 		defer this.unlock()
-		return uint32(r), toxerrf("join group chat failed: %d", cerr)
+		return uint32(r), &ConferenceJoinError{int(cerr)}
 	}
 	defer this.unlock()
+	this.markDirty()
 
 	if this.hooks.ConferenceJoin != nil {
 		this.hooks.ConferenceJoin(friendNumber, uint32(r), cookie)
@@ -306,7 +310,7 @@ func (this *Tox) ConferenceJoin(friendNumber uint32, cookie string) (uint32, err
 	return uint32(r), nil
 }
 
-func (this *Tox) ConferenceSendMessage(groupNumber uint32, mtype int, message string) (int, error) {
+func (this *Tox) ConferenceSendMessage(groupNumber uint32, mtype MessageType, message string) (int, error) {
 	this.lock()
 	defer this.unlock()
 
@@ -318,13 +322,13 @@ func (this *Tox) ConferenceSendMessage(groupNumber uint32, mtype int, message st
 	case MESSAGE_TYPE_NORMAL:
 	case MESSAGE_TYPE_ACTION:
 	default:
-		return 0, toxerrf("Invalid message type: %d", mtype)
+		return 0, fmt.Errorf("%w: %d", ErrInvalidMessageType, mtype)
 	}
 
 	var cerr C.Tox_Err_Conference_Send_Message
 	r := C.tox_conference_send_message(this.toxcore, _gn, (C.Tox_Message_Type)(mtype), (*C.uint8_t)(&_message[0]), _length, &cerr)
 	if r == false {
-		return 0, toxerrf("group send message failed: %d", cerr)
+		return 0, &ConferenceSendMessageError{int(cerr)}
 	}
 	return 1, nil
 }
@@ -340,11 +344,9 @@ func (this *Tox) ConferenceSetTitle(groupNumber uint32, title string) (int, erro
 	var cerr C.Tox_Err_Conference_Title
 	r := C.tox_conference_set_title(this.toxcore, _gn, (*C.uint8_t)(&_title[0]), _length, &cerr)
 	if r == false {
-		if len(title) > MAX_NAME_LENGTH {
-			return 0, errors.New("title too long")
-		}
-		return 0, toxerrf("set title failed:%d", cerr)
+		return 0, &ConferenceTitleError{int(cerr)}
 	}
+	this.markDirty()
 
 	if this.hooks.ConferenceSetTitle != nil {
 		this.hooks.ConferenceSetTitle(groupNumber, title)
@@ -356,9 +358,10 @@ func (this *Tox) ConferenceGetTitle(groupNumber uint32) (string, error) {
 	var _gn = C.uint32_t(groupNumber)
 	var _title [MAX_NAME_LENGTH]byte
 
-	r := C.tox_conference_get_title(this.toxcore, _gn, (*C.uint8_t)(&_title[0]), nil)
+	var cerr C.Tox_Err_Conference_Title
+	r := C.tox_conference_get_title(this.toxcore, _gn, (*C.uint8_t)(&_title[0]), &cerr)
 	if r == false {
-		return "", errors.New("get title failed")
+		return "", &ConferenceTitleError{int(cerr)}
 	}
 	return C.GoString((*C.char)(safeptr(_title[:]))), nil
 }
@@ -451,14 +454,15 @@ func (this *Tox) ConferenceGetChatlist() []uint32 {
 	return vec
 }
 
-func (this *Tox) ConferenceGetType(groupNumber uint32) (int, error) {
+func (this *Tox) ConferenceGetType(groupNumber uint32) (GroupchatType, error) {
 	var _gn = C.uint32_t(groupNumber)
 
-	r := C.tox_conference_get_type(this.toxcore, _gn, nil)
-	if int(r) == -1 {
-		return int(r), errors.New("get type failed")
+	var cerr C.Tox_Err_Conference_Get_Type
+	r := C.tox_conference_get_type(this.toxcore, _gn, &cerr)
+	if cerr != 0 {
+		return GroupchatType(r), &ConferenceGetTypeError{int(cerr)}
 	}
-	return int(r), nil
+	return GroupchatType(r), nil
 }
 
 func (this *Tox) ConferenceGetIdentifier(groupNumber uint32) (string, error) {
@@ -11,7 +11,7 @@ import "C"
 
 // legacy group callback type
 
-type cb_group_invite_ftype func(this *Tox, friendNumber uint32, itype uint8, cookie string, userData interface{})
+type cb_group_invite_ftype func(this *Tox, friendNumber uint32, itype GroupchatType, cookie string, userData interface{})
 type cb_group_message_ftype func(this *Tox, groupNumber int, peerNumber int, message string, userData interface{})
 
 type cb_group_action_ftype func(this *Tox, groupNumber int, peerNumber int, action string, userData interface{})
@@ -23,41 +23,41 @@ type cb_group_namelist_change_ftype func(this *Tox, groupNumber int, peerNumber
 func (this *Tox) CallbackGroupInvite(cbfn cb_group_invite_ftype, userData interface{}) {
 	this.CallbackGroupInviteAdd(cbfn, userData)
 }
-func (this *Tox) CallbackGroupInviteAdd(cbfn cb_group_invite_ftype, userData interface{}) {
-	cbfn_ := func(this *Tox, friendNumber uint32, itype uint8, cookie string, userData interface{}) {
+func (this *Tox) CallbackGroupInviteAdd(cbfn cb_group_invite_ftype, userData interface{}) CallbackHandle {
+	cbfn_ := func(this *Tox, friendNumber uint32, itype GroupchatType, cookie string, userData interface{}) {
 		cbfn(this, friendNumber, itype, cookie, userData)
 	}
-	this.CallbackConferenceInviteAdd(cbfn_, userData)
+	return this.CallbackConferenceInviteAdd(cbfn_, userData)
 }
 
 func (this *Tox) CallbackGroupMessage(cbfn cb_group_message_ftype, userData interface{}) {
 	this.CallbackGroupMessageAdd(cbfn, userData)
 }
-func (this *Tox) CallbackGroupMessageAdd(cbfn cb_group_message_ftype, userData interface{}) {
+func (this *Tox) CallbackGroupMessageAdd(cbfn cb_group_message_ftype, userData interface{}) CallbackHandle {
 	cbfn_ := func(this *Tox, groupNumber uint32, peerNumber uint32, message string, userData interface{}) {
 		cbfn(this, int(groupNumber), int(peerNumber), message, userData)
 	}
-	this.CallbackConferenceMessageAdd(cbfn_, userData)
+	return this.CallbackConferenceMessageAdd(cbfn_, userData)
 }
 
 func (this *Tox) CallbackGroupAction(cbfn cb_group_action_ftype, userData interface{}) {
 	this.CallbackGroupActionAdd(cbfn, userData)
 }
-func (this *Tox) CallbackGroupActionAdd(cbfn cb_group_action_ftype, userData interface{}) {
+func (this *Tox) CallbackGroupActionAdd(cbfn cb_group_action_ftype, userData interface{}) CallbackHandle {
 	cbfn_ := func(this *Tox, groupNumber uint32, peerNumber uint32, message string, userData interface{}) {
 		cbfn(this, int(groupNumber), int(peerNumber), message, userData)
 	}
-	this.CallbackConferenceActionAdd(cbfn_, userData)
+	return this.CallbackConferenceActionAdd(cbfn_, userData)
 }
 
 func (this *Tox) CallbackGroupTitle(cbfn cb_group_title_ftype, userData interface{}) {
 	this.CallbackGroupTitleAdd(cbfn, userData)
 }
-func (this *Tox) CallbackGroupTitleAdd(cbfn cb_group_title_ftype, userData interface{}) {
+func (this *Tox) CallbackGroupTitleAdd(cbfn cb_group_title_ftype, userData interface{}) CallbackHandle {
 	cbfn_ := func(this *Tox, groupNumber uint32, peerNumber uint32, title string, userData interface{}) {
 		cbfn(this, int(groupNumber), int(peerNumber), title, userData)
 	}
-	this.CallbackConferenceTitleAdd(cbfn_, userData)
+	return this.CallbackConferenceTitleAdd(cbfn_, userData)
 }
 
 // methods
@@ -144,5 +144,6 @@ func (this *Tox) GetChatList() []int32 {
 }
 
 func (this *Tox) GroupGetType(groupNumber uint32) (int, error) {
-	return this.ConferenceGetType(groupNumber)
+	gtype, err := this.ConferenceGetType(groupNumber)
+	return int(gtype), err
 }
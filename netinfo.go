@@ -0,0 +1,220 @@
+package tox
+
+/*
+#include <tox/tox.h>
+
+void callbackFriendConnectionStatusWrapperForC(Tox *, uint32_t, Tox_Connection, void*);
+void callbackSelfConnectionStatusWrapperForC(Tox *, int, void*);
+*/
+import "C"
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FriendNetworkInfo is the connection of one friend.
+type FriendNetworkInfo struct {
+	Number     uint32         `json:"number"`
+	PublicKey  PublicKey      `json:"public_key"`
+	Connection ConnectionType `json:"connection"`
+	Since      time.Time      `json:"since"` // last change, or the instance start
+}
+
+// NetworkInfo is a snapshot of the network state of an instance.
+type NetworkInfo struct {
+	Address         Address        `json:"address"`
+	DhtId           PublicKey      `json:"dht_id"`
+	UdpPort         uint16         `json:"udp_port"` // 0 if not bound
+	TcpPort         uint16         `json:"tcp_port"` // 0 without a TCP relay server
+	Connection      ConnectionType `json:"connection"`
+	ConnectionSince time.Time      `json:"connection_since"`
+
+	// the options the instance was created with
+	Ipv6           bool   `json:"ipv6"`
+	Udp            bool   `json:"udp"`
+	LocalDiscovery bool   `json:"local_discovery"`
+	HolePunching   bool   `json:"hole_punching"`
+	ProxyType      string `json:"proxy_type"`
+	ProxyHost      string `json:"proxy_host,omitempty"`
+	ProxyPort      uint16 `json:"proxy_port,omitempty"`
+
+	Friends []FriendNetworkInfo `json:"friends"`
+}
+
+// connTimes tracks when the self and friend connections last changed, from
+// the first NetworkInfo call on.
+type connTimes struct {
+	mu      sync.Mutex
+	on      bool
+	started time.Time
+	self    time.Time
+	friends map[uint32]time.Time
+}
+
+func (this *connTimes) tracking() bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.on
+}
+
+// changed is called by the connection status wrappers, friendNumber -1 is
+// self.
+func (this *connTimes) changed(friendNumber int64, now time.Time) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if !this.on {
+		return
+	}
+	if friendNumber < 0 {
+		this.self = now
+	} else {
+		this.friends[uint32(friendNumber)] = now
+	}
+}
+
+func (this *connTimes) forget(friendNumber uint32) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	delete(this.friends, friendNumber)
+}
+
+// since returns when the friend's connection last changed, friendNumber -1
+// is self.
+func (this *connTimes) since(friendNumber int64) time.Time {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	tm := this.self
+	if friendNumber >= 0 {
+		tm = this.friends[uint32(friendNumber)]
+	}
+	if tm.IsZero() {
+		return this.started
+	}
+	return tm
+}
+
+// connEvents are the events NetworkInfo follows. The C callbacks stay
+// registered once it was called.
+func (this *Tox) connEvents() []cEvent {
+	return []cEvent{
+		{this.cb_self_connection_statuss, func(on bool) {
+			cb := (*C.tox_self_connection_status_cb)(C.callbackSelfConnectionStatusWrapperForC)
+			if !on {
+				cb = nil
+			}
+			C.tox_callback_self_connection_status(this.toxcore, cb)
+		}},
+		{this.cb_friend_connection_statuss, func(on bool) {
+			cb := (*C.tox_friend_connection_status_cb)(C.callbackFriendConnectionStatusWrapperForC)
+			if !on {
+				cb = nil
+			}
+			C.tox_callback_friend_connection_status(this.toxcore, cb)
+		}},
+	}
+}
+
+// trackConnections starts following the connections on the first NetworkInfo
+// call, the changes before are not known.
+func (this *Tox) trackConnections() {
+	ct := &this.conntimes
+	ct.mu.Lock()
+	on := ct.on
+	if !on {
+		ct.on = true
+		ct.started = time.Now()
+		ct.friends = make(map[uint32]time.Time)
+	}
+	ct.mu.Unlock()
+	if !on {
+		for _, ev := range this.connEvents() {
+			ev.setc(true)
+		}
+	}
+}
+
+// NetworkInfo collects the ports, DHT ID, connection and options of the
+// instance, and the connection of every friend. The connection times count
+// from the first call, when it starts following the connections. Like the
+// other getters it doesn't lock the instance.
+func (this *Tox) NetworkInfo() (*NetworkInfo, error) {
+	if this.toxcore == nil {
+		return nil, ErrKilled
+	}
+	this.trackConnections()
+	info := &NetworkInfo{
+		Address:         this.SelfAddress(),
+		Connection:      this.SelfGetConnectionStatus(),
+		ConnectionSince: this.conntimes.since(-1),
+		ProxyType:       "none",
+	}
+	dhtid, err := ParsePublicKey(this.SelfGetDhtId())
+	if err != nil {
+		return nil, err
+	}
+	info.DhtId = dhtid
+	if info.UdpPort, err = this.SelfGetUdpPort(); err != nil && !errors.Is(err, ErrGetPortNotBound) {
+		return nil, err
+	}
+	if info.TcpPort, err = this.SelfGetTcpPort(); err != nil && !errors.Is(err, ErrGetPortNotBound) {
+		return nil, err
+	}
+
+	if opt := this.opts; opt != nil {
+		info.Ipv6 = opt.Ipv6_enabled
+		info.Udp = opt.Udp_enabled
+		info.LocalDiscovery = opt.Local_discovery_enabled
+		info.HolePunching = opt.Hole_punching_enabled
+		for name, ptype := range proxyTypeNames {
+			if int32(ptype) == opt.Proxy_type {
+				info.ProxyType = name
+			}
+		}
+		if opt.Proxy_type != int32(PROXY_TYPE_NONE) {
+			info.ProxyHost = opt.Proxy_host
+			info.ProxyPort = opt.Proxy_port
+		}
+	}
+
+	info.Friends = []FriendNetworkInfo{}
+	for _, fn := range this.SelfGetFriendList() {
+		pk, err := this.FriendPublicKey(fn)
+		if err != nil {
+			continue // deleted meanwhile
+		}
+		conn, err := this.FriendGetConnectionStatus(fn)
+		if err != nil {
+			continue
+		}
+		info.Friends = append(info.Friends, FriendNetworkInfo{fn, pk, conn, this.conntimes.since(int64(fn))})
+	}
+	return info, nil
+}
+
+var ErrNotThreadSafe = errors.New("tox instance is not ThreadSafe")
+
+// NetworkInfoHandler serves the NetworkInfo of a running instance as JSON,
+// for toxnetinfo -url. Requests come from the server's goroutines, so it
+// returns ErrNotThreadSafe for an instance without ThreadSafe.
+func NetworkInfoHandler(t *Tox) (http.Handler, error) {
+	if t.opts == nil || !t.opts.ThreadSafe {
+		return nil, ErrNotThreadSafe
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.lock()
+		info, err := t.NetworkInfo()
+		t.unlock()
+		if errors.Is(err, ErrKilled) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	}), nil
+}
@@ -10,7 +10,14 @@ extern void toxCallbackLog(Tox*, Tox_Log_Level, cchar_t*, uint32_t, cchar_t*, cc
 
 */
 import "C"
-import "unsafe"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/TokTok/go-toxcore-c/savedata"
+)
 
 const (
 	SAVEDATA_TYPE_NONE       = int(C.TOX_SAVEDATA_TYPE_NONE)
@@ -18,6 +25,8 @@ const (
 	SAVEDATA_TYPE_SECRET_KEY = int(C.TOX_SAVEDATA_TYPE_SECRET_KEY)
 )
 
+const MAX_HOSTNAME_LENGTH = int(C.TOX_MAX_HOSTNAME_LENGTH)
+
 const (
 	PROXY_TYPE_NONE   = int(C.TOX_PROXY_TYPE_NONE)
 	PROXY_TYPE_HTTP   = int(C.TOX_PROXY_TYPE_HTTP)
@@ -47,9 +56,177 @@ type ToxOptions struct {
 	Hole_punching_enabled   bool
 	ThreadSafe              bool
 	LogCallback             func(_ *Tox, level int, file string, line uint32, fname string, msg string)
+	PortFallback            *PortFallback // nil fails on the first ERR_NEW_PORT_ALLOC
+	StrictProxy             bool          // refuse Bootstrap and relay hostnames, see PresetTor
+	LanOnly                 bool          // refuse non LAN nodes and relays, see PresetLAN
+}
+
+// PortFallback makes NewToxWithError retry with other ports when tox_new fails
+// with ERR_NEW_PORT_ALLOC. Each retry moves Tcp_port by TcpStep and the
+// Start_port..End_port range by UdpStep. A zero Start_port means toxcore's
+// default range, which toxcore already searches, and is never moved.
+type PortFallback struct {
+	Tries   int // retries after the first attempt
+	TcpStep uint16
+	UdpStep uint16
+}
+
+// next returns the options for retry number try, counting from 1.
+func (this *PortFallback) next(opt *ToxOptions, try int) *ToxOptions {
+	o := *opt
+	if o.Tcp_port != 0 {
+		o.Tcp_port += this.TcpStep * uint16(try)
+	}
+	if o.Start_port != 0 {
+		o.Start_port += this.UdpStep * uint16(try)
+		o.End_port += this.UdpStep * uint16(try)
+	}
+	return &o
+}
+
+// ToxOption sets one field group of ToxOptions, see NewToxOptions.
+type ToxOption func(*ToxOptions)
+
+func WithIPv6(enabled bool) ToxOption {
+	return func(this *ToxOptions) { this.Ipv6_enabled = enabled }
+}
+
+func WithUDP(enabled bool) ToxOption {
+	return func(this *ToxOptions) { this.Udp_enabled = enabled }
+}
+
+// WithProxy also disables UDP, which would bypass the proxy.
+func WithProxy(ptype int, host string, port uint16) ToxOption {
+	return func(this *ToxOptions) {
+		this.Proxy_type = int32(ptype)
+		this.Proxy_host = host
+		this.Proxy_port = port
+		if ptype != PROXY_TYPE_NONE {
+			this.Udp_enabled = false
+		}
+	}
+}
+
+// WithSavedata loads a tox_get_savedata profile.
+func WithSavedata(data []byte) ToxOption {
+	return func(this *ToxOptions) {
+		this.Savedata_type = SAVEDATA_TYPE_TOX_SAVE
+		this.Savedata_data = data
+	}
+}
+
+// WithSecretKey starts a new profile with the given secret key.
+func WithSecretKey(seckey []byte) ToxOption {
+	return func(this *ToxOptions) {
+		this.Savedata_type = SAVEDATA_TYPE_SECRET_KEY
+		this.Savedata_data = seckey
+	}
+}
+
+// WithTcpPort runs a TCP relay on port, 0 disables it.
+func WithTcpPort(port uint16) ToxOption {
+	return func(this *ToxOptions) { this.Tcp_port = port }
+}
+
+// WithPortRange sets the UDP ports to bind, 0, 0 is toxcore's default range.
+func WithPortRange(start, end uint16) ToxOption {
+	return func(this *ToxOptions) {
+		this.Start_port = start
+		this.End_port = end
+	}
 }
 
-func NewToxOptions() *ToxOptions {
+func WithLocalDiscovery(enabled bool) ToxOption {
+	return func(this *ToxOptions) { this.Local_discovery_enabled = enabled }
+}
+
+func WithHolePunching(enabled bool) ToxOption {
+	return func(this *ToxOptions) { this.Hole_punching_enabled = enabled }
+}
+
+func WithThreadSafe(enabled bool) ToxOption {
+	return func(this *ToxOptions) { this.ThreadSafe = enabled }
+}
+
+func WithLogCallback(fn func(_ *Tox, level int, file string, line uint32, fname string, msg string)) ToxOption {
+	return func(this *ToxOptions) { this.LogCallback = fn }
+}
+
+func WithPortFallback(fb *PortFallback) ToxOption {
+	return func(this *ToxOptions) { this.PortFallback = fb }
+}
+
+var ErrInvalidOptions = errors.New("invalid tox options")
+
+// Validate reports the option combinations tox_new would reject, or that
+// would not do what they look like, without calling into toxcore.
+func (this *ToxOptions) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch int(this.Proxy_type) {
+	case PROXY_TYPE_NONE:
+	case PROXY_TYPE_HTTP, PROXY_TYPE_SOCKS5:
+		if this.Proxy_host == "" {
+			add("proxy type %d needs a Proxy_host", this.Proxy_type)
+		} else if len(this.Proxy_host) > MAX_HOSTNAME_LENGTH {
+			add("Proxy_host is longer than %d bytes", MAX_HOSTNAME_LENGTH)
+		}
+		if this.Proxy_port == 0 {
+			add("proxy type %d needs a Proxy_port", this.Proxy_type)
+		}
+		if this.Udp_enabled {
+			add("UDP must be disabled with a proxy, it would bypass it")
+		}
+	default:
+		add("unknown Proxy_type %d", this.Proxy_type)
+	}
+
+	if this.StrictProxy {
+		if this.Proxy_type == int32(PROXY_TYPE_NONE) {
+			add("StrictProxy needs a proxy")
+		}
+		if this.Local_discovery_enabled {
+			add("local discovery must be disabled with StrictProxy, it broadcasts on the LAN")
+		}
+	}
+	if this.LanOnly && this.Proxy_type != int32(PROXY_TYPE_NONE) {
+		add("LanOnly can't use a proxy")
+	}
+
+	if this.Start_port > this.End_port {
+		add("Start_port %d is above End_port %d", this.Start_port, this.End_port)
+	}
+
+	switch this.Savedata_type {
+	case SAVEDATA_TYPE_NONE:
+		if len(this.Savedata_data) > 0 {
+			add("Savedata_data is set but Savedata_type is none")
+		}
+	case SAVEDATA_TYPE_TOX_SAVE:
+		if len(this.Savedata_data) == 0 {
+			add("Savedata_type is tox save but Savedata_data is empty")
+		} else if savedata.IsEncrypted(this.Savedata_data) {
+			add("Savedata_data is encrypted, decrypt it first or use OpenProfile")
+		}
+	case SAVEDATA_TYPE_SECRET_KEY:
+		if len(this.Savedata_data) != SECRET_KEY_SIZE {
+			add("secret key savedata must be %d bytes, not %d", SECRET_KEY_SIZE, len(this.Savedata_data))
+		}
+	default:
+		add("unknown Savedata_type %d", this.Savedata_type)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidOptions, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// NewToxOptions returns toxcore's defaults with the options applied in order.
+func NewToxOptions(with ...ToxOption) *ToxOptions {
 	toxopts := C.tox_options_new(nil)
 	defer C.tox_options_free(toxopts)
 
@@ -64,17 +241,25 @@ func NewToxOptions() *ToxOptions {
 	opts.End_port = uint16(C.tox_options_get_end_port(toxopts))
 	opts.Hole_punching_enabled = bool(C.tox_options_get_hole_punching_enabled(toxopts))
 
+	for _, opt := range with {
+		opt(opts)
+	}
 	return opts
 }
 
-func (this *ToxOptions) toCToxOptions() *C.struct_Tox_Options {
-	toxopts := C.tox_options_new(nil)
+// toCToxOptions copies the strings and savedata to C memory, toxcore reads
+// them in tox_new. Call free after that.
+func (this *ToxOptions) toCToxOptions() (toxopts *C.struct_Tox_Options, free func()) {
+	var cmem []unsafe.Pointer
+	toxopts = C.tox_options_new(nil)
 	C.tox_options_default(toxopts)
 	C.tox_options_set_ipv6_enabled(toxopts, (C._Bool)(this.Ipv6_enabled))
 	C.tox_options_set_udp_enabled(toxopts, (C._Bool)(this.Udp_enabled))
 
-	if this.Savedata_data != nil {
-		C.tox_options_set_savedata_data(toxopts, (*C.uint8_t)(&this.Savedata_data[0]), C.size_t(len(this.Savedata_data)))
+	if len(this.Savedata_data) > 0 {
+		cdata := C.CBytes(this.Savedata_data)
+		cmem = append(cmem, cdata)
+		C.tox_options_set_savedata_data(toxopts, (*C.uint8_t)(cdata), C.size_t(len(this.Savedata_data)))
 		C.tox_options_set_savedata_type(toxopts, C.Tox_Savedata_Type(this.Savedata_type))
 	}
 	C.tox_options_set_tcp_port(toxopts, (C.uint16_t)(this.Tcp_port))
@@ -82,7 +267,9 @@ func (this *ToxOptions) toCToxOptions() *C.struct_Tox_Options {
 	C.tox_options_set_proxy_type(toxopts, C.Tox_Proxy_Type(this.Proxy_type))
 	C.tox_options_set_proxy_port(toxopts, C.uint16_t(this.Proxy_port))
 	if len(this.Proxy_host) > 0 {
-		C.tox_options_set_proxy_host(toxopts, C.CString(this.Proxy_host))
+		chost := C.CString(this.Proxy_host)
+		cmem = append(cmem, unsafe.Pointer(chost))
+		C.tox_options_set_proxy_host(toxopts, chost)
 	}
 
 	C.tox_options_set_local_discovery_enabled(toxopts, C._Bool(this.Local_discovery_enabled))
@@ -92,7 +279,12 @@ func (this *ToxOptions) toCToxOptions() *C.struct_Tox_Options {
 
 	C.tox_options_set_log_callback(toxopts, (*C.tox_log_cb)((unsafe.Pointer)(C.toxCallbackLog)))
 
-	return toxopts
+	return toxopts, func() {
+		C.tox_options_free(toxopts)
+		for _, p := range cmem {
+			C.free(p)
+		}
+	}
 }
 
 //export toxCallbackLog
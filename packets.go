@@ -0,0 +1,127 @@
+package tox
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Packet id ranges toxcore allows for the first byte of custom packets.
+const (
+	PACKET_ID_LOSSLESS_MIN = 160
+	PACKET_ID_LOSSLESS_MAX = 191
+	PACKET_ID_LOSSY_MIN    = 200
+	PACKET_ID_LOSSY_MAX    = 254
+)
+
+var ErrPacketHandlerExists = errors.New("packet id already has a handler")
+
+func IsLossyPacketID(id byte) bool {
+	return id >= PACKET_ID_LOSSY_MIN && id <= PACKET_ID_LOSSY_MAX
+}
+
+func IsLosslessPacketID(id byte) bool {
+	return id >= PACKET_ID_LOSSLESS_MIN && id <= PACKET_ID_LOSSLESS_MAX
+}
+
+// checkCustomPacket reports the errors toxcore would, before locking the
+// instance for the send.
+func checkCustomPacket(data []byte, validID func(byte) bool) error {
+	if len(data) == 0 {
+		return ErrFriendCustomPacketEmpty
+	}
+	if len(data) > MAX_CUSTOM_PACKET_SIZE {
+		return ErrFriendCustomPacketTooLong
+	}
+	if !validID(data[0]) {
+		return ErrFriendCustomPacketInvalid
+	}
+	return nil
+}
+
+func checkLossyPacket(data []byte) error {
+	return checkCustomPacket(data, IsLossyPacketID)
+}
+
+func checkLosslessPacket(data []byte) error {
+	return checkCustomPacket(data, IsLosslessPacketID)
+}
+
+// PacketHandler receives a custom packet, data includes the packet id byte.
+type PacketHandler func(t *Tox, friendNumber uint32, data []byte)
+
+// PacketRouter dispatches lossy and lossless custom packets to handlers by
+// their packet id, so several protocol extensions can share one Tox instance.
+// Packets without a handler are dropped.
+type PacketRouter struct {
+	t        *Tox
+	mu       sync.RWMutex
+	handlers map[byte]PacketHandler
+	cbhs     []CallbackHandle
+}
+
+// NewPacketRouter registers the router as a lossy and lossless packet listener
+// of t. Other packet listeners keep working alongside it.
+func NewPacketRouter(t *Tox) *PacketRouter {
+	this := &PacketRouter{t: t, handlers: make(map[byte]PacketHandler)}
+	this.cbhs = append(this.cbhs,
+		t.CallbackFriendLossyPacketBytesAdd(func(t *Tox, friendNumber uint32, data []byte, _ interface{}) {
+			this.dispatch(t, friendNumber, data)
+		}, nil),
+		t.CallbackFriendLosslessPacketBytesAdd(func(t *Tox, friendNumber uint32, data []byte, _ interface{}) {
+			this.dispatch(t, friendNumber, data)
+		}, nil))
+	return this
+}
+
+// Handle registers h for packets with the given id, which must be in the
+// lossy or lossless range. Each id has at most one handler.
+func (this *PacketRouter) Handle(id byte, h PacketHandler) error {
+	if !IsLossyPacketID(id) && !IsLosslessPacketID(id) {
+		return fmt.Errorf("packet id %d: %w", id, ErrFriendCustomPacketInvalid)
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if _, ok := this.handlers[id]; ok {
+		return fmt.Errorf("packet id %d: %w", id, ErrPacketHandlerExists)
+	}
+	this.handlers[id] = h
+	return nil
+}
+
+// Remove unregisters the handler of id, returns false if there was none.
+func (this *PacketRouter) Remove(id byte) bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	_, ok := this.handlers[id]
+	delete(this.handlers, id)
+	return ok
+}
+
+// Send sends data lossy or lossless, as its packet id says.
+func (this *PacketRouter) Send(friendNumber uint32, data []byte) error {
+	if len(data) > 0 && IsLosslessPacketID(data[0]) {
+		return this.t.FriendSendLosslessPacketBytes(friendNumber, data)
+	}
+	return this.t.FriendSendLossyPacketBytes(friendNumber, data)
+}
+
+// Close unregisters the router from its Tox instance.
+func (this *PacketRouter) Close() {
+	for _, h := range this.cbhs {
+		this.t.CallbackRemove(h)
+	}
+	this.cbhs = nil
+}
+
+func (this *PacketRouter) dispatch(t *Tox, friendNumber uint32, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	this.mu.RLock()
+	h := this.handlers[data[0]]
+	this.mu.RUnlock()
+	if h != nil {
+		h(t, friendNumber, data)
+	}
+}
@@ -0,0 +1,110 @@
+package tox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+var ErrStrictProxy = errors.New("refused, would send traffic outside the proxy")
+var ErrLanOnly = errors.New("refused, not a LAN address")
+
+// PresetTor routes everything through the Tor SOCKS5 proxy at host:port,
+// usually 127.0.0.1:9050. UDP, local discovery and hole punching are off,
+// and StrictProxy refuses calls that would leak around the proxy: UDP
+// Bootstrap, and TCP relays given by hostname, which toxcore resolves
+// itself. Connect with AddTcpRelay to relays given by IP.
+func PresetTor(host string, port uint16) ToxOption {
+	return PresetProxy(PROXY_TYPE_SOCKS5, host, port)
+}
+
+// PresetProxy is PresetTor for any proxy type.
+func PresetProxy(ptype int, host string, port uint16) ToxOption {
+	return func(this *ToxOptions) {
+		WithProxy(ptype, host, port)(this)
+		this.Udp_enabled = false
+		this.Local_discovery_enabled = false
+		this.Hole_punching_enabled = false
+		this.StrictProxy = true
+		this.LanOnly = false
+	}
+}
+
+// PresetTCPOnly connects through TCP relays only, for networks that block
+// UDP. Bootstrap still works, toxcore uses it to learn relays.
+func PresetTCPOnly() ToxOption {
+	return func(this *ToxOptions) {
+		this.Udp_enabled = false
+		this.Local_discovery_enabled = false
+		this.Hole_punching_enabled = false
+	}
+}
+
+// PresetLAN finds peers by local discovery, for networks without internet.
+// LanOnly refuses bootstrap nodes and TCP relays outside private, loopback
+// and link local addresses.
+func PresetLAN() ToxOption {
+	return func(this *ToxOptions) {
+		this.Proxy_type = int32(PROXY_TYPE_NONE)
+		this.Proxy_host = ""
+		this.Proxy_port = 0
+		this.Udp_enabled = true
+		this.Local_discovery_enabled = true
+		this.Hole_punching_enabled = false
+		this.StrictProxy = false
+		this.LanOnly = true
+	}
+}
+
+var lanNets []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "127.0.0.0/8", "169.254.0.0/16",
+		"::1/128", "fc00::/7", "fe80::/10",
+	} {
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		lanNets = append(lanNets, ipnet)
+	}
+}
+
+func isLanIP(ip net.IP) bool {
+	for _, ipnet := range lanNets {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPeerAddr enforces StrictProxy and LanOnly for a bootstrap node or TCP
+// relay, udp is true for Bootstrap.
+func (this *Tox) checkPeerAddr(addr string, udp bool) error {
+	opts := this.opts
+	if opts == nil {
+		return nil
+	}
+	if opts.StrictProxy {
+		if udp {
+			return fmt.Errorf("bootstrap %s: %w", addr, ErrStrictProxy)
+		}
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("relay hostname %s is resolved without the proxy: %w", addr, ErrStrictProxy)
+		}
+	}
+	if opts.LanOnly {
+		ips := []net.IP{net.ParseIP(addr)}
+		if ips[0] == nil {
+			var err error
+			if ips, err = net.LookupIP(addr); err != nil {
+				return err
+			}
+		}
+		for _, ip := range ips {
+			if !isLanIP(ip) {
+				return fmt.Errorf("%s (%s): %w", addr, ip, ErrLanOnly)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package tox
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/TokTok/go-toxcore-c/savedata"
+)
+
+var ErrProfileLocked = errors.New("profile is encrypted, passphrase required")
+var ErrProfileClosed = errors.New("profile is closed")
+
+// Profile is a save file, plain or encrypted with a passphrase.
+// The pass key is derived once and kept, so saving reuses its salt instead
+// of running the key derivation again.
+type Profile struct {
+	Path    string
+	Options *ToxOptions // ready for NewTox, with the decrypted savedata
+
+	mu        sync.Mutex
+	pkey      *ToxPassKey // nil for plain profiles, and once closed
+	encrypted bool
+}
+
+// OpenProfile loads the profile at path, decrypting it if it is encrypted.
+// A missing file gives a new profile, which is saved encrypted if passphrase
+// is not empty. Call Close to free the pass key.
+func OpenProfile(path string, passphrase []byte) (*Profile, error) {
+	this := &Profile{Path: path, Options: NewToxOptions()}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if len(passphrase) > 0 {
+			if this.pkey, err = Derive(passphrase); err != nil {
+				return nil, err
+			}
+			this.encrypted = true
+		}
+		return this, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(data) > 0 && IsDataEncrypted(data) {
+		if len(passphrase) == 0 {
+			return nil, ErrProfileLocked
+		}
+		_, err, salt := GetSalt(data)
+		if err != nil {
+			return nil, err
+		}
+		if this.pkey, err = DeriveWithSalt(passphrase, salt); err != nil {
+			return nil, err
+		}
+		this.encrypted = true
+		if _, err, data = this.pkey.Decrypt(data); err != nil {
+			this.Close()
+			return nil, err
+		}
+	}
+	if len(data) > 0 {
+		this.Options.Savedata_type = SAVEDATA_TYPE_TOX_SAVE
+		this.Options.Savedata_data = data
+	}
+	return this, nil
+}
+
+func (this *Profile) Encrypted() bool {
+	return this.encrypted
+}
+
+// Save writes data, encrypting it with the profile's pass key, atomically
+// to the profile path.
+func (this *Profile) Save(data []byte) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if this.encrypted {
+		if this.pkey == nil {
+			return ErrProfileClosed
+		}
+		var err error
+		if _, err, data = this.pkey.Encrypt(data); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(this.Path, data)
+}
+
+// Check is CheckSavedata for data encrypted with the profile's pass key, it
+// decrypts data to check it.
+func (this *Profile) Check(data []byte) error {
+	if !savedata.IsEncrypted(data) {
+		return CheckSavedata(data)
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if !this.encrypted {
+		return ErrProfileLocked
+	} else if this.pkey == nil {
+		return ErrProfileClosed
+	}
+	_, err, plain := this.pkey.Decrypt(data)
+	if err != nil {
+		return err
+	}
+	return CheckSavedata(plain)
+}
+
+// Load reads and decrypts the profile file again, so a Profile can be used
+// as the SaveStore for autosave.
+func (this *Profile) Load() ([]byte, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	data, err := ioutil.ReadFile(this.Path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoSavedata
+	} else if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 && IsDataEncrypted(data) {
+		if !this.encrypted {
+			return nil, ErrProfileLocked
+		} else if this.pkey == nil {
+			return nil, ErrProfileClosed
+		}
+		_, err, data = this.pkey.Decrypt(data)
+	}
+	return data, err
+}
+
+func (this *Profile) Close() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if this.pkey != nil {
+		this.pkey.Free()
+		this.pkey = nil
+	}
+}
+
+// SaveProfile writes the current savedata to the profile.
+func (this *Tox) SaveProfile(p *Profile) error {
+	return p.Save(this.GetSavedata())
+}
@@ -0,0 +1,112 @@
+package savedata
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Change is one difference between two profiles. Key names the friend or
+// conference for per-entry changes. Old is empty for added entries and New
+// for removed ones.
+type Change struct {
+	Field string `json:"field"`
+	Key   string `json:"key,omitempty"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+func (this Change) String() string {
+	what := this.Field
+	if this.Key != "" {
+		what += " " + this.Key
+	}
+	switch {
+	case this.Old == "":
+		return fmt.Sprintf("%s added: %q", what, this.New)
+	case this.New == "":
+		return fmt.Sprintf("%s removed: %q", what, this.Old)
+	}
+	return fmt.Sprintf("%s changed: %q -> %q", what, this.Old, this.New)
+}
+
+// Diff lists what changed from a to b: keys, nospam, self info, friends added,
+// removed or renamed, and conferences joined, left or retitled. The secret key
+// is only reported as changed, never printed.
+func Diff(a, b *Savedata) []Change {
+	var changes []Change
+	add := func(field, key, old, new string) {
+		if old != new {
+			changes = append(changes, Change{field, key, old, new})
+		}
+	}
+
+	add("public key", "", a.PublicKey.String(), b.PublicKey.String())
+	if a.SecretKey != b.SecretKey {
+		changes = append(changes, Change{"secret key", "", "(hidden)", "(changed)"})
+	}
+	add("nospam", "", fmt.Sprintf("%08X", a.Nospam), fmt.Sprintf("%08X", b.Nospam))
+	add("name", "", a.Name, b.Name)
+	add("status message", "", a.StatusMessage, b.StatusMessage)
+	add("status", "", fmt.Sprint(a.Status), fmt.Sprint(b.Status))
+
+	afriends := make(map[PublicKey]*Friend)
+	for i := range a.Friends {
+		afriends[a.Friends[i].PublicKey] = &a.Friends[i]
+	}
+	for i := range b.Friends {
+		fb := &b.Friends[i]
+		key := fb.PublicKey.String()
+		fa, ok := afriends[fb.PublicKey]
+		if !ok {
+			changes = append(changes, Change{"friend", key, "", friendLabel(fb)})
+			continue
+		}
+		delete(afriends, fb.PublicKey)
+		add("friend name", key, fa.Name, fb.Name)
+		add("friend status message", key, fa.StatusMessage, fb.StatusMessage)
+	}
+	for i := range a.Friends {
+		if fa, ok := afriends[a.Friends[i].PublicKey]; ok {
+			changes = append(changes, Change{"friend", fa.PublicKey.String(), friendLabel(fa), ""})
+		}
+	}
+
+	aconfs := make(map[[CONFERENCE_ID_SIZE]byte]*Conference)
+	for i := range a.Conferences {
+		aconfs[a.Conferences[i].ID] = &a.Conferences[i]
+	}
+	for i := range b.Conferences {
+		cb := &b.Conferences[i]
+		key := strings.ToUpper(hex.EncodeToString(cb.ID[:]))
+		ca, ok := aconfs[cb.ID]
+		if !ok {
+			changes = append(changes, Change{"conference", key, "", conferenceLabel(cb)})
+			continue
+		}
+		delete(aconfs, cb.ID)
+		add("conference title", key, ca.Title, cb.Title)
+	}
+	for i := range a.Conferences {
+		if ca, ok := aconfs[a.Conferences[i].ID]; ok {
+			changes = append(changes, Change{"conference", strings.ToUpper(hex.EncodeToString(ca.ID[:])), conferenceLabel(ca), ""})
+		}
+	}
+	return changes
+}
+
+// labels are never empty, so an entry without a name still shows as added
+// or removed
+func friendLabel(f *Friend) string {
+	if f.Name == "" {
+		return "(no name)"
+	}
+	return f.Name
+}
+
+func conferenceLabel(c *Conference) string {
+	if c.Title == "" {
+		return "(no title)"
+	}
+	return c.Title
+}
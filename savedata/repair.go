@@ -0,0 +1,143 @@
+package savedata
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var ErrNoKeys = errors.New("savedata keys are lost")
+
+var sectionNames = map[uint16]string{
+	SECTION_NOSPAMKEYS:    "nospam keys",
+	SECTION_DHT:           "dht",
+	SECTION_FRIENDS:       "friends",
+	SECTION_NAME:          "name",
+	SECTION_STATUSMESSAGE: "status message",
+	SECTION_STATUS:        "status",
+	SECTION_TCP_RELAY:     "tcp relays",
+	SECTION_PATH_NODE:     "path nodes",
+	SECTION_CONFERENCES:   "conferences",
+	SECTION_END:           "end",
+}
+
+func SectionName(typ uint16) string {
+	if name, ok := sectionNames[typ]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", typ)
+}
+
+// Repair salvages what it can from a damaged savedata. Sections that don't
+// decode are cut down to their valid part: whole friend records, the nodes
+// before a bad one, whole conferences. After a damaged section header it
+// resyncs on the next valid one. The returned problems describe everything
+// that was dropped; Marshal the result to get a loadable profile.
+// It fails only if the keys can't be recovered.
+func Repair(data []byte) (*Savedata, []string, error) {
+	if IsEncrypted(data) {
+		return nil, nil, ErrEncrypted
+	}
+
+	var problems []string
+	lost := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	off := 8
+	if len(data) < 8 || binary.LittleEndian.Uint32(data) != 0 ||
+		binary.LittleEndian.Uint32(data[4:]) != STATE_COOKIE_GLOBAL {
+		lost("bad header")
+		off = 0
+	}
+
+	this := &Savedata{}
+	haveKeys := false
+	for off < len(data) {
+		length, typ, ok := sectionHeader(data[off:])
+		if !ok {
+			next := resync(data, off+1)
+			lost("garbage at %#x: dropped %d bytes", off, next-off)
+			off = next
+			continue
+		}
+		if typ == SECTION_END {
+			break
+		}
+		body := data[off+8:]
+		maxLen := sectionMaxSize(typ)
+		if uint64(length) > uint64(len(body)) || (maxLen > 0 && length > uint32(maxLen)) {
+			// a bad length would swallow the sections after it
+			next := resync(data, off+8) - (off + 8)
+			if uint64(length) <= uint64(next) {
+				next = int(length)
+			} else {
+				lost("section %s at %#x: length %d, cut to %d bytes", SectionName(typ), off, length, next)
+			}
+			body = body[:next]
+		} else {
+			body = body[:length]
+		}
+		off += 8 + len(body)
+		if maxLen > 0 && len(body) > maxLen {
+			lost("section %s: dropped %d bytes over the maximum of %d", SectionName(typ), len(body)-maxLen, maxLen)
+			body = body[:maxLen]
+		}
+
+		if typ == SECTION_FRIENDS && len(body)%FRIEND_SIZE != 0 {
+			lost("section friends: dropped a partial friend record of %d bytes", len(body)%FRIEND_SIZE)
+			body = body[:len(body)-len(body)%FRIEND_SIZE]
+		}
+		if typ == SECTION_NOSPAMKEYS && len(body) > 4+PUBLIC_KEY_SIZE+SECRET_KEY_SIZE {
+			lost("section nospam keys: dropped %d extra bytes", len(body)-(4+PUBLIC_KEY_SIZE+SECRET_KEY_SIZE))
+			body = body[:4+PUBLIC_KEY_SIZE+SECRET_KEY_SIZE]
+		}
+		if typ == SECTION_STATUS && len(body) > 1 {
+			body = body[:1]
+		}
+
+		// parseSection keeps what it decoded before an error
+		nfriends, nconfs := len(this.Friends), len(this.Conferences)
+		if err := this.parseSection(Section{typ, body}); err != nil {
+			lost("section %s: %v, kept %d friends, %d conferences", SectionName(typ), err,
+				len(this.Friends)-nfriends, len(this.Conferences)-nconfs)
+			continue
+		}
+		if typ == SECTION_NOSPAMKEYS {
+			haveKeys = true
+		}
+	}
+
+	if !haveKeys {
+		return this, problems, ErrNoKeys
+	}
+	return this, problems, nil
+}
+
+// sectionMaxSize is the largest body Marshal writes for typ, 0 if unlimited.
+func sectionMaxSize(typ uint16) int {
+	switch typ {
+	case SECTION_NAME:
+		return FRIEND_NAME_MAX_SIZE
+	case SECTION_STATUSMESSAGE:
+		return FRIEND_STATUS_MAX_SIZE
+	}
+	return 0
+}
+
+func sectionHeader(b []byte) (length uint32, typ uint16, ok bool) {
+	if len(b) < 8 || binary.LittleEndian.Uint16(b[6:]) != STATE_COOKIE_TYPE {
+		return 0, 0, false
+	}
+	return binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint16(b[4:]), true
+}
+
+// resync finds the next offset that looks like a header of a known section.
+func resync(data []byte, off int) int {
+	for ; off+8 <= len(data); off++ {
+		if _, typ, ok := sectionHeader(data[off:]); ok && sectionNames[typ] != "" {
+			return off
+		}
+	}
+	return len(data)
+}
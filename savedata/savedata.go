@@ -0,0 +1,406 @@
+// Package savedata decodes the toxcore state format, as returned by
+// tox_get_savedata, without cgo or libtoxcore.
+package savedata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	STATE_COOKIE_GLOBAL = 0x15ed1b1f
+	STATE_COOKIE_TYPE   = 0x01ce
+
+	DHT_STATE_COOKIE_GLOBAL = 0x159000d
+	DHT_STATE_COOKIE_TYPE   = 0x11ce
+	DHT_STATE_TYPE_NODES    = 4
+)
+
+// Section types of the state format.
+const (
+	SECTION_NOSPAMKEYS    = 1
+	SECTION_DHT           = 2
+	SECTION_FRIENDS       = 3
+	SECTION_NAME          = 4
+	SECTION_STATUSMESSAGE = 5
+	SECTION_STATUS        = 6
+	SECTION_TCP_RELAY     = 10
+	SECTION_PATH_NODE     = 11
+	SECTION_CONFERENCES   = 20
+	SECTION_END           = 255
+)
+
+// Address families of packed nodes.
+const (
+	FAMILY_UDP_INET  = 2
+	FAMILY_UDP_INET6 = 10
+	FAMILY_TCP_INET  = 130
+	FAMILY_TCP_INET6 = 138
+)
+
+const (
+	PUBLIC_KEY_SIZE = 32
+	SECRET_KEY_SIZE = 32
+
+	FRIEND_REQUEST_MAX_SIZE = 1024
+	FRIEND_NAME_MAX_SIZE    = 128
+	FRIEND_STATUS_MAX_SIZE  = 1007
+	FRIEND_SIZE             = 1 + PUBLIC_KEY_SIZE + FRIEND_REQUEST_MAX_SIZE + 2 + FRIEND_NAME_MAX_SIZE + 2 + FRIEND_STATUS_MAX_SIZE + 2 + 1 + 4 + 8
+
+	CONFERENCE_ID_SIZE = 32
+)
+
+// FriendStatus is the state of a friend entry, not its user status.
+type FriendStatus uint8
+
+const (
+	FRIEND_NOFRIEND  FriendStatus = 0
+	FRIEND_ADDED     FriendStatus = 1
+	FRIEND_REQUESTED FriendStatus = 2
+	FRIEND_CONFIRMED FriendStatus = 3
+	FRIEND_ONLINE    FriendStatus = 4
+)
+
+var (
+	ErrEncrypted = errors.New("savedata is encrypted")
+	ErrBadHeader = errors.New("savedata header mismatch")
+	ErrTruncated = errors.New("savedata truncated")
+)
+
+// encrypted saves start with TOX_ENC_SAVE_MAGIC_NUMBER
+var encMagic = []byte("toxEsave")
+
+type PublicKey [PUBLIC_KEY_SIZE]byte
+
+func (this PublicKey) String() string {
+	return strings.ToUpper(hex.EncodeToString(this[:]))
+}
+
+func (this PublicKey) MarshalText() ([]byte, error) {
+	return []byte(this.String()), nil
+}
+
+// Node is a packed node of the DHT, TcpRelay and PathNodes sections.
+type Node struct {
+	TCP       bool
+	IP        net.IP
+	Port      uint16
+	PublicKey PublicKey
+}
+
+func (this Node) String() string {
+	proto := "udp"
+	if this.TCP {
+		proto = "tcp"
+	}
+	return fmt.Sprintf("%s/%s %s", proto, net.JoinHostPort(this.IP.String(), fmt.Sprint(this.Port)), this.PublicKey)
+}
+
+type Friend struct {
+	Status         FriendStatus
+	PublicKey      PublicKey
+	RequestMessage string // only for friends we sent a request to
+	Name           string
+	StatusMessage  string
+	UserStatus     uint8
+	RequestNospam  uint32
+	LastSeen       uint64 // unix time, 0 if never seen
+}
+
+// LastSeenTime returns the zero time if the friend was never seen.
+func (this Friend) LastSeenTime() time.Time {
+	if this.LastSeen == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(this.LastSeen), 0)
+}
+
+type ConferencePeer struct {
+	RealPublicKey PublicKey
+	TempPublicKey PublicKey
+	PeerNumber    uint16
+	LastActive    uint64
+	Nick          string
+}
+
+type Conference struct {
+	Type               uint8
+	ID                 [CONFERENCE_ID_SIZE]byte
+	MessageNumber      uint32
+	LossyMessageNumber uint16
+	PeerNumber         uint16
+	Title              string
+	Peers              []ConferencePeer
+}
+
+// Section is a raw section of the state format.
+type Section struct {
+	Type uint16
+	Data []byte
+}
+
+// Savedata is a decoded tox profile.
+type Savedata struct {
+	Nospam        uint32 // as tox_self_get_nospam returns it
+	PublicKey     PublicKey
+	SecretKey     [SECRET_KEY_SIZE]byte
+	DHTNodes      []Node
+	Friends       []Friend
+	Name          string
+	StatusMessage string
+	Status        uint8
+	TCPRelays     []Node
+	PathNodes     []Node
+	Conferences   []Conference
+
+	// Unknown holds the sections of types this package does not decode.
+	Unknown []Section
+}
+
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encMagic)
+}
+
+// Sections splits data into its raw sections, without the END section.
+func Sections(data []byte) ([]Section, error) {
+	if IsEncrypted(data) {
+		return nil, ErrEncrypted
+	}
+	if len(data) < 8 || binary.LittleEndian.Uint32(data) != 0 ||
+		binary.LittleEndian.Uint32(data[4:]) != STATE_COOKIE_GLOBAL {
+		return nil, ErrBadHeader
+	}
+	data = data[8:]
+
+	var sections []Section
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return sections, fmt.Errorf("section header: %w", ErrTruncated)
+		}
+		length := binary.LittleEndian.Uint32(data)
+		typ := binary.LittleEndian.Uint16(data[4:])
+		if cookie := binary.LittleEndian.Uint16(data[6:]); cookie != STATE_COOKIE_TYPE {
+			return sections, fmt.Errorf("section %d: bad cookie %#x", typ, cookie)
+		}
+		data = data[8:]
+		if uint64(length) > uint64(len(data)) {
+			return sections, fmt.Errorf("section %d: %w", typ, ErrTruncated)
+		}
+		if typ == SECTION_END {
+			break
+		}
+		sections = append(sections, Section{typ, data[:length]})
+		data = data[length:]
+	}
+	return sections, nil
+}
+
+// Parse decodes a plain (not encrypted) savedata.
+func Parse(data []byte) (*Savedata, error) {
+	sections, err := Sections(data)
+	if err != nil {
+		return nil, err
+	}
+
+	this := &Savedata{}
+	for _, sec := range sections {
+		if err := this.parseSection(sec); err != nil {
+			return this, fmt.Errorf("section %d: %w", sec.Type, err)
+		}
+	}
+	return this, nil
+}
+
+func (this *Savedata) parseSection(sec Section) error {
+	var err error
+	b := sec.Data
+	switch sec.Type {
+	case SECTION_NOSPAMKEYS:
+		if len(b) != 4+PUBLIC_KEY_SIZE+SECRET_KEY_SIZE {
+			return fmt.Errorf("nospam keys size %d", len(b))
+		}
+		this.Nospam = binary.BigEndian.Uint32(b)
+		copy(this.PublicKey[:], b[4:])
+		copy(this.SecretKey[:], b[4+PUBLIC_KEY_SIZE:])
+	case SECTION_DHT:
+		err = this.parseDHT(b)
+	case SECTION_FRIENDS:
+		err = this.parseFriends(b)
+	case SECTION_NAME:
+		this.Name = string(b)
+	case SECTION_STATUSMESSAGE:
+		this.StatusMessage = string(b)
+	case SECTION_STATUS:
+		if len(b) != 1 {
+			return fmt.Errorf("status size %d", len(b))
+		}
+		this.Status = b[0]
+	case SECTION_TCP_RELAY:
+		var nodes []Node
+		nodes, err = parseNodes(b)
+		this.TCPRelays = append(this.TCPRelays, nodes...)
+	case SECTION_PATH_NODE:
+		var nodes []Node
+		nodes, err = parseNodes(b)
+		this.PathNodes = append(this.PathNodes, nodes...)
+	case SECTION_CONFERENCES:
+		err = this.parseConferences(b)
+	default:
+		this.Unknown = append(this.Unknown, sec)
+	}
+	return err
+}
+
+// the DHT section nests its own cookie and subsections
+func (this *Savedata) parseDHT(b []byte) error {
+	if len(b) < 4 || binary.LittleEndian.Uint32(b) != DHT_STATE_COOKIE_GLOBAL {
+		return ErrBadHeader
+	}
+	b = b[4:]
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return ErrTruncated
+		}
+		length := binary.LittleEndian.Uint32(b)
+		typ := binary.LittleEndian.Uint16(b[4:])
+		if cookie := binary.LittleEndian.Uint16(b[6:]); cookie != DHT_STATE_COOKIE_TYPE {
+			return fmt.Errorf("dht subsection %d: bad cookie %#x", typ, cookie)
+		}
+		b = b[8:]
+		if uint64(length) > uint64(len(b)) {
+			return ErrTruncated
+		}
+		if typ == DHT_STATE_TYPE_NODES {
+			nodes, err := parseNodes(b[:length])
+			this.DHTNodes = append(this.DHTNodes, nodes...)
+			if err != nil {
+				return err
+			}
+		}
+		b = b[length:]
+	}
+	return nil
+}
+
+func parseNodes(b []byte) ([]Node, error) {
+	var nodes []Node
+	for len(b) > 0 {
+		var node Node
+		var iplen int
+		switch b[0] {
+		case FAMILY_UDP_INET:
+			iplen = net.IPv4len
+		case FAMILY_UDP_INET6:
+			iplen = net.IPv6len
+		case FAMILY_TCP_INET:
+			node.TCP, iplen = true, net.IPv4len
+		case FAMILY_TCP_INET6:
+			node.TCP, iplen = true, net.IPv6len
+		default:
+			return nodes, fmt.Errorf("unknown node family %d", b[0])
+		}
+		size := 1 + iplen + 2 + PUBLIC_KEY_SIZE
+		if len(b) < size {
+			return nodes, ErrTruncated
+		}
+		node.IP = append(net.IP(nil), b[1:1+iplen]...)
+		node.Port = binary.BigEndian.Uint16(b[1+iplen:])
+		copy(node.PublicKey[:], b[1+iplen+2:size])
+		nodes = append(nodes, node)
+		b = b[size:]
+	}
+	return nodes, nil
+}
+
+// clamp keeps a stored length inside its field
+func clamp(n uint16, max int) int {
+	if int(n) > max {
+		return max
+	}
+	return int(n)
+}
+
+func (this *Savedata) parseFriends(b []byte) error {
+	if len(b)%FRIEND_SIZE != 0 {
+		return fmt.Errorf("friends size %d is not a multiple of %d", len(b), FRIEND_SIZE)
+	}
+	for ; len(b) > 0; b = b[FRIEND_SIZE:] {
+		var f Friend
+		p := b
+		f.Status = FriendStatus(p[0])
+		p = p[1:]
+		copy(f.PublicKey[:], p)
+		p = p[PUBLIC_KEY_SIZE:]
+		info := p[:FRIEND_REQUEST_MAX_SIZE]
+		p = p[FRIEND_REQUEST_MAX_SIZE:]
+		f.RequestMessage = string(info[:clamp(binary.BigEndian.Uint16(p), len(info))])
+		p = p[2:]
+		name := p[:FRIEND_NAME_MAX_SIZE]
+		p = p[FRIEND_NAME_MAX_SIZE:]
+		f.Name = string(name[:clamp(binary.BigEndian.Uint16(p), len(name))])
+		p = p[2:]
+		stmsg := p[:FRIEND_STATUS_MAX_SIZE]
+		p = p[FRIEND_STATUS_MAX_SIZE:]
+		f.StatusMessage = string(stmsg[:clamp(binary.BigEndian.Uint16(p), len(stmsg))])
+		p = p[2:]
+		f.UserStatus = p[0]
+		f.RequestNospam = binary.BigEndian.Uint32(p[1:])
+		f.LastSeen = binary.BigEndian.Uint64(p[5:])
+		this.Friends = append(this.Friends, f)
+	}
+	return nil
+}
+
+// cursor reads the variable sized conference records
+type cursor struct {
+	b   []byte
+	err error
+}
+
+func (this *cursor) next(n int) []byte {
+	if this.err != nil || len(this.b) < n {
+		this.err = ErrTruncated
+		return make([]byte, n)
+	}
+	r := this.b[:n]
+	this.b = this.b[n:]
+	return r
+}
+
+func (this *cursor) u8() uint8   { return this.next(1)[0] }
+func (this *cursor) u16() uint16 { return binary.LittleEndian.Uint16(this.next(2)) }
+func (this *cursor) u32() uint32 { return binary.LittleEndian.Uint32(this.next(4)) }
+func (this *cursor) u64() uint64 { return binary.LittleEndian.Uint64(this.next(8)) }
+
+func (this *Savedata) parseConferences(b []byte) error {
+	c := &cursor{b: b}
+	for len(c.b) > 0 && c.err == nil {
+		var conf Conference
+		conf.Type = c.u8()
+		copy(conf.ID[:], c.next(CONFERENCE_ID_SIZE))
+		conf.MessageNumber = c.u32()
+		conf.LossyMessageNumber = c.u16()
+		conf.PeerNumber = c.u16()
+		numpeers := c.u32()
+		conf.Title = string(c.next(int(c.u8())))
+		for i := uint32(0); i < numpeers && c.err == nil; i++ {
+			var peer ConferencePeer
+			copy(peer.RealPublicKey[:], c.next(PUBLIC_KEY_SIZE))
+			copy(peer.TempPublicKey[:], c.next(PUBLIC_KEY_SIZE))
+			peer.PeerNumber = c.u16()
+			peer.LastActive = c.u64()
+			peer.Nick = string(c.next(int(c.u8())))
+			conf.Peers = append(conf.Peers, peer)
+		}
+		if c.err == nil {
+			this.Conferences = append(this.Conferences, conf)
+		}
+	}
+	return c.err
+}
@@ -0,0 +1,247 @@
+package savedata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testNode(family byte, ip net.IP, port uint16, pkb byte) []byte {
+	b := append([]byte{family}, ip...)
+	b = append(b, byte(port>>8), byte(port))
+	return append(b, bytes.Repeat([]byte{pkb}, PUBLIC_KEY_SIZE)...)
+}
+
+func testFriend(pkb byte, name string, lastSeen uint64) []byte {
+	b := make([]byte, FRIEND_SIZE)
+	b[0] = byte(FRIEND_CONFIRMED)
+	copy(b[1:], bytes.Repeat([]byte{pkb}, PUBLIC_KEY_SIZE))
+	off := 1 + PUBLIC_KEY_SIZE + FRIEND_REQUEST_MAX_SIZE + 2
+	copy(b[off:], name)
+	binary.BigEndian.PutUint16(b[off+FRIEND_NAME_MAX_SIZE:], uint16(len(name)))
+	binary.BigEndian.PutUint64(b[FRIEND_SIZE-8:], lastSeen)
+	return b
+}
+
+func testSavedata() []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b[4:], STATE_COOKIE_GLOBAL)
+
+	keys := []byte{0x01, 0x02, 0x03, 0x04}
+	keys = append(keys, bytes.Repeat([]byte{0xaa}, PUBLIC_KEY_SIZE)...)
+	keys = append(keys, bytes.Repeat([]byte{0xbb}, SECRET_KEY_SIZE)...)
+	b = appendSection(b, SECTION_NOSPAMKEYS, STATE_COOKIE_TYPE, keys)
+
+	dht := make([]byte, 4)
+	binary.LittleEndian.PutUint32(dht, DHT_STATE_COOKIE_GLOBAL)
+	dht = appendSection(dht, DHT_STATE_TYPE_NODES, DHT_STATE_COOKIE_TYPE,
+		append(testNode(FAMILY_UDP_INET, net.IPv4(1, 2, 3, 4).To4(), 33445, 0xd1),
+			testNode(FAMILY_UDP_INET6, net.ParseIP("::1"), 33446, 0xd2)...))
+	b = appendSection(b, SECTION_DHT, STATE_COOKIE_TYPE, dht)
+
+	b = appendSection(b, SECTION_FRIENDS, STATE_COOKIE_TYPE, append(testFriend(0xf1, "alice", 1600000000), testFriend(0xf2, "bob", 0)...))
+	b = appendSection(b, SECTION_NAME, STATE_COOKIE_TYPE, []byte("me"))
+	b = appendSection(b, SECTION_STATUSMESSAGE, STATE_COOKIE_TYPE, []byte("hi"))
+	b = appendSection(b, SECTION_STATUS, STATE_COOKIE_TYPE, []byte{2})
+	b = appendSection(b, SECTION_TCP_RELAY, STATE_COOKIE_TYPE, testNode(FAMILY_TCP_INET, net.IPv4(5, 6, 7, 8).To4(), 443, 0xe1))
+	b = appendSection(b, 42, STATE_COOKIE_TYPE, []byte{1, 2, 3})
+
+	conf := []byte{0}
+	conf = append(conf, bytes.Repeat([]byte{0xc1}, CONFERENCE_ID_SIZE)...)
+	conf = append(conf, 7, 0, 0, 0, 1, 0, 3, 0, 1, 0, 0, 0, 2, 'g', 't')
+	conf = append(conf, bytes.Repeat([]byte{0xc2}, PUBLIC_KEY_SIZE*2)...)
+	conf = append(conf, 3, 0, 9, 0, 0, 0, 0, 0, 0, 0, 1, 'p')
+	b = appendSection(b, SECTION_CONFERENCES, STATE_COOKIE_TYPE, conf)
+
+	return appendSection(b, SECTION_END, STATE_COOKIE_TYPE, nil)
+}
+
+func TestParse(t *testing.T) {
+	sd, err := Parse(testSavedata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sd.Nospam != 0x01020304 || sd.PublicKey[0] != 0xaa || sd.SecretKey[31] != 0xbb {
+		t.Error("keys", sd.Nospam, sd.PublicKey, sd.SecretKey)
+	}
+	if sd.Name != "me" || sd.StatusMessage != "hi" || sd.Status != 2 {
+		t.Error("self", sd.Name, sd.StatusMessage, sd.Status)
+	}
+	if len(sd.DHTNodes) != 2 || sd.DHTNodes[0].String() != "udp/1.2.3.4:33445 "+sd.DHTNodes[0].PublicKey.String() ||
+		!sd.DHTNodes[1].IP.Equal(net.ParseIP("::1")) || sd.DHTNodes[1].Port != 33446 {
+		t.Error("dht", sd.DHTNodes)
+	}
+	if len(sd.TCPRelays) != 1 || !sd.TCPRelays[0].TCP || sd.TCPRelays[0].Port != 443 {
+		t.Error("tcp relays", sd.TCPRelays)
+	}
+	if len(sd.Friends) != 2 || sd.Friends[0].Name != "alice" || sd.Friends[0].Status != FRIEND_CONFIRMED ||
+		sd.Friends[0].LastSeenTime().Unix() != 1600000000 || !sd.Friends[1].LastSeenTime().IsZero() {
+		t.Error("friends", sd.Friends)
+	}
+	if len(sd.Conferences) != 1 || sd.Conferences[0].Title != "gt" || sd.Conferences[0].MessageNumber != 7 ||
+		len(sd.Conferences[0].Peers) != 1 || sd.Conferences[0].Peers[0].Nick != "p" || sd.Conferences[0].Peers[0].LastActive != 9 {
+		t.Error("conferences", sd.Conferences)
+	}
+	if len(sd.Unknown) != 1 || sd.Unknown[0].Type != 42 || len(sd.Unknown[0].Data) != 3 {
+		t.Error("unknown", sd.Unknown)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse([]byte("toxEsave....")); err != ErrEncrypted {
+		t.Error("must encrypted", err)
+	}
+	if _, err := Parse([]byte{1, 2, 3}); err != ErrBadHeader {
+		t.Error("must bad header", err)
+	}
+	data := testSavedata()
+	if _, err := Parse(data[:len(data)-20]); !errors.Is(err, ErrTruncated) {
+		t.Error("must truncated", err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	sd, err := Parse(testSavedata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := sd.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd2, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sd, sd2) {
+		t.Errorf("must equal\n%+v\n%+v", sd, sd2)
+	}
+	data2, _ := sd2.Marshal()
+	if !bytes.Equal(data, data2) {
+		t.Error("must stable")
+	}
+
+	var pk PublicKey
+	pk[0] = 1
+	if !sd2.AddFriend(pk) || sd2.AddFriend(pk) || len(sd2.Friends) != 3 {
+		t.Error("must added once", len(sd2.Friends))
+	}
+	if !sd2.RemoveFriend(sd.Friends[0].PublicKey) || sd2.Friends[1].PublicKey != pk {
+		t.Error("must removed")
+	}
+	nospam := sd2.Nospam
+	if err := sd2.RotateNospam(); err != nil || sd2.Nospam == nospam {
+		t.Error("must rotated", err)
+	}
+	sd2.FilterNodes(func(node Node) bool { return !node.TCP })
+	if len(sd2.TCPRelays) != 0 || len(sd2.DHTNodes) != 2 {
+		t.Error("must filtered", sd2.TCPRelays, sd2.DHTNodes)
+	}
+	sd2.Name = string(make([]byte, FRIEND_NAME_MAX_SIZE+1))
+	if _, err := sd2.Marshal(); err == nil {
+		t.Error("must too long")
+	}
+}
+
+func TestRepair(t *testing.T) {
+	good := testSavedata()
+	if _, problems, err := Repair(good); err != nil || len(problems) != 0 {
+		t.Error("must intact", problems, err)
+	}
+
+	// garbage between name and status message, and a cut conferences section
+	data := append([]byte(nil), good[:len(good)-20]...)
+	pos := bytes.Index(data, []byte("me")) + 2
+	data = append(data[:pos], append([]byte("\xff\xff\xff"), data[pos:]...)...)
+	// a friend record cut short
+	fpos := bytes.Index(data, []byte("alice")) - (1 + PUBLIC_KEY_SIZE + FRIEND_REQUEST_MAX_SIZE + 2)
+	binary.LittleEndian.PutUint32(data[fpos-8:], 2*FRIEND_SIZE-10)
+	data = append(data[:fpos+2*FRIEND_SIZE-10], data[fpos+2*FRIEND_SIZE:]...)
+
+	sd, problems, err := Repair(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 4 {
+		t.Error("must 4 problems", problems)
+	}
+	if len(sd.Friends) != 1 || sd.Friends[0].Name != "alice" || sd.StatusMessage != "hi" ||
+		len(sd.TCPRelays) != 1 || len(sd.Conferences) != 0 || sd.PublicKey[0] != 0xaa {
+		t.Errorf("must salvaged %+v", sd)
+	}
+	fixed, err := sd.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(fixed); err != nil {
+		t.Error("must loadable", err)
+	}
+
+	// a name length past the end, or swallowing the sections after it
+	namePos := bytes.Index(good, []byte("me")) - 8
+	for _, length := range []uint32{0xffff, uint32(len(good) - namePos - 8)} {
+		data := append([]byte(nil), good...)
+		binary.LittleEndian.PutUint32(data[namePos:], length)
+		sd, problems, err := Repair(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(problems) != 1 || sd.Name != "me" || sd.StatusMessage != "hi" || len(sd.TCPRelays) != 1 ||
+			len(sd.Conferences) != 1 {
+			t.Errorf("must resync after name %d: %q %+v", length, problems, sd)
+		}
+		if _, err := sd.Marshal(); err != nil {
+			t.Error("must marshal", err)
+		}
+	}
+
+	// an over long name without a header after it
+	data = append([]byte(nil), good[:namePos]...)
+	data = appendSection(data, SECTION_NAME, STATE_COOKIE_TYPE, bytes.Repeat([]byte("n"), FRIEND_NAME_MAX_SIZE+5))
+	sd, problems, err = Repair(data)
+	if err != nil || len(problems) != 1 || len(sd.Name) != FRIEND_NAME_MAX_SIZE {
+		t.Error("must truncate name", len(sd.Name), problems, err)
+	}
+
+	if _, _, err := Repair(good[:20]); err != ErrNoKeys {
+		t.Error("must no keys", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, _ := Parse(testSavedata())
+	b, _ := Parse(testSavedata())
+	if changes := Diff(a, b); len(changes) != 0 {
+		t.Error("must same", changes)
+	}
+
+	b.Name = "you"
+	b.Friends[0].Name = "alice2"
+	b.Friends = b.Friends[:1]
+	b.Friends[0].PublicKey[0] = 0xf3
+	b.Friends = append(b.Friends, a.Friends[0])
+	b.Friends[1].Name = "carol"
+	b.Conferences = nil
+	b.SecretKey[0] = 0
+	changes := Diff(a, b)
+	want := []string{
+		`secret key changed: "(hidden)" -> "(changed)"`,
+		`name changed: "me" -> "you"`,
+		`friend ` + b.Friends[0].PublicKey.String() + ` added: "alice2"`,
+		`friend name ` + a.Friends[0].PublicKey.String() + ` changed: "alice" -> "carol"`,
+		`friend ` + a.Friends[1].PublicKey.String() + ` removed: "bob"`,
+		`conference ` + strings.Repeat("C1", CONFERENCE_ID_SIZE) + ` removed: "gt"`,
+	}
+	if len(changes) != len(want) {
+		t.Fatal("must", len(want), changes)
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Error("must", want[i], c)
+		}
+	}
+}
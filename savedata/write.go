@@ -0,0 +1,222 @@
+package savedata
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Marshal encodes the profile in the section order toxcore writes, followed
+// by the Unknown sections as they are. NewTox loads the result like a
+// tox_get_savedata one.
+func (this *Savedata) Marshal() ([]byte, error) {
+	b := make([]byte, 8, 1024)
+	binary.LittleEndian.PutUint32(b[4:], STATE_COOKIE_GLOBAL)
+
+	keys := make([]byte, 4, 4+PUBLIC_KEY_SIZE+SECRET_KEY_SIZE)
+	binary.BigEndian.PutUint32(keys, this.Nospam)
+	keys = append(append(keys, this.PublicKey[:]...), this.SecretKey[:]...)
+	b = appendSection(b, SECTION_NOSPAMKEYS, STATE_COOKIE_TYPE, keys)
+
+	nodes, err := marshalNodes(this.DHTNodes)
+	if err != nil {
+		return nil, fmt.Errorf("dht: %w", err)
+	}
+	dht := make([]byte, 4, 4+8+len(nodes))
+	binary.LittleEndian.PutUint32(dht, DHT_STATE_COOKIE_GLOBAL)
+	dht = appendSection(dht, DHT_STATE_TYPE_NODES, DHT_STATE_COOKIE_TYPE, nodes)
+	b = appendSection(b, SECTION_DHT, STATE_COOKIE_TYPE, dht)
+
+	friends := make([]byte, 0, len(this.Friends)*FRIEND_SIZE)
+	for i := range this.Friends {
+		if friends, err = appendFriend(friends, &this.Friends[i]); err != nil {
+			return nil, fmt.Errorf("friend %d: %w", i, err)
+		}
+	}
+	b = appendSection(b, SECTION_FRIENDS, STATE_COOKIE_TYPE, friends)
+
+	if len(this.Name) > FRIEND_NAME_MAX_SIZE {
+		return nil, fmt.Errorf("name too long: %d", len(this.Name))
+	}
+	if len(this.StatusMessage) > FRIEND_STATUS_MAX_SIZE {
+		return nil, fmt.Errorf("status message too long: %d", len(this.StatusMessage))
+	}
+	b = appendSection(b, SECTION_NAME, STATE_COOKIE_TYPE, []byte(this.Name))
+	b = appendSection(b, SECTION_STATUSMESSAGE, STATE_COOKIE_TYPE, []byte(this.StatusMessage))
+	b = appendSection(b, SECTION_STATUS, STATE_COOKIE_TYPE, []byte{this.Status})
+
+	if nodes, err = marshalNodes(this.TCPRelays); err != nil {
+		return nil, fmt.Errorf("tcp relays: %w", err)
+	}
+	b = appendSection(b, SECTION_TCP_RELAY, STATE_COOKIE_TYPE, nodes)
+	if nodes, err = marshalNodes(this.PathNodes); err != nil {
+		return nil, fmt.Errorf("path nodes: %w", err)
+	}
+	b = appendSection(b, SECTION_PATH_NODE, STATE_COOKIE_TYPE, nodes)
+
+	var confs []byte
+	for i := range this.Conferences {
+		if confs, err = appendConference(confs, &this.Conferences[i]); err != nil {
+			return nil, fmt.Errorf("conference %d: %w", i, err)
+		}
+	}
+	b = appendSection(b, SECTION_CONFERENCES, STATE_COOKIE_TYPE, confs)
+
+	for _, sec := range this.Unknown {
+		b = appendSection(b, sec.Type, STATE_COOKIE_TYPE, sec.Data)
+	}
+	return appendSection(b, SECTION_END, STATE_COOKIE_TYPE, nil), nil
+}
+
+func appendSection(b []byte, typ uint16, cookie uint16, data []byte) []byte {
+	var hdr [8]byte
+	binary.LittleEndian.PutUint32(hdr[:], uint32(len(data)))
+	binary.LittleEndian.PutUint16(hdr[4:], typ)
+	binary.LittleEndian.PutUint16(hdr[6:], cookie)
+	return append(append(b, hdr[:]...), data...)
+}
+
+func marshalNodes(nodes []Node) ([]byte, error) {
+	var b []byte
+	for _, node := range nodes {
+		var family byte
+		ip := node.IP.To4()
+		switch {
+		case ip != nil && node.TCP:
+			family = FAMILY_TCP_INET
+		case ip != nil:
+			family = FAMILY_UDP_INET
+		case len(node.IP) == net.IPv6len && node.TCP:
+			family, ip = FAMILY_TCP_INET6, node.IP
+		case len(node.IP) == net.IPv6len:
+			family, ip = FAMILY_UDP_INET6, node.IP
+		default:
+			return nil, fmt.Errorf("invalid node ip %v", node.IP)
+		}
+		b = append(append(b, family), ip...)
+		b = append(b, byte(node.Port>>8), byte(node.Port))
+		b = append(b, node.PublicKey[:]...)
+	}
+	return b, nil
+}
+
+func appendFriend(b []byte, f *Friend) ([]byte, error) {
+	if len(f.RequestMessage) > FRIEND_REQUEST_MAX_SIZE || len(f.Name) > FRIEND_NAME_MAX_SIZE ||
+		len(f.StatusMessage) > FRIEND_STATUS_MAX_SIZE {
+		return nil, fmt.Errorf("field too long")
+	}
+	var rec [FRIEND_SIZE]byte
+	p := rec[:]
+	p[0] = byte(f.Status)
+	p = p[1:]
+	copy(p, f.PublicKey[:])
+	p = p[PUBLIC_KEY_SIZE:]
+	copy(p, f.RequestMessage)
+	p = p[FRIEND_REQUEST_MAX_SIZE:]
+	binary.BigEndian.PutUint16(p, uint16(len(f.RequestMessage)))
+	p = p[2:]
+	copy(p, f.Name)
+	p = p[FRIEND_NAME_MAX_SIZE:]
+	binary.BigEndian.PutUint16(p, uint16(len(f.Name)))
+	p = p[2:]
+	copy(p, f.StatusMessage)
+	p = p[FRIEND_STATUS_MAX_SIZE:]
+	binary.BigEndian.PutUint16(p, uint16(len(f.StatusMessage)))
+	p = p[2:]
+	p[0] = f.UserStatus
+	binary.BigEndian.PutUint32(p[1:], f.RequestNospam)
+	binary.BigEndian.PutUint64(p[5:], f.LastSeen)
+	return append(b, rec[:]...), nil
+}
+
+func appendConference(b []byte, conf *Conference) ([]byte, error) {
+	if len(conf.Title) > 255 {
+		return nil, fmt.Errorf("title too long: %d", len(conf.Title))
+	}
+	var le [8]byte
+	b = append(append(b, conf.Type), conf.ID[:]...)
+	binary.LittleEndian.PutUint32(le[:], conf.MessageNumber)
+	b = append(b, le[:4]...)
+	binary.LittleEndian.PutUint16(le[:], conf.LossyMessageNumber)
+	b = append(b, le[:2]...)
+	binary.LittleEndian.PutUint16(le[:], conf.PeerNumber)
+	b = append(b, le[:2]...)
+	binary.LittleEndian.PutUint32(le[:], uint32(len(conf.Peers)))
+	b = append(b, le[:4]...)
+	b = append(append(b, byte(len(conf.Title))), conf.Title...)
+	for _, peer := range conf.Peers {
+		if len(peer.Nick) > 255 {
+			return nil, fmt.Errorf("peer %d nick too long: %d", peer.PeerNumber, len(peer.Nick))
+		}
+		b = append(append(b, peer.RealPublicKey[:]...), peer.TempPublicKey[:]...)
+		binary.LittleEndian.PutUint16(le[:], peer.PeerNumber)
+		b = append(b, le[:2]...)
+		binary.LittleEndian.PutUint64(le[:], peer.LastActive)
+		b = append(b, le[:8]...)
+		b = append(append(b, byte(len(peer.Nick))), peer.Nick...)
+	}
+	return b, nil
+}
+
+// FindFriend returns the index of the friend with pk in Friends, or -1.
+func (this *Savedata) FindFriend(pk PublicKey) int {
+	for i := range this.Friends {
+		if this.Friends[i].PublicKey == pk {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddFriend adds pk as a confirmed friend, like tox_friend_add_norequest.
+// Returns false if pk is already a friend.
+func (this *Savedata) AddFriend(pk PublicKey) bool {
+	if this.FindFriend(pk) >= 0 {
+		return false
+	}
+	this.Friends = append(this.Friends, Friend{Status: FRIEND_CONFIRMED, PublicKey: pk})
+	return true
+}
+
+// RemoveFriend returns false if pk is not a friend. Friend numbers of the
+// friends after it shift down by one when the profile is loaded.
+func (this *Savedata) RemoveFriend(pk PublicKey) bool {
+	i := this.FindFriend(pk)
+	if i < 0 {
+		return false
+	}
+	this.Friends = append(this.Friends[:i], this.Friends[i+1:]...)
+	return true
+}
+
+// RotateNospam sets a new random nospam, invalidating the old address.
+func (this *Savedata) RotateNospam() error {
+	var b [4]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			return err
+		}
+		if nospam := binary.BigEndian.Uint32(b[:]); nospam != this.Nospam {
+			this.Nospam = nospam
+			return nil
+		}
+	}
+}
+
+// FilterNodes keeps the DHT nodes, TCP relays and path nodes for which keep
+// returns true.
+func (this *Savedata) FilterNodes(keep func(Node) bool) {
+	filter := func(nodes []Node) []Node {
+		var kept []Node
+		for _, node := range nodes {
+			if keep(node) {
+				kept = append(kept, node)
+			}
+		}
+		return kept
+	}
+	this.DHTNodes = filter(this.DHTNodes)
+	this.TCPRelays = filter(this.TCPRelays)
+	this.PathNodes = filter(this.PathNodes)
+}
@@ -0,0 +1,218 @@
+package tox
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TokTok/go-toxcore-c/savedata"
+)
+
+var ErrNoSavedata = errors.New("no savedata stored")
+var ErrSavedataEncrypted = errors.New("savedata is encrypted, check it with Profile.Check")
+
+// SaveStore persists savedata. Load returns ErrNoSavedata when nothing was
+// saved yet.
+type SaveStore interface {
+	Load() ([]byte, error)
+	Save(data []byte) error
+}
+
+// SaveTo saves the current savedata to store.
+func (this *Tox) SaveTo(store SaveStore) error {
+	return store.Save(this.GetSavedata())
+}
+
+// CheckSavedata test-loads data before it replaces a good copy. It takes
+// plain data only, encrypted data needs the pass key, see Profile.Check.
+func CheckSavedata(data []byte) error {
+	if savedata.IsEncrypted(data) {
+		return ErrSavedataEncrypted
+	}
+	sd, err := savedata.Parse(data)
+	if err != nil {
+		return err
+	}
+	if sd.PublicKey == (savedata.PublicKey{}) {
+		return errors.New("savedata has no keys")
+	}
+	return nil
+}
+
+// FileStore keeps the savedata in a single file.
+type FileStore struct {
+	Path string
+}
+
+func (this *FileStore) Load() ([]byte, error) {
+	data, err := ioutil.ReadFile(this.Path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoSavedata
+	}
+	return data, err
+}
+
+func (this *FileStore) Save(data []byte) error {
+	return writeFileAtomic(this.Path, data)
+}
+
+// MemoryStore keeps the savedata in memory, for tests and short lived bots.
+type MemoryStore struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (this *MemoryStore) Load() ([]byte, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.data == nil {
+		return nil, ErrNoSavedata
+	}
+	return append([]byte(nil), this.data...), nil
+}
+
+func (this *MemoryStore) Save(data []byte) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.data = append([]byte(nil), data...)
+	return nil
+}
+
+// Snapshot is one saved version of a VersionedStore.
+type Snapshot struct {
+	Name string
+	Time time.Time
+	Size int64
+}
+
+const snapshotTimeFormat = "20060102T150405.000000000Z"
+const snapshotExt = ".tox"
+
+// VersionedStore keeps the last Keep snapshots of the savedata in Dir, one
+// file per save named by its UTC time. Saves that fail Check are rejected,
+// so a corrupt savedata never replaces a good one. The default CheckSavedata
+// takes plain savedata only, for encrypted snapshots set Check to the
+// Profile's Check.
+type VersionedStore struct {
+	Dir   string
+	Keep  int
+	Check func(data []byte) error
+
+	mu sync.Mutex
+}
+
+// NewVersionedStore creates dir if needed. It checks with CheckSavedata.
+func NewVersionedStore(dir string, keep int) (*VersionedStore, error) {
+	if keep < 1 {
+		return nil, fmt.Errorf("invalid snapshot count: %d", keep)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &VersionedStore{Dir: dir, Keep: keep, Check: CheckSavedata}, nil
+}
+
+// List returns the snapshots, newest first.
+func (this *VersionedStore) List() ([]Snapshot, error) {
+	files, err := ioutil.ReadDir(this.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var snaps []Snapshot
+	for _, fi := range files {
+		name := fi.Name()
+		if !fi.Mode().IsRegular() || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		tm, err := time.Parse(snapshotTimeFormat, strings.TrimSuffix(name, snapshotExt))
+		if err != nil {
+			continue
+		}
+		snaps = append(snaps, Snapshot{name, tm, fi.Size()})
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Time.After(snaps[j].Time) })
+	return snaps, nil
+}
+
+// Load returns the newest snapshot that passes Check.
+func (this *VersionedStore) Load() ([]byte, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	snaps, err := this.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range snaps {
+		data, err := this.LoadSnapshot(snap.Name)
+		if err == nil && this.check(data) == nil {
+			return data, nil
+		}
+	}
+	return nil, ErrNoSavedata
+}
+
+func (this *VersionedStore) LoadSnapshot(name string) ([]byte, error) {
+	if filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid snapshot name: %q", name)
+	}
+	return ioutil.ReadFile(filepath.Join(this.Dir, name))
+}
+
+func (this *VersionedStore) Save(data []byte) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.save(data)
+}
+
+// Restore saves the snapshot name again as the newest one, the snapshots
+// after it are kept until they are pruned.
+func (this *VersionedStore) Restore(name string) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	data, err := this.LoadSnapshot(name)
+	if err != nil {
+		return err
+	}
+	return this.save(data)
+}
+
+func (this *VersionedStore) check(data []byte) error {
+	if this.Check == nil {
+		return nil
+	}
+	return this.Check(data)
+}
+
+func (this *VersionedStore) save(data []byte) error {
+	if err := this.check(data); err != nil {
+		return fmt.Errorf("savedata rejected: %w", err)
+	}
+
+	snaps, err := this.List()
+	if err != nil {
+		return err
+	}
+	tm := time.Now().UTC()
+	// names must stay unique and ordered even for saves within a clock tick
+	if len(snaps) > 0 && !tm.After(snaps[0].Time) {
+		tm = snaps[0].Time.Add(time.Nanosecond)
+	}
+	name := tm.Format(snapshotTimeFormat) + snapshotExt
+	if err := writeFileAtomic(filepath.Join(this.Dir, name), data); err != nil {
+		return err
+	}
+
+	// snaps doesn't have the new one yet
+	for i := this.Keep - 1; i < len(snaps); i++ {
+		os.Remove(filepath.Join(this.Dir, snaps[i].Name))
+	}
+	return nil
+}
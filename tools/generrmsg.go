@@ -11,7 +11,44 @@ import (
 	"github.com/go-clang/v3.8/clang"
 )
 
+// headers to scan, with the enum prefixes we generate for
+var headers = []struct {
+	file     string
+	prefixes []string
+}{
+	{"tox/tox.h", []string{"TOX_ERR_"}},
+	{"tox/toxav.h", []string{"TOXAV_ERR_"}},
+	{"tox/toxencryptsave.h", []string{"TOX_ERR_"}},
+}
+
+var incdir = flag.String("incdir", "/usr/local/include", "toxcore include dir")
+
+func hasPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// TOX_ERR_FRIEND_ADD => ERR_FRIEND_ADD, TOXAV_ERR_CALL => AV_ERR_CALL
+func goName(cname string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(cname, "TOX"), "_")
+}
+
+// ERR_FRIEND_ADD => errFriendAddText, AV_ERR_CALL => avErrCallText
+func lookupName(name string) string {
+	fields := strings.Split(strings.ToLower(name), "_")
+	for i := 1; i < len(fields); i++ {
+		fields[i] = strings.Title(fields[i])
+	}
+	return strings.Join(fields, "") + "Text"
+}
+
 func main() {
+	flag.Parse()
+
 	idx := clang.NewIndex(0, 1)
 	defer idx.Dispose()
 
@@ -37,74 +74,88 @@ func main() {
 		}
 	}
 
-	cmdArgs := []string{"-std=c99", fmt.Sprintf("-I%s/include", clincdir)}
-	tu := idx.ParseTranslationUnit("/usr/local/include/tox/tox.h", cmdArgs, nil, 0)
-	defer tu.Dispose()
-
-	for _, d := range tu.Diagnostics() {
-		log.Println("PROBLEM:", d.Spelling())
-	}
+	cmdArgs := []string{"-std=c99", fmt.Sprintf("-I%s/include", clincdir), "-I" + *incdir}
 
 	fmt.Fprintf(os.Stdout, "package tox\n")
 	fmt.Fprintf(os.Stdout, "/*\n")
-	fmt.Fprintf(os.Stdout, "#include \"tox/tox.h\"\n")
+	for _, hdr := range headers {
+		fmt.Fprintf(os.Stdout, "#include \"%s\"\n", hdr.file)
+	}
 	fmt.Fprintf(os.Stdout, "*/\n")
 	fmt.Fprintf(os.Stdout, "import \"C\"\n")
 
-	var enumDecls = make(map[uint32]bool)
-	var enumConstDecls = make(map[uint32]bool)
-	var curEnumName string
-
-	tuc := tu.TranslationUnitCursor()
-	tuc.Visit(func(cursor, parent clang.Cursor) (status clang.ChildVisitResult) {
-		switch cursor.Kind() {
-		case clang.Cursor_EnumDecl:
-			//  log.Println(cursor.BriefCommentText())
-			if !strings.HasPrefix(cursor.Spelling(), "TOX_ERR_") {
-				break
-			}
-			if _, ok := enumDecls[cursor.HashCursor()]; ok {
-				break
-			}
-			enumDecls[cursor.HashCursor()] = true
-			curEnumName = cursor.Spelling()[3:]
-			log.Println(cursor.Type().Kind().String(), cursor.Type().Spelling())
-			fmt.Fprintf(os.Stdout, "\nvar %sS = make(map[int]string)\n", cursor.Spelling()[3:])
-			fmt.Fprintf(os.Stdout, "func init(){%sS[%d] = \"TE%02d: %s\"}\n", curEnumName, -1, -1, curEnumName)
-
-		case clang.Cursor_EnumConstantDecl:
-			if !strings.HasPrefix(cursor.Spelling(), "TOX_ERR_") {
-				break
-			}
-			if _, ok := enumConstDecls[cursor.HashCursor()]; ok {
-				break
-			}
-			enumConstDecls[cursor.HashCursor()] = true
-
-			// log.Println(cursor.BriefCommentText())
-			// log.Println(cursor.Kind().String(), cursor.Type().Kind().String(), cursor.Type().Spelling(), cursor.Spelling())
-			constComment := cursor.BriefCommentText()
-			constComment = strings.Replace(constComment, "\"", "\\\"", -1)
-			constName := cursor.Spelling()
-			enumNameFields := strings.Split(cursor.Spelling(), "_")
-			enumName := strings.Join(enumNameFields[1:len(enumNameFields)-1], "_")
-			enumName = curEnumName
-			constValue := cursor.EnumConstantDeclValue()
-			if false {
-				log.Println(enumName, constName, constValue, constComment)
-			}
-			fmt.Fprintf(os.Stdout, "const %s = int(C.%s) // %d\n", constName[4:], constName, constValue)
-			fmt.Fprintf(os.Stdout, "func init(){%sS[%s] = \"TE%02d: %s\"}\n", enumName, constName[4:], constValue, constComment)
+	// enums are shared between headers (toxav.h includes tox.h), emit them once
+	var enumDecls = make(map[string]bool)
+	var enumConstDecls = make(map[string]bool)
+	var enumNames []string
 
-		default:
-			// log.Println(cursor.Kind().String(), cursor.Type().Kind().String(), cursor.Type().Spelling(), cursor.Spelling())
+	for _, hdr := range headers {
+		tu := idx.ParseTranslationUnit(*incdir+"/"+hdr.file, cmdArgs, nil, 0)
+
+		for _, d := range tu.Diagnostics() {
+			log.Println("PROBLEM:", d.Spelling())
 		}
 
-		return clang.ChildVisit_Recurse
-	})
+		var curEnumName string
+		tuc := tu.TranslationUnitCursor()
+		tuc.Visit(func(cursor, parent clang.Cursor) (status clang.ChildVisitResult) {
+			switch cursor.Kind() {
+			case clang.Cursor_EnumDecl:
+				//  log.Println(cursor.BriefCommentText())
+				if !hasPrefix(cursor.Spelling(), hdr.prefixes) {
+					break
+				}
+				if _, ok := enumDecls[cursor.Spelling()]; ok {
+					curEnumName = ""
+					break
+				}
+				enumDecls[cursor.Spelling()] = true
+				curEnumName = "_" + goName(cursor.Spelling())
+				enumNames = append(enumNames, goName(cursor.Spelling()))
+				log.Println(cursor.Type().Kind().String(), cursor.Type().Spelling())
+				fmt.Fprintf(os.Stdout, "\nvar %sS = make(map[int]string)\n", curEnumName)
+				fmt.Fprintf(os.Stdout, "func init(){%sS[%d] = \"TE%02d: %s\"}\n", curEnumName, -1, -1, curEnumName)
+
+			case clang.Cursor_EnumConstantDecl:
+				if !hasPrefix(cursor.Spelling(), hdr.prefixes) || curEnumName == "" {
+					break
+				}
+				if _, ok := enumConstDecls[cursor.Spelling()]; ok {
+					break
+				}
+				enumConstDecls[cursor.Spelling()] = true
+
+				// log.Println(cursor.BriefCommentText())
+				// log.Println(cursor.Kind().String(), cursor.Type().Kind().String(), cursor.Type().Spelling(), cursor.Spelling())
+				constComment := cursor.BriefCommentText()
+				constComment = strings.Replace(constComment, "\"", "\\\"", -1)
+				constName := cursor.Spelling()
+				enumName := curEnumName
+				constValue := cursor.EnumConstantDeclValue()
+				if false {
+					log.Println(enumName, constName, constValue, constComment)
+				}
+				fmt.Fprintf(os.Stdout, "const %s = int(C.%s) // %d\n", goName(constName), constName, constValue)
+				fmt.Fprintf(os.Stdout, "func init(){%sS[%s] = \"TE%02d: %s\"}\n", enumName, goName(constName), constValue, constComment)
+
+			default:
+				// log.Println(cursor.Kind().String(), cursor.Type().Kind().String(), cursor.Type().Spelling(), cursor.Spelling())
+			}
+
+			return clang.ChildVisit_Recurse
+		})
+		tu.Dispose()
+	}
+
+	// lookup functions, see errtext in errors.go
+	fmt.Fprintf(os.Stdout, "\n")
+	for _, name := range enumNames {
+		fmt.Fprintf(os.Stdout, "func %s(code int) string { return errtext(_%sS, code) }\n", lookupName(name), name)
+	}
 }
 
 /*
 result structure:
 var _enumName_S map[int]string
+func enumNameText(code int) string
 */
@@ -35,11 +35,15 @@ static inline __attribute__((__unused__)) void fixnousetox(void) {
 */
 import "C"
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
 	"strings"
 	// "sync"
+	"time"
 	"unsafe"
 
 	deadlock "github.com/sasha-s/go-deadlock"
@@ -54,20 +58,22 @@ type cb_friend_request_ftype func(this *Tox, pubkey string, message string, user
 type cb_friend_message_ftype func(this *Tox, friendNumber uint32, message string, userData interface{})
 type cb_friend_name_ftype func(this *Tox, friendNumber uint32, newName string, userData interface{})
 type cb_friend_status_message_ftype func(this *Tox, friendNumber uint32, newStatus string, userData interface{})
-type cb_friend_status_ftype func(this *Tox, friendNumber uint32, status int, userData interface{})
-type cb_friend_connection_status_ftype func(this *Tox, friendNumber uint32, status int, userData interface{})
+type cb_friend_status_ftype func(this *Tox, friendNumber uint32, status UserStatus, userData interface{})
+type cb_friend_connection_status_ftype func(this *Tox, friendNumber uint32, status ConnectionType, userData interface{})
 type cb_friend_typing_ftype func(this *Tox, friendNumber uint32, isTyping uint8, userData interface{})
 type cb_friend_read_receipt_ftype func(this *Tox, friendNumber uint32, receipt uint32, userData interface{})
 type cb_friend_lossy_packet_ftype func(this *Tox, friendNumber uint32, data string, userData interface{})
 type cb_friend_lossless_packet_ftype func(this *Tox, friendNumber uint32, data string, userData interface{})
+type cb_friend_lossy_packet_bytes_ftype func(this *Tox, friendNumber uint32, data []byte, userData interface{})
+type cb_friend_lossless_packet_bytes_ftype func(this *Tox, friendNumber uint32, data []byte, userData interface{})
 
 // self callback type
-type cb_self_connection_status_ftype func(this *Tox, status int, userData interface{})
+type cb_self_connection_status_ftype func(this *Tox, status ConnectionType, userData interface{})
 
 // file callback type
 type cb_file_recv_control_ftype func(this *Tox, friendNumber uint32, fileNumber uint32,
-	control int, userData interface{})
-type cb_file_recv_ftype func(this *Tox, friendNumber uint32, fileNumber uint32, kind uint32, fileSize uint64,
+	control FileControlType, userData interface{})
+type cb_file_recv_ftype func(this *Tox, friendNumber uint32, fileNumber uint32, kind FileKind, fileSize uint64,
 	fileName string, userData interface{})
 type cb_file_recv_chunk_ftype func(this *Tox, friendNumber uint32, fileNumber uint32, position uint64,
 	data []byte, userData interface{})
@@ -83,37 +89,45 @@ type Tox struct {
 	// mu sync.RWMutex
 
 	// some callbacks, should be private
-	cb_friend_requests           map[unsafe.Pointer]interface{}
-	cb_friend_messages           map[unsafe.Pointer]interface{}
-	cb_friend_names              map[unsafe.Pointer]interface{}
-	cb_friend_status_messages    map[unsafe.Pointer]interface{}
-	cb_friend_statuss            map[unsafe.Pointer]interface{}
-	cb_friend_connection_statuss map[unsafe.Pointer]interface{}
-	cb_friend_typings            map[unsafe.Pointer]interface{}
-	cb_friend_read_receipts      map[unsafe.Pointer]interface{}
-	cb_friend_lossy_packets      map[unsafe.Pointer]interface{}
-	cb_friend_lossless_packets   map[unsafe.Pointer]interface{}
-	cb_self_connection_statuss   map[unsafe.Pointer]interface{}
-
-	cb_conference_invites            map[unsafe.Pointer]interface{}
-	cb_conference_messages           map[unsafe.Pointer]interface{}
-	cb_conference_actions            map[unsafe.Pointer]interface{}
-	cb_conference_titles             map[unsafe.Pointer]interface{}
-	cb_conference_peer_names         map[unsafe.Pointer]interface{}
-	cb_conference_peer_list_changeds map[unsafe.Pointer]interface{}
-
-	cb_file_recv_controls  map[unsafe.Pointer]interface{}
-	cb_file_recvs          map[unsafe.Pointer]interface{}
-	cb_file_recv_chunks    map[unsafe.Pointer]interface{}
-	cb_file_chunk_requests map[unsafe.Pointer]interface{}
+	cb_friend_requests           map[CallbackHandle]callbackEntry
+	cb_friend_messages           map[CallbackHandle]callbackEntry
+	cb_friend_names              map[CallbackHandle]callbackEntry
+	cb_friend_status_messages    map[CallbackHandle]callbackEntry
+	cb_friend_statuss            map[CallbackHandle]callbackEntry
+	cb_friend_connection_statuss map[CallbackHandle]callbackEntry
+	cb_friend_typings            map[CallbackHandle]callbackEntry
+	cb_friend_read_receipts      map[CallbackHandle]callbackEntry
+	cb_friend_lossy_packets      map[CallbackHandle]callbackEntry
+	cb_friend_lossless_packets   map[CallbackHandle]callbackEntry
+	cb_self_connection_statuss   map[CallbackHandle]callbackEntry
+
+	cb_conference_invites            map[CallbackHandle]callbackEntry
+	cb_conference_messages           map[CallbackHandle]callbackEntry
+	cb_conference_actions            map[CallbackHandle]callbackEntry
+	cb_conference_titles             map[CallbackHandle]callbackEntry
+	cb_conference_peer_names         map[CallbackHandle]callbackEntry
+	cb_conference_peer_list_changeds map[CallbackHandle]callbackEntry
+
+	cb_file_recv_controls  map[CallbackHandle]callbackEntry
+	cb_file_recvs          map[CallbackHandle]callbackEntry
+	cb_file_recv_chunks    map[CallbackHandle]callbackEntry
+	cb_file_chunk_requests map[CallbackHandle]callbackEntry
 
 	cb_audios map[uint32]interface{} // groupNumber => cb_audio_ftype
 
 	cb_iterate_data              interface{}
 	cb_conference_message_setted bool
+	cb_next_handle               CallbackHandle
+	cb_removers                  map[CallbackHandle]func()
 
-	hooks  callHookMethods
-	cbevts []func() // no need lock
+	hooks        callHookMethods
+	cbevts       []func() // no need lock
+	evstream     *eventStream
+	autosave     *autosaver
+	bootstrapper *Bootstrapper
+	watchdog     *Watchdog
+	conntimes    connTimes
+	avmu         deadlock.Mutex // held by ToxAV.Run iterations and both Kills, whatever ThreadSafe is
 }
 
 var cbUserDatas = newUserData()
@@ -121,400 +135,477 @@ var cbUserDatas = newUserData()
 //export callbackFriendRequestWrapperForC
 func callbackFriendRequestWrapperForC(m *C.Tox, a0 *C.cuint8_t, a1 *C.cuint8_t, a2 C.size_t, a3 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_requests {
-		pubkey_b := C.GoBytes(unsafe.Pointer(a0), C.int(PUBLIC_KEY_SIZE))
-		pubkey := hex.EncodeToString(pubkey_b)
-		pubkey = strings.ToUpper(pubkey)
-		message_b := C.GoBytes(unsafe.Pointer(a1), C.int(a2))
-		message := string(message_b)
-		cbfn := *(*cb_friend_request_ftype)(cbfni)
+	pubkey_b := C.GoBytes(unsafe.Pointer(a0), C.int(PUBLIC_KEY_SIZE))
+	pubkey := hex.EncodeToString(pubkey_b)
+	pubkey = strings.ToUpper(pubkey)
+	message_b := C.GoBytes(unsafe.Pointer(a1), C.int(a2))
+	message := string(message_b)
+	for _, cbe := range this.cb_friend_requests {
+		cbfn, ud := *(*cb_friend_request_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, pubkey, message, ud) })
 	}
+	this.putevt(FriendRequestEvent{pubkey, message})
 }
 
 func (this *Tox) CallbackFriendRequest(cbfn cb_friend_request_ftype, userData interface{}) {
 	this.CallbackFriendRequestAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendRequestAdd(cbfn cb_friend_request_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_friend_requests[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_requests[cbfnp] = userData
-
-	C.tox_callback_friend_request(this.toxcore, (*C.tox_friend_request_cb)(C.callbackFriendRequestWrapperForC))
+func (this *Tox) CallbackFriendRequestAdd(cbfn cb_friend_request_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_requests, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_request(this.toxcore, (*C.tox_friend_request_cb)(C.callbackFriendRequestWrapperForC))
+		} else {
+			C.tox_callback_friend_request(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFriendMessageWrapperForC
 func callbackFriendMessageWrapperForC(m *C.Tox, a0 C.uint32_t, mtype C.Tox_Message_Type,
 	a1 *C.cuint8_t, a2 C.size_t, a3 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_messages {
-		message_ := C.GoStringN((*C.char)(unsafe.Pointer(a1)), (C.int)(a2))
-		cbfn := *(*cb_friend_message_ftype)(cbfni)
+	message_ := C.GoStringN((*C.char)(unsafe.Pointer(a1)), (C.int)(a2))
+	for _, cbe := range this.cb_friend_messages {
+		cbfn, ud := *(*cb_friend_message_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, uint32(a0), message_, ud) })
 	}
+	this.putevt(FriendMessageEvent{uint32(a0), MessageType(mtype), message_})
 }
 
 func (this *Tox) CallbackFriendMessage(cbfn cb_friend_message_ftype, userData interface{}) {
 	this.CallbackFriendMessageAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendMessageAdd(cbfn cb_friend_message_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_friend_messages[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_messages[cbfnp] = userData
-
-	C.tox_callback_friend_message(this.toxcore, (*C.tox_friend_message_cb)(C.callbackFriendMessageWrapperForC))
+func (this *Tox) CallbackFriendMessageAdd(cbfn cb_friend_message_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_messages, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_message(this.toxcore, (*C.tox_friend_message_cb)(C.callbackFriendMessageWrapperForC))
+		} else {
+			C.tox_callback_friend_message(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFriendNameWrapperForC
 func callbackFriendNameWrapperForC(m *C.Tox, a0 C.uint32_t, a1 *C.cuint8_t, a2 C.size_t, a3 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_names {
-		name := C.GoStringN((*C.char)((unsafe.Pointer)(a1)), C.int(a2))
-		cbfn := *(*cb_friend_name_ftype)(cbfni)
+	this.markDirty()
+	name := C.GoStringN((*C.char)((unsafe.Pointer)(a1)), C.int(a2))
+	for _, cbe := range this.cb_friend_names {
+		cbfn, ud := *(*cb_friend_name_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, uint32(a0), name, ud) })
 	}
+	this.putevt(FriendNameEvent{uint32(a0), name})
 }
 
 func (this *Tox) CallbackFriendName(cbfn cb_friend_name_ftype, userData interface{}) {
 	this.CallbackFriendNameAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendNameAdd(cbfn cb_friend_name_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_friend_names[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_names[cbfnp] = userData
-
-	C.tox_callback_friend_name(this.toxcore, (*C.tox_friend_name_cb)(C.callbackFriendNameWrapperForC))
+func (this *Tox) CallbackFriendNameAdd(cbfn cb_friend_name_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_names, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_name(this.toxcore, (*C.tox_friend_name_cb)(C.callbackFriendNameWrapperForC))
+		} else {
+			C.tox_callback_friend_name(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFriendStatusMessageWrapperForC
 func callbackFriendStatusMessageWrapperForC(m *C.Tox, a0 C.uint32_t, a1 *C.cuint8_t, a2 C.size_t, a3 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_status_messages {
-		statusText := C.GoStringN((*C.char)(unsafe.Pointer(a1)), C.int(a2))
-		cbfn := *(*cb_friend_status_message_ftype)(cbfni)
+	this.markDirty()
+	statusText := C.GoStringN((*C.char)(unsafe.Pointer(a1)), C.int(a2))
+	for _, cbe := range this.cb_friend_status_messages {
+		cbfn, ud := *(*cb_friend_status_message_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, uint32(a0), statusText, ud) })
 	}
+	this.putevt(FriendStatusMessageEvent{uint32(a0), statusText})
 }
 
 func (this *Tox) CallbackFriendStatusMessage(cbfn cb_friend_status_message_ftype, userData interface{}) {
 	this.CallbackFriendStatusMessageAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendStatusMessageAdd(cbfn cb_friend_status_message_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_friend_status_messages[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_status_messages[cbfnp] = userData
-
-	C.tox_callback_friend_status_message(this.toxcore, (*C.tox_friend_status_message_cb)(C.callbackFriendStatusMessageWrapperForC))
+func (this *Tox) CallbackFriendStatusMessageAdd(cbfn cb_friend_status_message_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_status_messages, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_status_message(this.toxcore, (*C.tox_friend_status_message_cb)(C.callbackFriendStatusMessageWrapperForC))
+		} else {
+			C.tox_callback_friend_status_message(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFriendStatusWrapperForC
 func callbackFriendStatusWrapperForC(m *C.Tox, a0 C.uint32_t, a1 C.Tox_User_Status, a2 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_statuss {
-		cbfn := *(*cb_friend_status_ftype)(cbfni)
-		this.putcbevts(func() { cbfn(this, uint32(a0), int(a1), ud) })
+	this.markDirty()
+	for _, cbe := range this.cb_friend_statuss {
+		cbfn, ud := *(*cb_friend_status_ftype)(cbe.fn), cbe.ud
+		this.putcbevts(func() { cbfn(this, uint32(a0), UserStatus(a1), ud) })
 	}
+	this.putevt(FriendStatusEvent{uint32(a0), UserStatus(a1)})
 }
 
 func (this *Tox) CallbackFriendStatus(cbfn cb_friend_status_ftype, userData interface{}) {
 	this.CallbackFriendStatusAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendStatusAdd(cbfn cb_friend_status_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_friend_statuss[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_statuss[cbfnp] = userData
-
-	C.tox_callback_friend_status(this.toxcore, (*C.tox_friend_status_cb)(C.callbackFriendStatusWrapperForC))
+func (this *Tox) CallbackFriendStatusAdd(cbfn cb_friend_status_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_statuss, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_status(this.toxcore, (*C.tox_friend_status_cb)(C.callbackFriendStatusWrapperForC))
+		} else {
+			C.tox_callback_friend_status(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFriendConnectionStatusWrapperForC
 func callbackFriendConnectionStatusWrapperForC(m *C.Tox, a0 C.uint32_t, a1 C.Tox_Connection, a2 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_connection_statuss {
-		cbfn := *(*cb_friend_connection_status_ftype)((unsafe.Pointer)(cbfni))
-		this.putcbevts(func() { cbfn(this, uint32(a0), int(a1), ud) })
+	this.markDirty()
+	this.conntimes.changed(int64(a0), time.Now())
+	for _, cbe := range this.cb_friend_connection_statuss {
+		cbfn, ud := *(*cb_friend_connection_status_ftype)(cbe.fn), cbe.ud
+		this.putcbevts(func() { cbfn(this, uint32(a0), ConnectionType(a1), ud) })
 	}
+	this.putevt(FriendConnectionStatusEvent{uint32(a0), ConnectionType(a1)})
 }
 
 func (this *Tox) CallbackFriendConnectionStatus(cbfn cb_friend_connection_status_ftype, userData interface{}) {
 	this.CallbackFriendConnectionStatusAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendConnectionStatusAdd(cbfn cb_friend_connection_status_ftype, userData interface{}) {
-	cbfnp := unsafe.Pointer(&cbfn)
-	if _, ok := this.cb_friend_connection_statuss[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_connection_statuss[cbfnp] = userData
-
-	C.tox_callback_friend_connection_status(this.toxcore, (*C.tox_friend_connection_status_cb)(C.callbackFriendConnectionStatusWrapperForC))
+func (this *Tox) CallbackFriendConnectionStatusAdd(cbfn cb_friend_connection_status_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_connection_statuss, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_connection_status(this.toxcore, (*C.tox_friend_connection_status_cb)(C.callbackFriendConnectionStatusWrapperForC))
+		} else {
+			C.tox_callback_friend_connection_status(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFriendTypingWrapperForC
 func callbackFriendTypingWrapperForC(m *C.Tox, a0 C.uint32_t, a1 C.uint8_t, a2 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_typings {
-		cbfn := *(*cb_friend_typing_ftype)(cbfni)
+	for _, cbe := range this.cb_friend_typings {
+		cbfn, ud := *(*cb_friend_typing_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, uint32(a0), uint8(a1), ud) })
 	}
+	this.putevt(FriendTypingEvent{uint32(a0), a1 != 0})
 }
 
 func (this *Tox) CallbackFriendTyping(cbfn cb_friend_typing_ftype, userData interface{}) {
 	this.CallbackFriendTypingAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendTypingAdd(cbfn cb_friend_typing_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_friend_typings[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_typings[cbfnp] = userData
-
-	C.tox_callback_friend_typing(this.toxcore, (*C.tox_friend_typing_cb)(C.callbackFriendTypingWrapperForC))
+func (this *Tox) CallbackFriendTypingAdd(cbfn cb_friend_typing_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_typings, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_typing(this.toxcore, (*C.tox_friend_typing_cb)(C.callbackFriendTypingWrapperForC))
+		} else {
+			C.tox_callback_friend_typing(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFriendReadReceiptWrapperForC
 func callbackFriendReadReceiptWrapperForC(m *C.Tox, a0 C.uint32_t, a1 C.uint32_t, a2 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_read_receipts {
-		cbfn := *(*cb_friend_read_receipt_ftype)(cbfni)
+	for _, cbe := range this.cb_friend_read_receipts {
+		cbfn, ud := *(*cb_friend_read_receipt_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, uint32(a0), uint32(a1), ud) })
 	}
+	this.putevt(FriendReadReceiptEvent{uint32(a0), uint32(a1)})
 }
 
 func (this *Tox) CallbackFriendReadReceipt(cbfn cb_friend_read_receipt_ftype, userData interface{}) {
 	this.CallbackFriendReadReceiptAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendReadReceiptAdd(cbfn cb_friend_read_receipt_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_friend_read_receipts[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_read_receipts[cbfnp] = userData
-
-	C.tox_callback_friend_read_receipt(this.toxcore, (*C.tox_friend_read_receipt_cb)(C.callbackFriendReadReceiptWrapperForC))
+func (this *Tox) CallbackFriendReadReceiptAdd(cbfn cb_friend_read_receipt_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_read_receipts, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_read_receipt(this.toxcore, (*C.tox_friend_read_receipt_cb)(C.callbackFriendReadReceiptWrapperForC))
+		} else {
+			C.tox_callback_friend_read_receipt(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFriendLossyPacketWrapperForC
 func callbackFriendLossyPacketWrapperForC(m *C.Tox, a0 C.uint32_t, a1 *C.cuint8_t, len C.size_t, a2 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_lossy_packets {
-		cbfn := *(*cb_friend_lossy_packet_ftype)(cbfni)
-		msg := C.GoStringN((*C.char)(unsafe.Pointer(a1)), C.int(len))
-		this.putcbevts(func() { cbfn(this, uint32(a0), msg, ud) })
+	for _, cbe := range this.cb_friend_lossy_packets {
+		cbfn, ud := *(*cb_friend_lossy_packet_bytes_ftype)(cbe.fn), cbe.ud
+		data := C.GoBytes(unsafe.Pointer(a1), C.int(len))
+		this.putcbevts(func() { cbfn(this, uint32(a0), data, ud) })
+	}
+	if this.evstream != nil {
+		this.putevt(FriendLossyPacketEvent{uint32(a0), C.GoBytes(unsafe.Pointer(a1), C.int(len))})
 	}
 }
 
 func (this *Tox) CallbackFriendLossyPacket(cbfn cb_friend_lossy_packet_ftype, userData interface{}) {
 	this.CallbackFriendLossyPacketAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendLossyPacketAdd(cbfn cb_friend_lossy_packet_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_friend_lossy_packets[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_lossy_packets[cbfnp] = userData
+func (this *Tox) CallbackFriendLossyPacketAdd(cbfn cb_friend_lossy_packet_ftype, userData interface{}) CallbackHandle {
+	return this.CallbackFriendLossyPacketBytesAdd(func(t *Tox, friendNumber uint32, data []byte, ud interface{}) {
+		cbfn(t, friendNumber, string(data), ud)
+	}, userData)
+}
 
-	C.tox_callback_friend_lossy_packet(this.toxcore, (*C.tox_friend_lossy_packet_cb)(C.callbackFriendLossyPacketWrapperForC))
+// CallbackFriendLossyPacketBytesAdd is like CallbackFriendLossyPacketAdd, but
+// passes the packet as is, including its leading packet id byte.
+func (this *Tox) CallbackFriendLossyPacketBytesAdd(cbfn cb_friend_lossy_packet_bytes_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_lossy_packets, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_lossy_packet(this.toxcore, (*C.tox_friend_lossy_packet_cb)(C.callbackFriendLossyPacketWrapperForC))
+		} else {
+			C.tox_callback_friend_lossy_packet(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFriendLosslessPacketWrapperForC
 func callbackFriendLosslessPacketWrapperForC(m *C.Tox, a0 C.uint32_t, a1 *C.cuint8_t, len C.size_t, a2 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_friend_lossless_packets {
-		cbfn := *(*cb_friend_lossless_packet_ftype)(cbfni)
-		msg := C.GoStringN((*C.char)(unsafe.Pointer(a1)), C.int(len))
-		this.putcbevts(func() { cbfn(this, uint32(a0), msg, ud) })
+	for _, cbe := range this.cb_friend_lossless_packets {
+		cbfn, ud := *(*cb_friend_lossless_packet_bytes_ftype)(cbe.fn), cbe.ud
+		data := C.GoBytes(unsafe.Pointer(a1), C.int(len))
+		this.putcbevts(func() { cbfn(this, uint32(a0), data, ud) })
+	}
+	if this.evstream != nil {
+		this.putevt(FriendLosslessPacketEvent{uint32(a0), C.GoBytes(unsafe.Pointer(a1), C.int(len))})
 	}
 }
 
 func (this *Tox) CallbackFriendLosslessPacket(cbfn cb_friend_lossless_packet_ftype, userData interface{}) {
 	this.CallbackFriendLosslessPacketAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFriendLosslessPacketAdd(cbfn cb_friend_lossless_packet_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_friend_lossless_packets[cbfnp]; ok {
-		return
-	}
-	this.cb_friend_lossless_packets[cbfnp] = userData
+func (this *Tox) CallbackFriendLosslessPacketAdd(cbfn cb_friend_lossless_packet_ftype, userData interface{}) CallbackHandle {
+	return this.CallbackFriendLosslessPacketBytesAdd(func(t *Tox, friendNumber uint32, data []byte, ud interface{}) {
+		cbfn(t, friendNumber, string(data), ud)
+	}, userData)
+}
 
-	C.tox_callback_friend_lossless_packet(this.toxcore, (*C.tox_friend_lossless_packet_cb)(C.callbackFriendLosslessPacketWrapperForC))
+// CallbackFriendLosslessPacketBytesAdd is like CallbackFriendLosslessPacketAdd, but
+// passes the packet as is, including its leading packet id byte.
+func (this *Tox) CallbackFriendLosslessPacketBytesAdd(cbfn cb_friend_lossless_packet_bytes_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_friend_lossless_packets, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_friend_lossless_packet(this.toxcore, (*C.tox_friend_lossless_packet_cb)(C.callbackFriendLosslessPacketWrapperForC))
+		} else {
+			C.tox_callback_friend_lossless_packet(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackSelfConnectionStatusWrapperForC
 func callbackSelfConnectionStatusWrapperForC(m *C.Tox, status C.int, a2 unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_self_connection_statuss {
-		cbfn := *(*cb_self_connection_status_ftype)(cbfni)
-		this.putcbevts(func() { cbfn(this, int(status), ud) })
+	this.conntimes.changed(-1, time.Now())
+	for _, cbe := range this.cb_self_connection_statuss {
+		cbfn, ud := *(*cb_self_connection_status_ftype)(cbe.fn), cbe.ud
+		this.putcbevts(func() { cbfn(this, ConnectionType(status), ud) })
 	}
+	this.putevt(SelfConnectionStatusEvent{ConnectionType(status)})
 }
 
 func (this *Tox) CallbackSelfConnectionStatus(cbfn cb_self_connection_status_ftype, userData interface{}) {
 	this.CallbackSelfConnectionStatusAdd(cbfn, userData)
 }
-func (this *Tox) CallbackSelfConnectionStatusAdd(cbfn cb_self_connection_status_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_self_connection_statuss[cbfnp]; ok {
-		return
-	}
-	this.cb_self_connection_statuss[cbfnp] = userData
-
-	C.tox_callback_self_connection_status(this.toxcore, (*C.tox_self_connection_status_cb)(C.callbackSelfConnectionStatusWrapperForC))
+func (this *Tox) CallbackSelfConnectionStatusAdd(cbfn cb_self_connection_status_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_self_connection_statuss, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_self_connection_status(this.toxcore, (*C.tox_self_connection_status_cb)(C.callbackSelfConnectionStatusWrapperForC))
+		} else {
+			C.tox_callback_self_connection_status(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFileRecvControlWrapperForC
 func callbackFileRecvControlWrapperForC(m *C.Tox, friendNumber C.uint32_t, fileNumber C.uint32_t,
 	control C.Tox_File_Control, userData unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_file_recv_controls {
-		cbfn := *(*cb_file_recv_control_ftype)(cbfni)
-		this.putcbevts(func() { cbfn(this, uint32(friendNumber), uint32(fileNumber), int(control), ud) })
+	for _, cbe := range this.cb_file_recv_controls {
+		cbfn, ud := *(*cb_file_recv_control_ftype)(cbe.fn), cbe.ud
+		this.putcbevts(func() { cbfn(this, uint32(friendNumber), uint32(fileNumber), FileControlType(control), ud) })
 	}
+	this.putevt(FileRecvControlEvent{uint32(friendNumber), uint32(fileNumber), FileControlType(control)})
 }
 
 func (this *Tox) CallbackFileRecvControl(cbfn cb_file_recv_control_ftype, userData interface{}) {
 	this.CallbackFileRecvControlAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFileRecvControlAdd(cbfn cb_file_recv_control_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_file_recv_controls[cbfnp]; ok {
-		return
-	}
-	this.cb_file_recv_controls[cbfnp] = userData
-
-	C.tox_callback_file_recv_control(this.toxcore, (*C.tox_file_recv_control_cb)(C.callbackFileRecvControlWrapperForC))
+func (this *Tox) CallbackFileRecvControlAdd(cbfn cb_file_recv_control_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_file_recv_controls, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_file_recv_control(this.toxcore, (*C.tox_file_recv_control_cb)(C.callbackFileRecvControlWrapperForC))
+		} else {
+			C.tox_callback_file_recv_control(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFileRecvWrapperForC
 func callbackFileRecvWrapperForC(m *C.Tox, friendNumber C.uint32_t, fileNumber C.uint32_t, kind C.uint32_t,
 	fileSize C.uint64_t, fileName *C.cuint8_t, fileNameLength C.size_t, userData unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_file_recvs {
-		cbfn := *(*cb_file_recv_ftype)(cbfni)
-		fileName_ := C.GoStringN((*C.char)(unsafe.Pointer(fileName)), C.int(fileNameLength))
+	fileName_ := C.GoStringN((*C.char)(unsafe.Pointer(fileName)), C.int(fileNameLength))
+	for _, cbe := range this.cb_file_recvs {
+		cbfn, ud := *(*cb_file_recv_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() {
-			cbfn(this, uint32(friendNumber), uint32(fileNumber), uint32(kind),
+			cbfn(this, uint32(friendNumber), uint32(fileNumber), FileKind(kind),
 				uint64(fileSize), fileName_, ud)
 		})
 	}
+	this.putevt(FileRecvEvent{uint32(friendNumber), uint32(fileNumber), FileKind(kind), uint64(fileSize), fileName_})
 }
 
 func (this *Tox) CallbackFileRecv(cbfn cb_file_recv_ftype, userData interface{}) {
 	this.CallbackFileRecvAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFileRecvAdd(cbfn cb_file_recv_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_file_recvs[cbfnp]; ok {
-		return
-	}
-	this.cb_file_recvs[cbfnp] = userData
-
-	C.tox_callback_file_recv(this.toxcore, (*C.tox_file_recv_cb)(C.callbackFileRecvWrapperForC))
+func (this *Tox) CallbackFileRecvAdd(cbfn cb_file_recv_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_file_recvs, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_file_recv(this.toxcore, (*C.tox_file_recv_cb)(C.callbackFileRecvWrapperForC))
+		} else {
+			C.tox_callback_file_recv(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFileRecvChunkWrapperForC
 func callbackFileRecvChunkWrapperForC(m *C.Tox, friendNumber C.uint32_t, fileNumber C.uint32_t,
 	position C.uint64_t, data *C.cuint8_t, length C.size_t, userData unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_file_recv_chunks {
-		cbfn := *(*cb_file_recv_chunk_ftype)(cbfni)
+	for _, cbe := range this.cb_file_recv_chunks {
+		cbfn, ud := *(*cb_file_recv_chunk_ftype)(cbe.fn), cbe.ud
 		data_ := C.GoBytes((unsafe.Pointer)(data), C.int(length))
 		this.putcbevts(func() { cbfn(this, uint32(friendNumber), uint32(fileNumber), uint64(position), data_, ud) })
 	}
+	if this.evstream != nil {
+		data_ := C.GoBytes((unsafe.Pointer)(data), C.int(length))
+		this.putevt(FileRecvChunkEvent{uint32(friendNumber), uint32(fileNumber), uint64(position), data_})
+	}
 }
 
 func (this *Tox) CallbackFileRecvChunk(cbfn cb_file_recv_chunk_ftype, userData interface{}) {
 	this.CallbackFileRecvChunkAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFileRecvChunkAdd(cbfn cb_file_recv_chunk_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_file_recv_chunks[cbfnp]; ok {
-		return
-	}
-	this.cb_file_recv_chunks[cbfnp] = userData
-
-	C.tox_callback_file_recv_chunk(this.toxcore, (*C.tox_file_recv_chunk_cb)(C.callbackFileRecvChunkWrapperForC))
+func (this *Tox) CallbackFileRecvChunkAdd(cbfn cb_file_recv_chunk_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_file_recv_chunks, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_file_recv_chunk(this.toxcore, (*C.tox_file_recv_chunk_cb)(C.callbackFileRecvChunkWrapperForC))
+		} else {
+			C.tox_callback_file_recv_chunk(this.toxcore, nil)
+		}
+	})
 }
 
 //export callbackFileChunkRequestWrapperForC
 func callbackFileChunkRequestWrapperForC(m *C.Tox, friendNumber C.uint32_t, fileNumber C.uint32_t,
 	position C.uint64_t, length C.size_t, userData unsafe.Pointer) {
 	var this = cbUserDatas.get(m)
-	for cbfni, ud := range this.cb_file_chunk_requests {
-		cbfn := *(*cb_file_chunk_request_ftype)(cbfni)
+	for _, cbe := range this.cb_file_chunk_requests {
+		cbfn, ud := *(*cb_file_chunk_request_ftype)(cbe.fn), cbe.ud
 		this.putcbevts(func() { cbfn(this, uint32(friendNumber), uint32(fileNumber), uint64(position), int(length), ud) })
 	}
+	this.putevt(FileChunkRequestEvent{uint32(friendNumber), uint32(fileNumber), uint64(position), int(length)})
 }
 
 func (this *Tox) CallbackFileChunkRequest(cbfn cb_file_chunk_request_ftype, userData interface{}) {
 	this.CallbackFileChunkRequestAdd(cbfn, userData)
 }
-func (this *Tox) CallbackFileChunkRequestAdd(cbfn cb_file_chunk_request_ftype, userData interface{}) {
-	cbfnp := (unsafe.Pointer)(&cbfn)
-	if _, ok := this.cb_file_chunk_requests[cbfnp]; ok {
-		return
+func (this *Tox) CallbackFileChunkRequestAdd(cbfn cb_file_chunk_request_ftype, userData interface{}) CallbackHandle {
+	return this.addCallback(this.cb_file_chunk_requests, unsafe.Pointer(&cbfn), userData, func(on bool) {
+		if on {
+			C.tox_callback_file_chunk_request(this.toxcore, (*C.tox_file_chunk_request_cb)(C.callbackFileChunkRequestWrapperForC))
+		} else {
+			C.tox_callback_file_chunk_request(this.toxcore, nil)
+		}
+	})
+}
+
+// NewTox logs the error and returns nil on failure. Unlike NewToxWithError
+// it doesn't Validate, options are only rejected by tox_new.
+func NewTox(opt *ToxOptions) *Tox {
+	if opt == nil {
+		opt = NewToxOptions()
+	}
+	tox, err := newToxFallback(opt)
+	if err != nil {
+		log.Println(err)
+		return nil
 	}
-	this.cb_file_chunk_requests[cbfnp] = userData
+	return tox
+}
 
-	C.tox_callback_file_chunk_request(this.toxcore, (*C.tox_file_chunk_request_cb)(C.callbackFileChunkRequestWrapperForC))
+// NewToxWithError returns the Validate error, or a *ToxNewError if tox_new
+// fails, e.g. ErrNewPortAlloc once opt.PortFallback, if any, ran out of tries.
+// After a retry the instance holds a copy of opt with the ports that worked.
+func NewToxWithError(opt *ToxOptions) (*Tox, error) {
+	if opt == nil {
+		opt = NewToxOptions()
+	}
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+	return newToxFallback(opt)
 }
 
-func NewTox(opt *ToxOptions) *Tox {
-	var tox = new(Tox)
-	if opt != nil {
-		tox.opts = opt
-	} else {
-		tox.opts = NewToxOptions()
+func newToxFallback(opt *ToxOptions) (*Tox, error) {
+	tox, err := newTox(opt)
+	if fb := opt.PortFallback; fb != nil {
+		for try := 1; try <= fb.Tries && errors.Is(err, ErrNewPortAlloc); try++ {
+			tox, err = newTox(fb.next(opt, try))
+		}
 	}
-	toxopts := tox.opts.toCToxOptions()
-	defer C.tox_options_free(toxopts)
+	return tox, err
+}
+
+func newTox(opt *ToxOptions) (*Tox, error) {
+	var tox = new(Tox)
+	tox.opts = opt
+	toxopts, free := tox.opts.toCToxOptions()
+	defer free()
 
 	var cerr C.Tox_Err_New
 	var toxcore = C.tox_new(toxopts, &cerr)
 	tox.toxcore = toxcore
 	if toxcore == nil {
-		log.Println(toxerr(cerr))
-		return nil
+		return nil, &ToxNewError{int(cerr)}
 	}
 	cbUserDatas.set(toxcore, tox)
 
 	//
-	tox.cb_friend_requests = make(map[unsafe.Pointer]interface{})
-	tox.cb_friend_messages = make(map[unsafe.Pointer]interface{})
-	tox.cb_friend_names = make(map[unsafe.Pointer]interface{})
-	tox.cb_friend_status_messages = make(map[unsafe.Pointer]interface{})
-	tox.cb_friend_statuss = make(map[unsafe.Pointer]interface{})
-	tox.cb_friend_connection_statuss = make(map[unsafe.Pointer]interface{})
-	tox.cb_friend_typings = make(map[unsafe.Pointer]interface{})
-	tox.cb_friend_read_receipts = make(map[unsafe.Pointer]interface{})
-	tox.cb_friend_lossy_packets = make(map[unsafe.Pointer]interface{})
-	tox.cb_friend_lossless_packets = make(map[unsafe.Pointer]interface{})
-	tox.cb_self_connection_statuss = make(map[unsafe.Pointer]interface{})
-
-	tox.cb_conference_invites = make(map[unsafe.Pointer]interface{})
-	tox.cb_conference_messages = make(map[unsafe.Pointer]interface{})
-	tox.cb_conference_actions = make(map[unsafe.Pointer]interface{})
-	tox.cb_conference_titles = make(map[unsafe.Pointer]interface{})
-	tox.cb_conference_peer_names = make(map[unsafe.Pointer]interface{})
-	tox.cb_conference_peer_list_changeds = make(map[unsafe.Pointer]interface{})
-
-	tox.cb_file_recv_controls = make(map[unsafe.Pointer]interface{})
-	tox.cb_file_recvs = make(map[unsafe.Pointer]interface{})
-	tox.cb_file_recv_chunks = make(map[unsafe.Pointer]interface{})
-	tox.cb_file_chunk_requests = make(map[unsafe.Pointer]interface{})
+	tox.cb_friend_requests = make(map[CallbackHandle]callbackEntry)
+	tox.cb_friend_messages = make(map[CallbackHandle]callbackEntry)
+	tox.cb_friend_names = make(map[CallbackHandle]callbackEntry)
+	tox.cb_friend_status_messages = make(map[CallbackHandle]callbackEntry)
+	tox.cb_friend_statuss = make(map[CallbackHandle]callbackEntry)
+	tox.cb_friend_connection_statuss = make(map[CallbackHandle]callbackEntry)
+	tox.cb_friend_typings = make(map[CallbackHandle]callbackEntry)
+	tox.cb_friend_read_receipts = make(map[CallbackHandle]callbackEntry)
+	tox.cb_friend_lossy_packets = make(map[CallbackHandle]callbackEntry)
+	tox.cb_friend_lossless_packets = make(map[CallbackHandle]callbackEntry)
+	tox.cb_self_connection_statuss = make(map[CallbackHandle]callbackEntry)
+
+	tox.cb_conference_invites = make(map[CallbackHandle]callbackEntry)
+	tox.cb_conference_messages = make(map[CallbackHandle]callbackEntry)
+	tox.cb_conference_actions = make(map[CallbackHandle]callbackEntry)
+	tox.cb_conference_titles = make(map[CallbackHandle]callbackEntry)
+	tox.cb_conference_peer_names = make(map[CallbackHandle]callbackEntry)
+	tox.cb_conference_peer_list_changeds = make(map[CallbackHandle]callbackEntry)
+
+	tox.cb_file_recv_controls = make(map[CallbackHandle]callbackEntry)
+	tox.cb_file_recvs = make(map[CallbackHandle]callbackEntry)
+	tox.cb_file_recv_chunks = make(map[CallbackHandle]callbackEntry)
+	tox.cb_file_chunk_requests = make(map[CallbackHandle]callbackEntry)
 
 	tox.cb_audios = make(map[uint32]interface{})
+	tox.cb_removers = make(map[CallbackHandle]func())
 
-	return tox
+	return tox, nil
 }
 
 func (this *Tox) Kill() {
@@ -522,48 +613,65 @@ func (this *Tox) Kill() {
 		return
 	}
 
+	// before the instance lock, AV callbacks may take that one
+	this.avmu.Lock()
 	this.lock()
-	defer this.unlock()
+	if this.toxcore == nil {
+		this.unlock()
+		this.avmu.Unlock()
+		return
+	}
 
+	save := this.autosaveKill()
+	if this.evstream != nil {
+		this.evstream.close()
+	}
 	cbUserDatas.del(this.toxcore)
 	C.tox_kill(this.toxcore)
 	this.toxcore = nil
 	this.Killed = true
+	this.unlock()
+	this.avmu.Unlock()
+
+	save()
 }
 
 // uint32_t tox_iteration_interval(Tox *tox);
+//
+// A killed instance returns KILLED_ITERATION_INTERVAL.
 func (this *Tox) IterationInterval() int {
 	this.lock()
 	defer this.unlock()
 
+	if this.toxcore == nil {
+		return KILLED_ITERATION_INTERVAL
+	}
 	r := C.tox_iteration_interval(this.toxcore)
 	return int(r)
 }
 
 /* The main loop that needs to be run in intervals of tox_iteration_interval() ms. */
 // void tox_iterate(Tox *tox);
-// compatable with legacy version
+// compatable with legacy version, a killed instance is logged and skipped,
+// see Run for the error
 func (this *Tox) Iterate() {
-	this.lock()
-	if this.Killed {
-		log.Panic("Tox was already killed")
+	if err := this.iterate(nil); err != nil {
+		log.Println(err)
 	}
-	if this.toxcore == nil {
-		log.Panic("toxcore became nil")
-	}
-	C.tox_iterate(this.toxcore, nil)
-	cbevts := this.cbevts
-	this.cbevts = nil
-	this.unlock()
-
-	this.invokeCallbackEvents(cbevts)
 }
 
 // for toktok new method
 func (this *Tox) Iterate2(userData interface{}) {
+	if err := this.iterate(userData); err != nil {
+		log.Println(err)
+	}
+}
+
+func (this *Tox) iterate(userData interface{}) error {
 	this.lock()
-	if this.toxcore == nil {
-		log.Panic("toxcore became nil")
+	if this.Killed || this.toxcore == nil {
+		this.unlock()
+		return ErrKilled
 	}
 	this.cb_iterate_data = userData
 	C.tox_iterate(this.toxcore, nil)
@@ -573,6 +681,49 @@ func (this *Tox) Iterate2(userData interface{}) {
 	this.unlock()
 
 	this.invokeCallbackEvents(cbevts)
+	this.autosaveIterate()
+	this.bootstrapIterate()
+	this.watchdogIterate()
+	return nil
+}
+
+// Run calls Iterate every IterationInterval until ctx is done or the
+// instance is killed. It returns ctx.Err() on cancellation and ErrKilled
+// once Kill was called, so it replaces the hand written
+// `for { t.Iterate(); time.Sleep(...) }` loop.
+func (this *Tox) Run(ctx context.Context) error {
+	return this.run(ctx, nil)
+}
+
+// Run2 is like Run, but drives Iterate2 with the given userData.
+func (this *Tox) Run2(ctx context.Context, userData interface{}) error {
+	return this.run(ctx, userData)
+}
+
+func (this *Tox) run(ctx context.Context, userData interface{}) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if err := this.iterate(userData); err != nil {
+			return err
+		}
+
+		this.lock()
+		if this.Killed || this.toxcore == nil {
+			this.unlock()
+			return ErrKilled
+		}
+		interval := time.Duration(C.tox_iteration_interval(this.toxcore)) * time.Millisecond
+		this.unlock()
+		timer.Reset(interval)
+	}
 }
 
 func (this *Tox) invokeCallbackEvents(cbevts []func()) {
@@ -609,107 +760,144 @@ func (this *Tox) GetSavedata() []byte {
  * @param pubkey hex 64B length
  */
 func (this *Tox) Bootstrap(addr string, port uint16, pubkey string) (bool, error) {
-	this.lock()
-	defer this.unlock()
-
-	b_pubkey, err := hex.DecodeString(pubkey)
+	pk, err := ParsePublicKey(pubkey)
 	if err != nil {
-		return false, toxerr("Invalid pubkey")
+		return false, err
 	}
+	return this.BootstrapKey(addr, port, pk)
+}
+
+// BootstrapKey fails with ErrStrictProxy or ErrLanOnly if the options
+// forbid the node.
+func (this *Tox) BootstrapKey(addr string, port uint16, pubkey PublicKey) (bool, error) {
+	if err := this.checkPeerAddr(addr, true); err != nil {
+		return false, err
+	}
+	this.lock()
+	defer this.unlock()
 
 	var _addr = C.CString(addr)
 	defer C.free(unsafe.Pointer(_addr))
 	var _port = C.uint16_t(port)
-	var _cpubkey = (*C.uint8_t)(&b_pubkey[0])
+	var _cpubkey = (*C.uint8_t)(&pubkey[0])
 
 	var cerr C.Tox_Err_Bootstrap
 	r := C.tox_bootstrap(this.toxcore, _addr, _port, _cpubkey, &cerr)
 	if cerr > 0 {
-		return false, toxerr(cerr)
+		return false, &BootstrapError{int(cerr)}
 	}
 	return bool(r), nil
 }
 
 func (this *Tox) SelfGetAddress() string {
-	var addr [ADDRESS_SIZE]byte
+	return this.SelfAddress().String()
+}
+
+func (this *Tox) SelfAddress() Address {
+	var addr Address
 	var caddr = (*C.uint8_t)(unsafe.Pointer(&addr[0]))
 	C.tox_self_get_address(this.toxcore, caddr)
-
-	return strings.ToUpper(hex.EncodeToString(addr[:]))
+	return addr
 }
 
-func (this *Tox) SelfGetConnectionStatus() int {
+func (this *Tox) SelfGetConnectionStatus() ConnectionType {
 	r := C.tox_self_get_connection_status(this.toxcore)
-	return int(r)
+	return ConnectionType(r)
 }
 
+// the checksum is left to toxcore, which fails with ErrFriendAddBadChecksum
 func (this *Tox) FriendAdd(friendId string, message string) (uint32, error) {
+	var addr Address
+	if err := decodeHex(addr[:], friendId, "address"); err != nil {
+		return 0, err
+	}
+	return this.FriendAddAddress(addr, message)
+}
+
+func (this *Tox) FriendAddAddress(addr Address, message string) (uint32, error) {
 	this.lock()
 	defer this.unlock()
 
-	friendId_b, err := hex.DecodeString(friendId)
-	friendId_p := (*C.uint8_t)(&friendId_b[0])
-	if err != nil {
-		log.Panic(err)
-	}
+	friendId_p := (*C.uint8_t)(&addr[0])
 
 	cmessage := []byte(message)
+	var cmessage_p *C.uint8_t
+	if len(cmessage) > 0 {
+		cmessage_p = (*C.uint8_t)(&cmessage[0])
+	}
 
 	var cerr C.Tox_Err_Friend_Add
 	r := C.tox_friend_add(this.toxcore, friendId_p,
-		(*C.uint8_t)(&cmessage[0]), C.size_t(len(message)), &cerr)
+		cmessage_p, C.size_t(len(message)), &cerr)
 	if cerr > 0 {
-		return uint32(r), toxerr(cerr)
+		return uint32(r), &FriendAddError{int(cerr)}
 	}
+	this.markDirty()
 	return uint32(r), nil
 }
 
+// friendId may be a public key or a whole address
 func (this *Tox) FriendAddNorequest(friendId string) (uint32, error) {
-	this.lock()
-	defer this.unlock()
-
-	friendId_b, err := hex.DecodeString(friendId)
+	pubkey, err := parseKeyOrAddress(friendId)
 	if err != nil {
 		return 0, err
 	}
-	friendId_p := (*C.uint8_t)(&friendId_b[0])
+	return this.FriendAddNorequestKey(pubkey)
+}
+
+func (this *Tox) FriendAddNorequestKey(pubkey PublicKey) (uint32, error) {
+	this.lock()
+	defer this.unlock()
+
+	friendId_p := (*C.uint8_t)(&pubkey[0])
 
 	var cerr C.Tox_Err_Friend_Add
 	r := C.tox_friend_add_norequest(this.toxcore, friendId_p, &cerr)
 	if cerr > 0 {
-		return uint32(r), toxerr(cerr)
+		return uint32(r), &FriendAddError{int(cerr)}
 	}
+	this.markDirty()
 	return uint32(r), nil
 }
 
 func (this *Tox) FriendByPublicKey(pubkey string) (uint32, error) {
-	pubkey_b, err := hex.DecodeString(pubkey)
+	pk, err := parseKeyOrAddress(pubkey)
 	if err != nil {
 		return 0, err
 	}
-	var pubkey_p = (*C.uint8_t)(&pubkey_b[0])
+	return this.FriendByKey(pk)
+}
+
+func (this *Tox) FriendByKey(pubkey PublicKey) (uint32, error) {
+	var pubkey_p = (*C.uint8_t)(&pubkey[0])
 
 	var cerr C.Tox_Err_Friend_By_Public_Key
 	r := C.tox_friend_by_public_key(this.toxcore, pubkey_p, &cerr)
 	if cerr != C.TOX_ERR_FRIEND_BY_PUBLIC_KEY_OK {
-		return uint32(r), toxerr(cerr)
+		return uint32(r), &FriendByPublicKeyError{int(cerr)}
 	}
 	return uint32(r), nil
 }
 
 func (this *Tox) FriendGetPublicKey(friendNumber uint32) (string, error) {
+	pubkey, err := this.FriendPublicKey(friendNumber)
+	if err != nil {
+		return "", err
+	}
+	return pubkey.String(), nil
+}
+
+func (this *Tox) FriendPublicKey(friendNumber uint32) (PublicKey, error) {
 	var _fn = C.uint32_t(friendNumber)
-	var pubkey_b = make([]byte, PUBLIC_KEY_SIZE)
-	var pubkey_p = (*C.uint8_t)(&pubkey_b[0])
+	var pubkey PublicKey
+	var pubkey_p = (*C.uint8_t)(&pubkey[0])
 
 	var cerr C.Tox_Err_Friend_Get_Public_Key
 	r := C.tox_friend_get_public_key(this.toxcore, _fn, pubkey_p, &cerr)
 	if cerr > 0 || bool(r) == false {
-		return "", toxerr(cerr)
+		return pubkey, &FriendGetPublicKeyError{int(cerr)}
 	}
-	pubkey_h := hex.EncodeToString(pubkey_b)
-	pubkey_h = strings.ToUpper(pubkey_h)
-	return pubkey_h, nil
+	return pubkey, nil
 }
 
 func (this *Tox) FriendDelete(friendNumber uint32) (bool, error) {
@@ -721,20 +909,22 @@ func (this *Tox) FriendDelete(friendNumber uint32) (bool, error) {
 	var cerr C.Tox_Err_Friend_Delete
 	r := C.tox_friend_delete(this.toxcore, _fn, &cerr)
 	if cerr > 0 {
-		return bool(r), toxerr(cerr)
+		return bool(r), &FriendDeleteError{int(cerr)}
 	}
+	this.conntimes.forget(friendNumber)
+	this.markDirty()
 	return bool(r), nil
 }
 
-func (this *Tox) FriendGetConnectionStatus(friendNumber uint32) (int, error) {
+func (this *Tox) FriendGetConnectionStatus(friendNumber uint32) (ConnectionType, error) {
 	var _fn = C.uint32_t(friendNumber)
 
 	var cerr C.Tox_Err_Friend_Query
 	r := C.tox_friend_get_connection_status(this.toxcore, _fn, &cerr)
 	if cerr > 0 {
-		return int(r), toxerr(cerr)
+		return ConnectionType(r), &FriendQueryError{int(cerr)}
 	}
-	return int(r), nil
+	return ConnectionType(r), nil
 }
 
 func (this *Tox) FriendExists(friendNumber uint32) bool {
@@ -759,7 +949,7 @@ func (this *Tox) FriendSendMessage(friendNumber uint32, message string) (uint32,
 	var cerr C.Tox_Err_Friend_Send_Message
 	r := C.tox_friend_send_message(this.toxcore, _fn, mtype, (*C.uint8_t)(&_message[0]), _length, &cerr)
 	if cerr != C.TOX_ERR_FRIEND_SEND_MESSAGE_OK {
-		return uint32(r), toxerr(cerr)
+		return uint32(r), &FriendSendMessageError{int(cerr)}
 	}
 	return uint32(r), nil
 }
@@ -776,7 +966,7 @@ func (this *Tox) FriendSendAction(friendNumber uint32, action string) (uint32, e
 	var cerr C.Tox_Err_Friend_Send_Message
 	r := C.tox_friend_send_message(this.toxcore, _fn, mtype, (*C.uint8_t)(&_action[0]), _length, &cerr)
 	if cerr > 0 {
-		return uint32(r), toxerr(cerr)
+		return uint32(r), &FriendSendMessageError{int(cerr)}
 	}
 	return uint32(r), nil
 }
@@ -791,8 +981,9 @@ func (this *Tox) SelfSetName(name string) error {
 	var cerr C.Tox_Err_Set_Info
 	C.tox_self_set_name(this.toxcore, (*C.uint8_t)(&_name[0]), _length, &cerr)
 	if cerr > 0 {
-		return toxerr(cerr)
+		return &SetInfoError{int(cerr)}
 	}
+	this.markDirty()
 	return nil
 }
 
@@ -813,7 +1004,7 @@ func (this *Tox) FriendGetName(friendNumber uint32) (string, error) {
 
 	r := C.tox_friend_get_name(this.toxcore, _fn, (*C.uint8_t)(safeptr(_name)), &cerr)
 	if !bool(r) {
-		return "", toxerr(cerr)
+		return "", &FriendQueryError{int(cerr)}
 	}
 	return string(_name), nil
 }
@@ -824,7 +1015,7 @@ func (this *Tox) FriendGetNameSize(friendNumber uint32) (int, error) {
 	var cerr C.Tox_Err_Friend_Query
 	r := C.tox_friend_get_name_size(this.toxcore, _fn, &cerr)
 	if cerr > 0 {
-		return int(r), toxerr(cerr)
+		return int(r), &FriendQueryError{int(cerr)}
 	}
 	return int(r), nil
 }
@@ -844,14 +1035,16 @@ func (this *Tox) SelfSetStatusMessage(status string) (bool, error) {
 	var cerr C.Tox_Err_Set_Info
 	r := C.tox_self_set_status_message(this.toxcore, (*C.uint8_t)(&_status[0]), _length, &cerr)
 	if cerr > 0 {
-		return false, toxerr(cerr)
+		return false, &SetInfoError{int(cerr)}
 	}
+	this.markDirty()
 	return bool(r), nil
 }
 
-func (this *Tox) SelfSetStatus(status uint8) {
+func (this *Tox) SelfSetStatus(status UserStatus) {
 	var _status = C.Tox_User_Status(status)
 	C.tox_self_set_status(this.toxcore, _status)
+	this.markDirty()
 }
 
 func (this *Tox) FriendGetStatusMessageSize(friendNumber uint32) (int, error) {
@@ -860,7 +1053,7 @@ func (this *Tox) FriendGetStatusMessageSize(friendNumber uint32) (int, error) {
 	var cerr C.Tox_Err_Friend_Query
 	r := C.tox_friend_get_status_message_size(this.toxcore, _fn, &cerr)
 	if cerr > 0 {
-		return int(r), toxerr(cerr)
+		return int(r), &FriendQueryError{int(cerr)}
 	}
 	return int(r), nil
 }
@@ -875,7 +1068,7 @@ func (this *Tox) FriendGetStatusMessage(friendNumber uint32) (string, error) {
 	var cerr C.Tox_Err_Friend_Query
 	len := C.tox_friend_get_status_message_size(this.toxcore, _fn, &cerr)
 	if cerr > 0 {
-		return "", toxerr(cerr)
+		return "", &FriendQueryError{int(cerr)}
 	}
 
 	_buf := make([]byte, len)
@@ -883,7 +1076,7 @@ func (this *Tox) FriendGetStatusMessage(friendNumber uint32) (string, error) {
 	cerr = 0
 	r := C.tox_friend_get_status_message(this.toxcore, _fn, (*C.uint8_t)(safeptr(_buf)), &cerr)
 	if !bool(r) || cerr > 0 {
-		return "", toxerr(cerr)
+		return "", &FriendQueryError{int(cerr)}
 	}
 	return string(_buf[:]), nil
 }
@@ -896,20 +1089,20 @@ func (this *Tox) SelfGetStatusMessage() (string, error) {
 	return string(_buf[:]), nil
 }
 
-func (this *Tox) FriendGetStatus(friendNumber uint32) (int, error) {
+func (this *Tox) FriendGetStatus(friendNumber uint32) (UserStatus, error) {
 	var _fn = C.uint32_t(friendNumber)
 
 	var cerr C.Tox_Err_Friend_Query
 	r := C.tox_friend_get_status(this.toxcore, _fn, &cerr)
 	if cerr > 0 {
-		return int(r), toxerr(cerr)
+		return UserStatus(r), &FriendQueryError{int(cerr)}
 	}
-	return int(r), nil
+	return UserStatus(r), nil
 }
 
-func (this *Tox) SelfGetStatus() int {
+func (this *Tox) SelfGetStatus() UserStatus {
 	r := C.tox_self_get_status(this.toxcore)
-	return int(r)
+	return UserStatus(r)
 }
 
 func (this *Tox) FriendGetLastOnline(friendNumber uint32) (uint64, error) {
@@ -918,7 +1111,7 @@ func (this *Tox) FriendGetLastOnline(friendNumber uint32) (uint64, error) {
 	var cerr C.Tox_Err_Friend_Get_Last_Online
 	r := C.tox_friend_get_last_online(this.toxcore, _fn, &cerr)
 	if cerr > 0 {
-		return uint64(r), toxerr(cerr)
+		return uint64(r), &FriendGetLastOnlineError{int(cerr)}
 	}
 	return uint64(r), nil
 }
@@ -933,7 +1126,7 @@ func (this *Tox) SelfSetTyping(friendNumber uint32, typing bool) (bool, error) {
 	var cerr C.Tox_Err_Set_Typing
 	r := C.tox_self_set_typing(this.toxcore, _fn, _typing, &cerr)
 	if cerr > 0 {
-		return bool(r), toxerr(cerr)
+		return bool(r), &SetTypingError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -944,7 +1137,7 @@ func (this *Tox) FriendGetTyping(friendNumber uint32) (bool, error) {
 	var cerr C.Tox_Err_Friend_Query
 	r := C.tox_friend_get_typing(this.toxcore, _fn, &cerr)
 	if cerr > 0 {
-		return bool(r), toxerr(cerr)
+		return bool(r), &FriendQueryError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -979,7 +1172,18 @@ func (this *Tox) SelfGetUdpPort() (uint16, error) {
 	var cerr C.Tox_Err_Get_Port
 	r := C.tox_self_get_udp_port(this.toxcore, &cerr)
 	if cerr > 0 {
-		return 0, toxerr(cerr)
+		return 0, &GetPortError{int(cerr)}
+	}
+	return uint16(r), nil
+}
+
+// SelfGetTcpPort fails with ErrGetPortNotBound without a TCP relay server,
+// see ToxOptions.Tcp_port.
+func (this *Tox) SelfGetTcpPort() (uint16, error) {
+	var cerr C.Tox_Err_Get_Port
+	r := C.tox_self_get_tcp_port(this.toxcore, &cerr)
+	if cerr > 0 {
+		return 0, &GetPortError{int(cerr)}
 	}
 	return uint16(r), nil
 }
@@ -996,14 +1200,17 @@ func (this *Tox) SelfSetNospam(nospam uint32) {
 	var _nospam = C.uint32_t(nospam)
 
 	C.tox_self_set_nospam(this.toxcore, _nospam)
+	this.markDirty()
 }
 
 func (this *Tox) SelfGetPublicKey() string {
-	var _pubkey [PUBLIC_KEY_SIZE]byte
+	return this.SelfPublicKey().String()
+}
 
+func (this *Tox) SelfPublicKey() PublicKey {
+	var _pubkey PublicKey
 	C.tox_self_get_public_key(this.toxcore, (*C.uint8_t)(&_pubkey[0]))
-
-	return strings.ToUpper(hex.EncodeToString(_pubkey[:]))
+	return _pubkey
 }
 
 func (this *Tox) SelfGetSecretKey() string {
@@ -1017,33 +1224,51 @@ func (this *Tox) SelfGetSecretKey() string {
 // tox_lossy_***
 
 func (this *Tox) FriendSendLossyPacket(friendNumber uint32, data string) error {
+	return this.FriendSendLossyPacketBytes(friendNumber, []byte(data))
+}
+
+// FriendSendLossyPacketBytes sends a custom lossy packet, its first byte must be
+// in the PACKET_ID_LOSSY_MIN..PACKET_ID_LOSSY_MAX range.
+func (this *Tox) FriendSendLossyPacketBytes(friendNumber uint32, data []byte) error {
+	if err := checkLossyPacket(data); err != nil {
+		return err
+	}
+
 	this.lock()
 	defer this.unlock()
 
 	var _fn = C.uint32_t(friendNumber)
-	var _data = []byte(data)
 	var _length = C.size_t(len(data))
 
 	var cerr C.Tox_Err_Friend_Custom_Packet
-	r := C.tox_friend_send_lossy_packet(this.toxcore, _fn, (*C.uint8_t)(&_data[0]), _length, &cerr)
+	r := C.tox_friend_send_lossy_packet(this.toxcore, _fn, (*C.uint8_t)(&data[0]), _length, &cerr)
 	if !r || cerr != C.TOX_ERR_FRIEND_CUSTOM_PACKET_OK {
-		return toxerr(cerr)
+		return &FriendCustomPacketError{int(cerr)}
 	}
 	return nil
 }
 
 func (this *Tox) FriendSendLosslessPacket(friendNumber uint32, data string) error {
+	return this.FriendSendLosslessPacketBytes(friendNumber, []byte(data))
+}
+
+// FriendSendLosslessPacketBytes sends a custom lossless packet, its first byte must be
+// in the PACKET_ID_LOSSLESS_MIN..PACKET_ID_LOSSLESS_MAX range.
+func (this *Tox) FriendSendLosslessPacketBytes(friendNumber uint32, data []byte) error {
+	if err := checkLosslessPacket(data); err != nil {
+		return err
+	}
+
 	this.lock()
 	defer this.unlock()
 
 	var _fn = C.uint32_t(friendNumber)
-	var _data = []byte(data)
 	var _length = C.size_t(len(data))
 
 	var cerr C.Tox_Err_Friend_Custom_Packet
-	r := C.tox_friend_send_lossless_packet(this.toxcore, _fn, (*C.uint8_t)(&_data[0]), _length, &cerr)
+	r := C.tox_friend_send_lossless_packet(this.toxcore, _fn, (*C.uint8_t)(&data[0]), _length, &cerr)
 	if !r || cerr != C.TOX_ERR_FRIEND_CUSTOM_PACKET_OK {
-		return toxerr(cerr)
+		return &FriendCustomPacketError{int(cerr)}
 	}
 	return nil
 }
@@ -1060,17 +1285,17 @@ func (this *Tox) Hash(data string, datalen uint32) (string, bool, error) {
 }
 
 // tox_callback_file_***
-func (this *Tox) FileControl(friendNumber uint32, fileNumber uint32, control int) (bool, error) {
+func (this *Tox) FileControl(friendNumber uint32, fileNumber uint32, control FileControlType) (bool, error) {
 	var cerr C.Tox_Err_File_Control
 	r := C.tox_file_control(this.toxcore, C.uint32_t(friendNumber), C.uint32_t(fileNumber),
 		C.Tox_File_Control(control), &cerr)
 	if cerr > 0 {
-		return false, toxerr(cerr)
+		return false, &FileControlError{int(cerr)}
 	}
 	return bool(r), nil
 }
 
-func (this *Tox) FileSend(friendNumber uint32, kind uint32, fileSize uint64, fileId string, fileName string) (uint32, error) {
+func (this *Tox) FileSend(friendNumber uint32, kind FileKind, fileSize uint64, fileId string, fileName string) (uint32, error) {
 	this.lock()
 	defer this.unlock()
 
@@ -1083,7 +1308,7 @@ func (this *Tox) FileSend(friendNumber uint32, kind uint32, fileSize uint64, fil
 	r := C.tox_file_send(this.toxcore, C.uint32_t(friendNumber), C.uint32_t(kind), C.uint64_t(fileSize),
 		nil, (*C.uint8_t)(&_fileName[0]), C.size_t(len(fileName)), &cerr)
 	if cerr > 0 {
-		return uint32(r), toxerr(cerr)
+		return uint32(r), &FileSendError{int(cerr)}
 	}
 	return uint32(r), nil
 }
@@ -1093,13 +1318,13 @@ func (this *Tox) FileSendChunk(friendNumber uint32, fileNumber uint32, position
 	defer this.unlock()
 
 	if data == nil || len(data) == 0 {
-		return false, toxerr("empty data")
+		return false, &FileSendChunkError{ERR_FILE_SEND_CHUNK_INVALID_LENGTH}
 	}
 	var cerr C.Tox_Err_File_Send_Chunk
 	r := C.tox_file_send_chunk(this.toxcore, C.uint32_t(friendNumber), C.uint32_t(fileNumber),
 		C.uint64_t(position), (*C.uint8_t)(&data[0]), C.size_t(len(data)), &cerr)
 	if cerr > 0 {
-		return bool(r), toxerr(cerr)
+		return bool(r), &FileSendChunkError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -1112,7 +1337,7 @@ func (this *Tox) FileSeek(friendNumber uint32, fileNumber uint32, position uint6
 	r := C.tox_file_seek(this.toxcore, C.uint32_t(friendNumber), C.uint32_t(fileNumber),
 		C.uint64_t(position), &cerr)
 	if cerr > 0 {
-		return false, toxerr(cerr)
+		return false, &FileSeekError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -1124,7 +1349,7 @@ func (this *Tox) FileGetFileId(friendNumber uint32, fileNumber uint32) (string,
 	r := C.tox_file_get_file_id(this.toxcore, C.uint32_t(fileNumber), C.uint32_t(fileNumber),
 		(*C.uint8_t)(&fileId_b[0]), &cerr)
 	if cerr > 0 || bool(r) == false {
-		return "", toxerr(cerr)
+		return "", &FileGetError{int(cerr)}
 	}
 
 	var fileId_h = strings.ToUpper(hex.EncodeToString(fileId_b))
@@ -1133,36 +1358,127 @@ func (this *Tox) FileGetFileId(friendNumber uint32, fileNumber uint32) (string,
 
 // boostrap, see upper
 func (this *Tox) AddTcpRelay(addr string, port uint16, pubkey string) (bool, error) {
+	pk, err := ParsePublicKey(pubkey)
+	if err != nil {
+		return false, err
+	}
+	return this.AddTcpRelayKey(addr, port, pk)
+}
+
+func (this *Tox) AddTcpRelayKey(addr string, port uint16, pubkey PublicKey) (bool, error) {
+	if err := this.checkPeerAddr(addr, false); err != nil {
+		return false, err
+	}
 	this.lock()
 	defer this.unlock()
 
 	var _addr = C.CString(addr)
 	defer C.free(unsafe.Pointer(_addr))
 	var _port = C.uint16_t(port)
-	b_pubkey, err := hex.DecodeString(pubkey)
-	if err != nil {
-		log.Panic(err)
-	}
-	if strings.ToUpper(hex.EncodeToString(b_pubkey)) != pubkey {
-		log.Panic("wtf, hex enc/dec err")
-	}
-	var _pubkey = (*C.uint8_t)(&b_pubkey[0])
+	var _pubkey = (*C.uint8_t)(&pubkey[0])
 
 	var cerr C.Tox_Err_Bootstrap
 	r := C.tox_add_tcp_relay(this.toxcore, _addr, _port, _pubkey, &cerr)
 	if cerr > 0 {
-		return bool(r), toxerr(cerr)
+		return bool(r), &BootstrapError{int(cerr)}
 	}
 	return bool(r), nil
 }
 
-func (this *Tox) IsConnected() int {
+func (this *Tox) IsConnected() ConnectionType {
 	r := C.tox_self_get_connection_status(this.toxcore)
-	return int(r)
+	return ConnectionType(r)
 }
 
 func (this *Tox) putcbevts(f func()) { this.cbevts = append(this.cbevts, f) }
 
+// CallbackHandle identifies one listener registered by a Callback*Add method.
+type CallbackHandle uint64
+
+type callbackEntry struct {
+	fn unsafe.Pointer // *cb_*_ftype
+	ud interface{}
+}
+
+// addCallback stores the listener in cbs and turns on the C callback with setc
+// when it is the first one. The C callback is turned off again when the last
+// listener is removed, unless the Events stream or autosave still needs it.
+func (this *Tox) addCallback(cbs map[CallbackHandle]callbackEntry, fn unsafe.Pointer, ud interface{}, setc func(on bool)) CallbackHandle {
+	this.lock()
+	defer this.unlock()
+
+	this.cb_next_handle++
+	h := this.cb_next_handle
+	if len(cbs) == 0 {
+		setc(true)
+	}
+	cbs[h] = callbackEntry{fn, ud}
+	this.cb_removers[h] = func() {
+		delete(cbs, h)
+		if len(cbs) == 0 && !this.needsCallback(cbs) {
+			setc(false)
+		}
+	}
+	return h
+}
+
+// cEvent is the listeners of an event and the switch of its C callback.
+type cEvent struct {
+	cbs  map[CallbackHandle]callbackEntry
+	setc func(on bool)
+}
+
+func (this cEvent) in(events []cEvent) bool {
+	p := reflect.ValueOf(this.cbs).Pointer()
+	for _, ev := range events {
+		if reflect.ValueOf(ev.cbs).Pointer() == p {
+			return true
+		}
+	}
+	return false
+}
+
+// needsCallback is true if the instance keeps the C callback of the event of
+// cbs registered for itself, without listeners. Called with the instance
+// locked.
+func (this *Tox) needsCallback(cbs map[CallbackHandle]callbackEntry) bool {
+	if this.evstream != nil {
+		return true
+	}
+	ev := cEvent{cbs: cbs}
+	if this.conntimes.tracking() && ev.in(this.connEvents()) {
+		return true
+	}
+	return this.autosave != nil && ev.in(this.autosaveEvents())
+}
+
+// releaseCallbacks unregisters the C callbacks of the events that nothing
+// listens to anymore. Called with the instance locked.
+func (this *Tox) releaseCallbacks(events []cEvent) {
+	for _, ev := range events {
+		if len(ev.cbs) == 0 && !this.needsCallback(ev.cbs) {
+			ev.setc(false)
+		}
+	}
+}
+
+// CallbackRemove unregisters the listener identified by h.
+// Returns false if h is unknown or was already removed.
+func (this *Tox) CallbackRemove(h CallbackHandle) bool {
+	this.lock()
+	defer this.unlock()
+
+	remove, ok := this.cb_removers[h]
+	if !ok {
+		return false
+	}
+	delete(this.cb_removers, h)
+	if this.toxcore != nil {
+		remove()
+	}
+	return true
+}
+
 // ------------
 func KeepPkg() {
 }
@@ -1,17 +1,29 @@
 package tox
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/TokTok/go-toxcore-c/savedata"
 )
 
 // `go test -v -run Covers` will show untested functions
@@ -238,6 +250,50 @@ func TestBootstrap(t *testing.T) {
 	})
 }
 
+func TestRun(t *testing.T) {
+	t.Run("cancel", func(t *testing.T) {
+		_t := NewTox(nil)
+		defer _t.Kill()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if err := _t.Run(ctx); err != context.DeadlineExceeded {
+			t.Error("must deadline", err)
+		}
+	})
+	t.Run("killed", func(t *testing.T) {
+		_t := NewTox(nil)
+		_t.Kill()
+		if err := _t.Run(context.Background()); err != ErrKilled {
+			t.Error("must killed", err)
+		}
+		if _t.IterationInterval() != KILLED_ITERATION_INTERVAL {
+			t.Error("must fallback interval")
+		}
+	})
+	t.Run("av", func(t *testing.T) {
+		_t := NewTox(nil)
+		defer _t.Kill()
+		av, err := NewToxAV(_t)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if err := av.Run(ctx); err != context.DeadlineExceeded {
+			t.Error("must deadline", err)
+		}
+		av.Kill()
+		if err := av.Run(context.Background()); err != ErrKilled {
+			t.Error("must killed", err)
+		}
+		if av.IterationInterval() != KILLED_ITERATION_INTERVAL {
+			t.Error("must fallback interval")
+		}
+	})
+}
+
 type MiniTox struct {
 	t      *Tox
 	stopch chan struct{}
@@ -421,7 +477,7 @@ func TestCommunication(t *testing.T) {
 		}, nil)
 
 		// testing
-		t1.t.CallbackFriendConnectionStatus(func(_ *Tox, friendNumber uint32, status int,
+		t1.t.CallbackFriendConnectionStatus(func(_ *Tox, friendNumber uint32, status ConnectionType,
 			d interface{}) {
 		}, nil)
 		t1nameChanged := false
@@ -668,7 +724,7 @@ func TestCommunication(t *testing.T) {
 			if err != nil {
 				t.Error(err)
 			}
-			if uint8(gtype) != CONFERENCE_TYPE_TEXT {
+			if GroupchatType(gtype) != CONFERENCE_TYPE_TEXT {
 				t.Error(gtype, CONFERENCE_TYPE_TEXT)
 			}
 			if t1.t.GroupNumberPeers(gn) != 1 {
@@ -744,7 +800,7 @@ func TestCommunication(t *testing.T) {
 			t1.t.FriendAddNorequest(friendId)
 		}, nil)
 
-		t1.t.CallbackConferenceInvite(func(_ *Tox, friendNumber uint32, itype uint8, data string, ud interface{}) {
+		t1.t.CallbackConferenceInvite(func(_ *Tox, friendNumber uint32, itype GroupchatType, data string, ud interface{}) {
 			switch itype {
 			case CONFERENCE_TYPE_TEXT:
 				_, err := t1.t.JoinGroupChat(friendNumber, data)
@@ -835,7 +891,7 @@ func TestCommunication(t *testing.T) {
 			t1.t.FriendAddNorequest(friendId)
 		}, nil)
 
-		t1.t.CallbackConferenceInvite(func(_ *Tox, friendNumber uint32, itype uint8, data string, ud interface{}) {
+		t1.t.CallbackConferenceInvite(func(_ *Tox, friendNumber uint32, itype GroupchatType, data string, ud interface{}) {
 			switch itype {
 			case CONFERENCE_TYPE_TEXT:
 				t1.t.JoinGroupChat(friendNumber, data)
@@ -937,7 +993,7 @@ func TestCommunication(t *testing.T) {
 			}
 			defer _t2.Kill()
 			log.Println(_t2)
-			_t2.CallbackGroupInviteAdd(func(_ *Tox, friendNumber uint32, itype uint8, data string, userData interface{}) {
+			_t2.CallbackGroupInviteAdd(func(_ *Tox, friendNumber uint32, itype GroupchatType, data string, userData interface{}) {
 				log.Println(friendNumber, itype)
 			}, nil)
 			go func() {
@@ -978,7 +1034,7 @@ func TestCommunication(t *testing.T) {
 		}, nil)
 
 		t1.t.CallbackFileRecv(func(_ *Tox, friendNumber uint32, fileNumber uint32,
-			kind uint32, fileSize uint64, fileName string, d interface{}) {
+			kind FileKind, fileSize uint64, fileName string, d interface{}) {
 			log.Println(fileNumber, fileSize, fileName)
 			_, err := t1.t.FileSeek(friendNumber, fileNumber, 15)
 			if err != nil {
@@ -996,7 +1052,7 @@ func TestCommunication(t *testing.T) {
 			recvData += string(data)
 		}, nil)
 		t1.t.CallbackFileRecvControl(func(_ *Tox, friendNumber uint32, fileNumber uint32,
-			control int, ud interface{}) {
+			control FileControlType, ud interface{}) {
 			// log.Println(fileNumber, control)
 		}, nil)
 
@@ -1015,7 +1071,7 @@ func TestCommunication(t *testing.T) {
 		}, nil)
 		sendRecvDone := false
 		t2.t.CallbackFileRecvControl(func(_ *Tox, friendNumber uint32, fileNumber uint32,
-			control int, ud interface{}) {
+			control FileControlType, ud interface{}) {
 			// log.Println(fileNumber, control)
 			if control == FILE_CONTROL_CANCEL {
 				sendRecvDone = true
@@ -1063,6 +1119,1008 @@ func TestCommunication(t *testing.T) {
 	})
 }
 
+func TestEvents(t *testing.T) {
+	t.Run("drop oldest", func(t *testing.T) {
+		es := newEventStream(2, EVENT_OVERFLOW_DROP_OLDEST)
+		for i := 0; i < 5; i++ {
+			es.push(FriendReadReceiptEvent{0, uint32(i)})
+		}
+		if evt := <-es.ch; evt.(FriendReadReceiptEvent).Receipt != 3 {
+			t.Error("must 3", evt)
+		}
+		if evt := <-es.ch; evt.(FriendReadReceiptEvent).Receipt != 4 {
+			t.Error("must 4", evt)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		es := newEventStream(1, EVENT_OVERFLOW_ERROR)
+		es.push(SelfConnectionStatusEvent{CONNECTION_UDP})
+		es.push(SelfConnectionStatusEvent{CONNECTION_NONE})
+		if es.err != ErrEventOverflow {
+			t.Error("must overflow", es.err)
+		}
+		<-es.ch
+		if _, ok := <-es.ch; ok {
+			t.Error("must closed")
+		}
+	})
+	t.Run("killed", func(t *testing.T) {
+		killed := &Tox{opts: &ToxOptions{}, Killed: true}
+		if _, ok := <-killed.Events(1, EVENT_OVERFLOW_BLOCK); ok {
+			t.Error("must closed")
+		}
+		if err := killed.EventsErr(); err != ErrKilled {
+			t.Error("must killed", err)
+		}
+	})
+	t.Run("block", func(t *testing.T) {
+		es := newEventStream(0, EVENT_OVERFLOW_BLOCK)
+		go es.close()
+		es.push(SelfConnectionStatusEvent{CONNECTION_UDP})
+		if _, ok := <-es.ch; ok {
+			t.Error("must closed")
+		}
+	})
+	t.Run("friend request", func(t *testing.T) {
+		t1 := NewMiniTox()
+		t2 := NewMiniTox()
+		defer t1.t.Kill()
+		defer t2.t.Kill()
+
+		if err := link(t1, t2); err != nil {
+			t.Error("must ok", err)
+		}
+		evch := t1.t.Events(16, EVENT_OVERFLOW_DROP_OLDEST)
+
+		go t1.Iterate()
+		go t2.Iterate()
+		defer t1.stop()
+		defer t2.stop()
+
+		waitcond(func() bool {
+			return t1.t.SelfGetConnectionStatus() != CONNECTION_NONE && t2.t.SelfGetConnectionStatus() != CONNECTION_NONE
+		}, 100)
+		if _, err := t2.t.FriendAdd(t1.t.SelfGetAddress(), "hoho"); err != nil {
+			t.Error(err)
+		}
+
+		timeout := time.After(30 * time.Second)
+		for {
+			select {
+			case evt := <-evch:
+				if req, ok := evt.(FriendRequestEvent); ok {
+					if req.PublicKey != t2.t.SelfGetPublicKey() || req.Message != "hoho" {
+						t.Error("request not match", req)
+					}
+					return
+				}
+			case <-timeout:
+				t.Error("no friend request event")
+				return
+			}
+		}
+	})
+}
+
+func TestErrors(t *testing.T) {
+	var err error = &FriendAddError{ERR_FRIEND_ADD_OWN_KEY}
+	if !errors.Is(err, ErrFriendAddOwnKey) {
+		t.Error("must own key", err)
+	}
+	if errors.Is(err, ErrFriendAddAlreadySent) || errors.Is(err, &FriendQueryError{ERR_FRIEND_ADD_OWN_KEY}) {
+		t.Error("must not match", err)
+	}
+	if err.Error() != "friend add: The friend address belongs to the sending client." {
+		t.Error("bad message", err.Error())
+	}
+
+	var fserr *FileSendChunkError
+	if !errors.As(fmt.Errorf("wrapped: %w", ErrFileSendChunkSendq), &fserr) || fserr.Code != ERR_FILE_SEND_CHUNK_SENDQ {
+		t.Error("must sendq", fserr)
+	}
+	if s := (&GetPortError{99}).Error(); s != "get port: error 99" {
+		t.Error("bad unknown message", s)
+	}
+	if s := ErrAVCallFriendNotConnected.Error(); s != "toxav call: The friend was valid, but not currently connected." {
+		t.Error("bad av message", s)
+	}
+	if s := ErrDecryptionFailed.Error(); !strings.HasPrefix(s, "decryption: The encrypted byte array") {
+		t.Error("bad decryption message", s)
+	}
+
+	// checked before calling into toxcore
+	tx := &Tox{opts: &ToxOptions{}}
+	if _, err := NewToxAV(nil); !errors.Is(err, ErrAVNewNull) {
+		t.Error("must av null", err)
+	}
+	for _, cookie := range []string{"", strings.Repeat("zz", 20)} {
+		if _, err := tx.ConferenceJoin(0, cookie); !errors.Is(err, ErrConferenceJoinInvalidLength) {
+			t.Error("must invalid cookie", err)
+		}
+		if _, err := tx.JoinAVGroupChat(0, cookie, nil); !errors.Is(err, ErrConferenceJoinInvalidLength) {
+			t.Error("must invalid av cookie", err)
+		}
+	}
+	if _, err := tx.FileSendChunk(0, 0, 0, nil); !errors.Is(err, ErrFileSendChunkInvalidLength) {
+		t.Error("must invalid length", err)
+	}
+	if _, err := tx.ConferenceSendMessage(0, 42, "msg"); !errors.Is(err, ErrInvalidMessageType) {
+		t.Error("must invalid message type", err)
+	}
+}
+
+func TestEnums(t *testing.T) {
+	if s := CONNECTION_UDP.String(); s != "CONNECTION_UDP" {
+		t.Error("must CONNECTION_UDP", s)
+	}
+	if s := ConnectionType(42).String(); s != "ConnectionType(42)" {
+		t.Error("bad unknown", s)
+	}
+	if _, err := ConnectionType(42).MarshalText(); err == nil {
+		t.Error("must failed")
+	}
+	var us UserStatus
+	for _, text := range []string{"USER_STATUS_BUSY", "busy", " Busy "} {
+		if err := us.UnmarshalText([]byte(text)); err != nil || us != USER_STATUS_BUSY {
+			t.Error("must busy", text, us, err)
+		}
+	}
+	if err := us.UnmarshalText([]byte("sleeping")); err == nil || us != USER_STATUS_BUSY {
+		t.Error("must failed and unchanged", us, err)
+	}
+	text, err := FILE_CONTROL_PAUSE.MarshalText()
+	var fc FileControlType
+	if err != nil || fc.UnmarshalText(text) != nil || fc != FILE_CONTROL_PAUSE {
+		t.Error("must round trip", string(text), fc, err)
+	}
+
+	st := FRIEND_CALL_STATE_SENDING_A | FRIEND_CALL_STATE_ACCEPTING_V
+	if s := st.String(); s != "FRIEND_CALL_STATE_SENDING_A|FRIEND_CALL_STATE_ACCEPTING_V" {
+		t.Error("bad call state", s)
+	}
+	var st2 CallState
+	if err := st2.UnmarshalText([]byte("sending_a|accepting_v")); err != nil || st2 != st {
+		t.Error("must equal", st2, err)
+	}
+	if s := CallState(0).String(); s != "FRIEND_CALL_STATE_NONE" {
+		t.Error("must none", s)
+	}
+	if ConnStatusString(int(CONNECTION_TCP)) != "CONNECTION_TCP" || ConnStatusString(42) != "" {
+		t.Error("legacy string changed")
+	}
+}
+
+func TestAddress(t *testing.T) {
+	pk, err := ParsePublicKey(strings.ToLower(bsnodes[0].key))
+	if err != nil || pk.String() != bsnodes[0].key {
+		t.Fatal("must parse any case", pk, err)
+	}
+	addr := NewAddress(pk, 0x0A0B0C0D)
+	if addr.PublicKey() != pk || addr.Nospam() != 0x0A0B0C0D || !addr.Valid() {
+		t.Error("must round trip", addr)
+	}
+	if s := addr.String(); s[64:72] != "0A0B0C0D" {
+		t.Error("nospam must be big endian", s)
+	}
+
+	for _, s := range []string{addr.String(), strings.ToLower(addr.String()), addr.URI(),
+		"TOX://" + addr.String() + "?message=hi"} {
+		if addr2, err := ParseAddress(s); err != nil || addr2 != addr {
+			t.Error("must parse", s, err)
+		}
+	}
+	broken := addr
+	broken[PUBLIC_KEY_SIZE+4] ^= 0xff
+	if _, err := ParseAddress(broken.String()); err != ErrAddressChecksum {
+		t.Error("must checksum error", err)
+	}
+	if _, err := ParseAddress(addr.String()[2:]); err == nil {
+		t.Error("must length error")
+	}
+
+	data, err := json.Marshal(struct {
+		Addr   Address
+		Nospam Nospam
+	}{addr, addr.Nospam()})
+	if err != nil || string(data) != `{"Addr":"`+addr.String()+`","Nospam":"0A0B0C0D"}` {
+		t.Error("bad json", string(data), err)
+	}
+	var v struct{ Addr Address }
+	if err := json.Unmarshal(data, &v); err != nil || v.Addr != addr {
+		t.Error("must unmarshal", v, err)
+	}
+}
+
+func TestCallbackRemove(t *testing.T) {
+	t1 := NewMiniTox()
+	defer t1.t.Kill()
+
+	h1 := t1.t.CallbackFriendMessageAdd(func(_ *Tox, friendNumber uint32, msg string, ud interface{}) {}, nil)
+	h2 := t1.t.CallbackFriendMessageAdd(func(_ *Tox, friendNumber uint32, msg string, ud interface{}) {}, nil)
+	if h1 == h2 {
+		t.Error("must different", h1, h2)
+	}
+	if len(t1.t.cb_friend_messages) != 2 {
+		t.Error("must 2", len(t1.t.cb_friend_messages))
+	}
+	if !t1.t.CallbackRemove(h1) {
+		t.Error("must removed")
+	}
+	if t1.t.CallbackRemove(h1) {
+		t.Error("must already removed")
+	}
+	if !t1.t.CallbackRemove(h2) || len(t1.t.cb_friend_messages) != 0 {
+		t.Error("must empty", len(t1.t.cb_friend_messages))
+	}
+
+	hm := t1.t.CallbackConferenceMessageAdd(func(_ *Tox, groupNumber uint32, peerNumber uint32, msg string, ud interface{}) {}, nil)
+	ha := t1.t.CallbackGroupActionAdd(func(_ *Tox, groupNumber int, peerNumber int, msg string, ud interface{}) {}, nil)
+	t1.t.CallbackRemove(hm)
+	if !t1.t.cb_conference_message_setted {
+		t.Error("must still set for actions")
+	}
+	t1.t.CallbackRemove(ha)
+	if t1.t.cb_conference_message_setted {
+		t.Error("must unset")
+	}
+}
+
+func TestPacketRouter(t *testing.T) {
+	if err := checkLossyPacket(nil); err != ErrFriendCustomPacketEmpty {
+		t.Error("must empty", err)
+	}
+	if err := checkLossyPacket([]byte{PACKET_ID_LOSSLESS_MIN, 1}); err != ErrFriendCustomPacketInvalid {
+		t.Error("must invalid", err)
+	}
+	if err := checkLosslessPacket(make([]byte, MAX_CUSTOM_PACKET_SIZE+1)); err != ErrFriendCustomPacketTooLong {
+		t.Error("must too long", err)
+	}
+	if checkLossyPacket([]byte{PACKET_ID_LOSSY_MAX}) != nil || checkLosslessPacket([]byte{PACKET_ID_LOSSLESS_MAX, 0}) != nil {
+		t.Error("must valid")
+	}
+
+	r := &PacketRouter{handlers: make(map[byte]PacketHandler)}
+	var got []byte
+	if err := r.Handle(PACKET_ID_LOSSY_MIN, func(_ *Tox, friendNumber uint32, data []byte) { got = data }); err != nil {
+		t.Error(err)
+	}
+	if err := r.Handle(PACKET_ID_LOSSY_MIN, func(_ *Tox, friendNumber uint32, data []byte) {}); !errors.Is(err, ErrPacketHandlerExists) {
+		t.Error("must exists", err)
+	}
+	if err := r.Handle(100, func(_ *Tox, friendNumber uint32, data []byte) {}); !errors.Is(err, ErrFriendCustomPacketInvalid) {
+		t.Error("must invalid", err)
+	}
+	r.dispatch(nil, 0, []byte{PACKET_ID_LOSSY_MIN + 1, 1})
+	if got != nil {
+		t.Error("must not dispatched", got)
+	}
+	r.dispatch(nil, 0, []byte{PACKET_ID_LOSSY_MIN, 0, 1})
+	if !bytes.Equal(got, []byte{PACKET_ID_LOSSY_MIN, 0, 1}) {
+		t.Error("must dispatched", got)
+	}
+	if !r.Remove(PACKET_ID_LOSSY_MIN) || r.Remove(PACKET_ID_LOSSY_MIN) {
+		t.Error("must removed once")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "toxtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "tox.data")
+	for _, data := range []string{"first", "second"} {
+		if err := writeFileAtomic(fname, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := ioutil.ReadFile(fname); string(got) != data {
+			t.Error("must", data, string(got))
+		}
+	}
+	if fi, _ := os.Stat(fname); fi.Mode().Perm() != 0600 {
+		t.Error("must 0600", fi.Mode())
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Error("must no temp files left", len(files))
+	}
+}
+
+func TestProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "toxtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "tox.data")
+
+	p, err := OpenProfile(fname, []byte("secret"))
+	if err != nil || !p.Encrypted() || p.Options.Savedata_data != nil {
+		t.Fatal("must new encrypted profile", err)
+	}
+	if err := p.Save([]byte("savedata")); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+
+	if _, err := OpenProfile(fname, nil); err != ErrProfileLocked {
+		t.Error("must locked", err)
+	}
+	if _, err := OpenProfile(fname, []byte("wrong")); !errors.Is(err, ErrDecryptionFailed) {
+		t.Error("must wrong passphrase", err)
+	}
+	p, err = OpenProfile(fname, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if string(p.Options.Savedata_data) != "savedata" || p.Options.Savedata_type != SAVEDATA_TYPE_TOX_SAVE {
+		t.Error("must decrypted", p.Options.Savedata_data)
+	}
+
+	sd := &savedata.Savedata{Name: "check"}
+	sd.PublicKey[0] = 1
+	plain, _ := sd.Marshal()
+	if err := p.Save(plain); err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := ioutil.ReadFile(fname)
+	if err := CheckSavedata(enc); err != ErrSavedataEncrypted {
+		t.Error("must refuse encrypted", err)
+	}
+	if err := p.Check(enc); err != nil {
+		t.Error("must decrypt and check", err)
+	}
+	enc[len(enc)-1] ^= 1
+	if err := p.Check(enc); err == nil {
+		t.Error("must detect corrupt encrypted savedata")
+	}
+}
+
+// TestSavedataMarshal loads a profile written by the savedata package.
+func TestSavedataMarshal(t *testing.T) {
+	t1 := NewMiniTox()
+	defer t1.t.Kill()
+	t2 := NewMiniTox()
+	defer t2.t.Kill()
+	t1.t.SelfSetName("marshal")
+	t1.t.SelfSetStatusMessage("round trip")
+	if _, err := t1.t.FriendAddNorequestKey(t2.t.SelfPublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := t1.t.ConferenceNew(); err != nil {
+		t.Fatal(err)
+	}
+	orig := t1.t.GetSavedata()
+
+	sd, err := savedata.Parse(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := sd.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t3 := NewTox(NewToxOptions(WithLocalDiscovery(false), WithSavedata(data)))
+	if t3 == nil {
+		t.Fatal("must load marshaled savedata")
+	}
+	defer t3.Kill()
+	if t3.SelfGetName() != "marshal" || t3.SelfGetNospam() != t1.t.SelfGetNospam() ||
+		!reflect.DeepEqual(t3.SelfGetFriendList(), t1.t.SelfGetFriendList()) {
+		t.Error("must same profile", t3.SelfGetName(), t3.SelfGetNospam(), t3.SelfGetFriendList())
+	}
+	if t3.ConferenceGetChatlistSize() != 1 {
+		t.Error("must keep the conference")
+	}
+	if !bytes.Equal(t3.GetSavedata(), orig) {
+		t.Error("must same savedata")
+	}
+
+	// edited offline
+	other := NewMiniTox()
+	defer other.t.Kill()
+	if !sd.AddFriend(savedata.PublicKey(other.t.SelfPublicKey())) {
+		t.Fatal("must add friend")
+	}
+	if err := sd.RotateNospam(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = sd.Marshal(); err != nil {
+		t.Fatal(err)
+	}
+	t4 := NewTox(NewToxOptions(WithLocalDiscovery(false), WithSavedata(data)))
+	if t4 == nil {
+		t.Fatal("must load edited savedata")
+	}
+	defer t4.Kill()
+	if _, err := t4.FriendByPublicKey(other.t.SelfPublicKey().String()); err != nil || t4.SelfGetNospam() != sd.Nospam {
+		t.Error("must edited profile", err, t4.SelfGetNospam(), sd.Nospam)
+	}
+}
+
+func TestSaveStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "toxtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	profiles := make([][]byte, 5)
+	for i := range profiles {
+		sd := &savedata.Savedata{Name: fmt.Sprint("bot", i)}
+		sd.PublicKey[0] = 1
+		profiles[i], _ = sd.Marshal()
+	}
+
+	stores := []SaveStore{&MemoryStore{}, &FileStore{filepath.Join(dir, "tox.data")}}
+	vs, err := NewVersionedStore(filepath.Join(dir, "versions"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores = append(stores, vs)
+	for _, store := range stores {
+		if _, err := store.Load(); err != ErrNoSavedata {
+			t.Error("must no savedata", err)
+		}
+		for _, data := range profiles {
+			if err := store.Save(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if data, err := store.Load(); err != nil || !bytes.Equal(data, profiles[4]) {
+			t.Error("must last", err)
+		}
+	}
+
+	if err := vs.Save([]byte("garbage")); err == nil {
+		t.Error("must rejected")
+	}
+	snaps, err := vs.List()
+	if err != nil || len(snaps) != 3 || !snaps[0].Time.After(snaps[1].Time) {
+		t.Fatal("must 3 newest first", snaps, err)
+	}
+	if err := vs.Restore(snaps[2].Name); err != nil {
+		t.Error(err)
+	}
+	if data, _ := vs.Load(); !bytes.Equal(data, profiles[2]) {
+		t.Error("must restored")
+	}
+	if snaps, _ = vs.List(); len(snaps) != 3 {
+		t.Error("must pruned", snaps)
+	}
+}
+
+func TestAutosaveDebounce(t *testing.T) {
+	as := &autosaver{store: &MemoryStore{}, delay: time.Second}
+	now := time.Now()
+	if as.due(now) {
+		t.Error("must not due when clean")
+	}
+	as.mark(now)
+	as.mark(now.Add(900 * time.Millisecond))
+	if as.due(now.Add(time.Second)) {
+		t.Error("must wait for the last change")
+	}
+	if !as.due(now.Add(1900 * time.Millisecond)) {
+		t.Error("must due after delay")
+	}
+	for i := 1; i < autosaveMaxDelays*2; i++ {
+		as.mark(now.Add(time.Duration(i) * 500 * time.Millisecond))
+	}
+	if !as.due(now.Add(autosaveMaxDelays * time.Second)) {
+		t.Error("must due after max delays")
+	}
+	if !as.take() || as.take() || as.due(now.Add(time.Hour)) {
+		t.Error("must take once")
+	}
+}
+
+func TestAutosave(t *testing.T) {
+	t1 := NewMiniTox()
+	store := &MemoryStore{}
+	t1.t.SelfSetName("before")
+	if err := t1.t.EnableAutosave(store, 0, func(err error) { t.Error(err) }); err != nil {
+		t.Fatal(err)
+	}
+
+	t1.t.Iterate()
+	if data, err := store.Load(); err != nil || !bytes.Contains(data, []byte("before")) {
+		t.Error("must save changes made before enabling", err)
+	}
+	store.Save(nil)
+	t1.t.Iterate()
+	if data, _ := store.Load(); len(data) != 0 {
+		t.Error("must not save unchanged")
+	}
+	t1.t.SelfSetName("autosave")
+	t1.t.Iterate()
+	data, err := store.Load()
+	if err != nil || !bytes.Equal(data, t1.t.GetSavedata()) {
+		t.Error("must saved", err)
+	}
+
+	if !t1.t.needsCallback(t1.t.cb_friend_names) || t1.t.needsCallback(t1.t.cb_friend_messages) {
+		t.Error("must keep only the autosave callbacks")
+	}
+	h := t1.t.CallbackFriendNameAdd(func(*Tox, uint32, string, interface{}) {}, nil)
+	t1.t.CallbackRemove(h)
+	if err := t1.t.DisableAutosave(); err != nil || t1.t.needsCallback(t1.t.cb_friend_names) {
+		t.Error("must release the autosave callbacks", err)
+	}
+	if err := t1.t.EnableAutosave(store, 0, func(err error) { t.Error(err) }); err != nil {
+		t.Fatal(err)
+	}
+
+	t1.t.SelfSetStatusMessage("killed")
+	t1.t.Kill()
+	if data, _ := store.Load(); !bytes.Contains(data, []byte("killed")) {
+		t.Error("must saved on kill")
+	}
+	if err := t1.t.EnableAutosave(store, 0, nil); err != ErrKilled {
+		t.Error("must killed", err)
+	}
+}
+
+func TestPortFallback(t *testing.T) {
+	fb := &PortFallback{Tries: 2, TcpStep: 1, UdpStep: 10}
+	opt := &ToxOptions{Tcp_port: 34000, Start_port: 34100, End_port: 34109}
+	o := fb.next(opt, 2)
+	if o.Tcp_port != 34002 || o.Start_port != 34120 || o.End_port != 34129 || opt.Tcp_port != 34000 {
+		t.Error("must moved", o.Tcp_port, o.Start_port, o.End_port)
+	}
+	if o := fb.next(&ToxOptions{}, 1); o.Tcp_port != 0 || o.Start_port != 0 {
+		t.Error("must keep defaults", o.Tcp_port, o.Start_port)
+	}
+
+	opts := NewToxOptions()
+	opts.Local_discovery_enabled = false
+	opts.Tcp_port = 34567
+	t1, err := NewToxWithError(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer t1.Kill()
+
+	if _, err := NewToxWithError(opts); !errors.Is(err, ErrNewPortAlloc) {
+		t.Error("must port alloc", err)
+	}
+	opts.PortFallback = &PortFallback{Tries: 3, TcpStep: 1}
+	t2, err := NewToxWithError(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer t2.Kill()
+	if t2.opts.Tcp_port != 34568 || opts.Tcp_port != 34567 {
+		t.Error("must next port", t2.opts.Tcp_port)
+	}
+}
+
+func TestToxOptionsValidate(t *testing.T) {
+	build := func(opts ...ToxOption) *ToxOptions {
+		o := &ToxOptions{Ipv6_enabled: true, Udp_enabled: true}
+		for _, opt := range opts {
+			opt(o)
+		}
+		return o
+	}
+	if err := build().Validate(); err != nil {
+		t.Error("must valid", err)
+	}
+	o := build(WithProxy(PROXY_TYPE_SOCKS5, "127.0.0.1", 9050), WithPortRange(33445, 33545))
+	if err := o.Validate(); err != nil || o.Udp_enabled {
+		t.Error("must valid without udp", err, o.Udp_enabled)
+	}
+
+	bad := map[string]*ToxOptions{
+		"needs a Proxy_host":  build(WithProxy(PROXY_TYPE_HTTP, "", 8080)),
+		"needs a Proxy_port":  build(WithProxy(PROXY_TYPE_HTTP, "proxy", 0)),
+		"longer than":         build(WithProxy(PROXY_TYPE_HTTP, strings.Repeat("x", MAX_HOSTNAME_LENGTH+1), 8080)),
+		"UDP must be":         build(WithProxy(PROXY_TYPE_SOCKS5, "127.0.0.1", 9050), WithUDP(true)),
+		"unknown Proxy_type":  build(WithProxy(42, "proxy", 1)),
+		"is above End_port":   build(WithPortRange(33545, 33445)),
+		"Savedata_data is em": build(WithSavedata(nil)),
+		"is encrypted":        build(WithSavedata([]byte("toxEsave0123456789"))),
+		"must be 32 bytes":    build(WithSecretKey([]byte{1, 2, 3})),
+		"Savedata_type is no": build(func(o *ToxOptions) { o.Savedata_data = []byte{1} }),
+	}
+	for want, o := range bad {
+		err := o.Validate()
+		if !errors.Is(err, ErrInvalidOptions) || !strings.Contains(err.Error(), want) {
+			t.Errorf("must %q, got %v", want, err)
+		}
+	}
+
+	err := build(WithProxy(PROXY_TYPE_HTTP, "", 0), WithPortRange(2, 1)).Validate()
+	if err == nil || strings.Count(err.Error(), ";") != 2 {
+		t.Error("must list all problems", err)
+	}
+	if _, err := NewToxWithError(build(WithPortRange(2, 1))); !errors.Is(err, ErrInvalidOptions) {
+		t.Error("must fail before tox_new", err)
+	}
+	// NewTox leaves it to tox_new, which swaps the range
+	if _t := NewTox(build(WithPortRange(33545, 33445))); _t == nil {
+		t.Error("must compatible")
+	} else {
+		_t.Kill()
+	}
+}
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "toxconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pk := strings.Repeat("AB", PUBLIC_KEY_SIZE)
+
+	jsonFile := filepath.Join(dir, "bot.json")
+	ioutil.WriteFile(jsonFile, []byte(`{"udp": false, "proxy_type": "SOCKS5", "proxy_host": "127.0.0.1",
+		"proxy_port": 9050, "bootstrap": [{"addr": "node.example", "port": 33445, "pubkey": "`+pk+`"}]}`), 0600)
+	cfg := &Config{Ipv6: true, Udp: true, ProxyType: "none"}
+	if err := cfg.LoadFile(jsonFile); err != nil {
+		t.Fatal(err)
+	}
+	want := &Config{Ipv6: true, ProxyType: "socks5", ProxyHost: "127.0.0.1", ProxyPort: 9050,
+		Bootstrap: []BootNode{{"node.example", 33445, pk}}}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("must json %+v, got %+v", want, cfg)
+	}
+
+	tomlFile := filepath.Join(dir, "bot.toml")
+	ioutil.WriteFile(tomlFile, []byte(`# bot
+udp = false
+proxy_type = "socks5" # tor
+proxy_host = '127.0.0.1'
+proxy_port = 9050
+
+[[bootstrap]]
+addr = "node.example"
+port = 33445
+pubkey = "`+pk+`"
+`), 0600)
+	cfg = &Config{Ipv6: true, Udp: true, ProxyType: "none"}
+	if err := cfg.LoadFile(tomlFile); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("must toml %+v, got %+v", want, cfg)
+	}
+
+	ioutil.WriteFile(tomlFile, []byte("udp = false\nprxy_type = \"http\"\n"), 0600)
+	if err := cfg.LoadFile(tomlFile); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Error("must unknown key", err)
+	}
+
+	os.Setenv("TOX_TCP_PORT", "33446")
+	os.Setenv("TOX_BOOTSTRAP", "[::1]:33445:"+pk)
+	defer os.Unsetenv("TOX_TCP_PORT")
+	defer os.Unsetenv("TOX_BOOTSTRAP")
+	cfg = &Config{ProxyType: "none", Bootstrap: []BootNode{{"node.example", 33445, pk}}}
+	if err := cfg.LoadEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TcpPort != 33446 || len(cfg.Bootstrap) != 1 || cfg.Bootstrap[0].Addr != "::1" {
+		t.Errorf("must env %+v", cfg)
+	}
+	os.Setenv("TOX_TCP_PORT", "70000")
+	if err := cfg.LoadEnv(); err == nil || !strings.Contains(err.Error(), "TOX_TCP_PORT") {
+		t.Error("must bad port", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg = &Config{Udp: true, ProxyType: "none", Bootstrap: []BootNode{{"node.example", 33445, pk}}}
+	cfg.BindFlags(fs)
+	if err := fs.Parse([]string{"-udp=false", "-proxy-type", "http", "-savedata", "bot.tox",
+		"-bootstrap", "1.2.3.4:33445:" + pk, "-bootstrap", "5.6.7.8:33445:" + pk}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Udp || cfg.ProxyType != "http" || cfg.Savedata != "bot.tox" {
+		t.Errorf("must flags %+v", cfg)
+	}
+	if len(cfg.Bootstrap) != 2 || cfg.Bootstrap[0].Addr != "1.2.3.4" || cfg.Bootstrap[1].Addr != "5.6.7.8" {
+		t.Errorf("must replace, then add bootstrap nodes %+v", cfg.Bootstrap)
+	}
+	if f := fs.Lookup("proxy-type"); f == nil || f.DefValue != "none" {
+		t.Error("must default from config", f)
+	}
+
+	passFile := filepath.Join(dir, "pass")
+	for content, want := range map[string]string{"pw": "pw", "pw\n": "pw", "pw\r\n": "pw", "pw\n\n": "pw\n"} {
+		ioutil.WriteFile(passFile, []byte(content), 0600)
+		if p, err := ReadPassphraseFile(passFile); err != nil || string(p) != want {
+			t.Errorf("must passphrase %q of %q, got %q %v", want, content, p, err)
+		}
+	}
+}
+
+func TestPresets(t *testing.T) {
+	pk := strings.Repeat("AB", PUBLIC_KEY_SIZE)
+	apply := func(opts ...ToxOption) *ToxOptions {
+		o := &ToxOptions{Ipv6_enabled: true, Udp_enabled: true, Local_discovery_enabled: true, Hole_punching_enabled: true}
+		for _, opt := range opts {
+			opt(o)
+		}
+		return o
+	}
+
+	o := apply(PresetTor("127.0.0.1", 9050))
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if o.Udp_enabled || o.Local_discovery_enabled || o.Hole_punching_enabled || !o.StrictProxy ||
+		o.Proxy_type != int32(PROXY_TYPE_SOCKS5) {
+		t.Errorf("must tor options %+v", o)
+	}
+	tor := &Tox{opts: o}
+	if _, err := tor.Bootstrap("1.2.3.4", 33445, pk); !errors.Is(err, ErrStrictProxy) {
+		t.Error("must refuse udp bootstrap", err)
+	}
+	if _, err := tor.AddTcpRelay("node.example", 33445, pk); !errors.Is(err, ErrStrictProxy) {
+		t.Error("must refuse relay hostname", err)
+	}
+	if err := tor.checkPeerAddr("1.2.3.4", false); err != nil {
+		t.Error("must allow relay ip", err)
+	}
+	if err := apply(PresetTor("127.0.0.1", 9050), WithLocalDiscovery(true)).Validate(); err == nil {
+		t.Error("must refuse local discovery")
+	}
+
+	o = apply(PresetTCPOnly())
+	if err := o.Validate(); err != nil || o.Udp_enabled || o.StrictProxy {
+		t.Errorf("must tcp only %+v %v", o, err)
+	}
+
+	o = apply(PresetTor("127.0.0.1", 9050), PresetLAN())
+	if err := o.Validate(); err != nil || !o.LanOnly || o.StrictProxy || !o.Udp_enabled || o.Proxy_type != 0 {
+		t.Errorf("must lan %+v %v", o, err)
+	}
+	lan := &Tox{opts: o}
+	for addr, ok := range map[string]bool{"192.168.1.2": true, "10.1.1.1": true, "fe80::1": true, "127.0.0.1": true, "1.2.3.4": false, "2001:db8::1": false} {
+		if err := lan.checkPeerAddr(addr, true); (err == nil) != ok || (!ok && !errors.Is(err, ErrLanOnly)) {
+			t.Error("must lan check", addr, ok, err)
+		}
+	}
+}
+
+func TestBootstrapper(t *testing.T) {
+	key := func(b byte) string { return strings.Repeat(fmt.Sprintf("%02X", b), PUBLIC_KEY_SIZE) }
+	list, err := ReadNodeList(strings.NewReader(`{"last_scan": 1600000000, "nodes": [
+		{"ipv4": "node1.example", "ipv6": "-", "port": 33445, "tcp_ports": [3389], "public_key": "` + key(1) + `",
+		 "status_udp": true, "status_tcp": true, "last_ping": 1600000000},
+		{"ipv4": "node2.example", "ipv6": "-", "port": 33445, "public_key": "` + key(2) + `",
+		 "status_udp": false, "status_tcp": false, "last_ping": 1600000000},
+		{"ipv4": "node3.example", "port": 33445, "public_key": "` + key(3) + `"},
+		{"ipv4": "node4.example", "port": 33445, "public_key": "bad"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Nodes) != 3 || list.LastScan != 1600000000 || list.Nodes[0].TcpPorts[0] != 3389 {
+		t.Fatalf("must drop bad key %+v", list)
+	}
+
+	dir, err := ioutil.TempDir("", "toxnodes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// strict proxy refuses every call on hostnames, so nothing reaches toxcore
+	tx := &Tox{opts: &ToxOptions{StrictProxy: true}}
+	b := NewBootstrapper(tx)
+	b.CachePath = filepath.Join(dir, "nodes.json")
+	b.AddNodes(list.Nodes...)
+	b.AddBootNodes(BootNode{"node5.example", 33445, key(5)})
+	if nodes := b.Nodes(); len(nodes) != 3 {
+		t.Fatal("must skip down nodes", nodes)
+	}
+	if n, err := b.Bootstrap(); n != 0 || !errors.Is(err, ErrStrictProxy) {
+		t.Error("must refused", n, err)
+	}
+
+	b.Count = 2
+	b.Interval = time.Minute
+	now := b.lastTry
+	b.iterate(false, now.Add(time.Second))
+	if b.lastTry != now {
+		t.Error("must wait interval")
+	}
+	b.iterate(false, now.Add(time.Minute))
+	if !b.lastTry.After(now) || len(b.tried) != 2 {
+		t.Error("must retry", b.lastTry, b.tried)
+	}
+	b.iterate(true, now.Add(2*time.Minute))
+	cache, err := LoadNodeList(b.CachePath)
+	if err != nil || len(cache.Nodes) != 0 {
+		t.Error("must not cache refused nodes", cache, err)
+	}
+	b.BootstrapRelays()
+	if len(b.tried) != 2 || containsNode(b.tried, key(3)) {
+		t.Error("must relays only", b.tried)
+	}
+
+	// a real instance takes the calls on addresses, a node without any
+	// doesn't get one
+	t1 := NewMiniTox()
+	defer t1.t.Kill()
+	b1 := NewBootstrapper(t1.t)
+	b1.CachePath = b.CachePath
+	b1.Count = 2*bootstrapCacheSize + 1
+	b1.AddNodes(NodeInfo{Ipv4: "-", Ipv6: "-", Port: 33445, PublicKey: key(15)})
+	for i := 0; i < 2*bootstrapCacheSize; i++ {
+		b1.AddNodes(NodeInfo{Ipv4: "127.0.0.1", Port: 33445, PublicKey: key(byte(16 + i))})
+	}
+	if n, err := b1.Bootstrap(); n != 2*bootstrapCacheSize || err != nil {
+		t.Error("must bootstrap", n, err)
+	}
+	b1.iterate(true, time.Now())
+	cache, err = LoadNodeList(b.CachePath)
+	if err != nil || len(cache.Nodes) != bootstrapCacheSize || containsNode(cache.Nodes, key(15)) {
+		t.Error("must cache up to 16 nodes that took calls", cache, err)
+	}
+
+	b2 := NewBootstrapper(t1.t)
+	b2.CachePath = b.CachePath
+	b2.Count = 2
+	b2.AddNodes(NodeInfo{Ipv4: "127.0.0.2", Port: 33445, PublicKey: key(1)})
+	b2.Start()
+	if picked := b2.pick(true); !containsNode(cache.Nodes, picked[0].PublicKey) {
+		t.Error("must prefer cached", picked)
+	}
+	if t1.t.bootstrapper != b2 {
+		t.Error("must started")
+	}
+	b2.Stop()
+	if t1.t.bootstrapper != nil {
+		t.Error("must stopped")
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	tx := &Tox{opts: &ToxOptions{StrictProxy: true}}
+	b := NewBootstrapper(tx)
+	b.AddBootNodes(BootNode{"node1.example", 33445, strings.Repeat("AB", PUBLIC_KEY_SIZE)})
+	udpOnly := strings.Repeat("CD", PUBLIC_KEY_SIZE)
+	b.AddNodes(NodeInfo{Ipv4: "node2.example", Port: 33445, PublicKey: udpOnly, StatusUdp: true, LastPing: 1})
+	w := NewWatchdog(tx, b)
+	w.MinBackoff = time.Second
+	w.MaxBackoff = 4 * time.Second
+	w.ReportInterval = 10 * time.Second
+	var events []string
+	w.OnEvent = func(ev NetworkEvent) { events = append(events, ev.String()) }
+
+	if w.backoff(0) != time.Second || w.backoff(2) != 4*time.Second || w.backoff(10) != 4*time.Second {
+		t.Error("must backoff", w.backoff(0), w.backoff(2), w.backoff(10))
+	}
+
+	start := time.Now()
+	w.setStatus(CONNECTION_NONE, start)
+	var tries []time.Duration
+	var udp []bool
+	for s := 0; s <= 20; s++ {
+		now := start.Add(time.Duration(s) * time.Second)
+		last := b.lastTry
+		w.tick(now)
+		if b.lastTry != last {
+			tries = append(tries, now.Sub(start))
+			udp = append(udp, containsNode(b.tried, udpOnly))
+		}
+	}
+	want := []time.Duration{1, 3, 7, 11, 15, 19}
+	for i := range want {
+		want[i] *= time.Second
+	}
+	if !reflect.DeepEqual(tries, want) {
+		t.Error("must back off", tries)
+	}
+	if !reflect.DeepEqual(udp, []bool{true, true, true, false, false, false}) {
+		t.Error("must fall back to relays after 3 attempts", udp)
+	}
+	if !w.tcpOnly {
+		t.Error("must tcp only")
+	}
+
+	w.setStatus(CONNECTION_TCP, start.Add(21*time.Second))
+	w.tick(start.Add(60 * time.Second))
+	w.setStatus(CONNECTION_TCP, start.Add(61*time.Second))
+	w.setStatus(CONNECTION_UDP, start.Add(62*time.Second))
+	if state, since := w.State(); state != NETWORK_ONLINE || !since.Equal(start.Add(62*time.Second)) {
+		t.Error("must online", state, since)
+	}
+	wantEvents := []string{"offline", "offline for 10s, 3 re-bootstraps",
+		"offline for 11s, 4 re-bootstraps, TCP relays only", "degraded (TCP only)", "online"}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("must events %q", events)
+	}
+}
+
+func TestNetworkInfo(t *testing.T) {
+	t1 := NewMiniTox()
+	defer t1.t.Kill()
+	t2 := NewMiniTox()
+	defer t2.t.Kill()
+
+	if _, err := t1.t.SelfGetTcpPort(); !errors.Is(err, ErrGetPortNotBound) {
+		t.Error("must not bound", err)
+	}
+	fn, err := t1.t.FriendAddNorequestKey(t2.t.SelfPublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if t1.t.needsCallback(t1.t.cb_self_connection_statuss) {
+		t.Error("must not follow connections before NetworkInfo")
+	}
+	info, err := t1.t.NetworkInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !t1.t.needsCallback(t1.t.cb_self_connection_statuss) || t1.t.needsCallback(t1.t.cb_friend_typings) {
+		t.Error("must keep only the connection callbacks")
+	}
+	udpPort, _ := t1.t.SelfGetUdpPort()
+	if info.UdpPort == 0 || info.UdpPort != udpPort || info.TcpPort != 0 {
+		t.Error("must ports", info.UdpPort, info.TcpPort)
+	}
+	if info.DhtId.String() != t1.t.SelfGetDhtId() || info.Address != t1.t.SelfAddress() {
+		t.Error("must ids", info.DhtId, info.Address)
+	}
+	if info.ProxyType != "none" || info.LocalDiscovery || info.Connection != CONNECTION_NONE {
+		t.Errorf("must options %+v", info)
+	}
+	if len(info.Friends) != 1 || info.Friends[0].Number != fn || info.Friends[0].PublicKey != t2.t.SelfPublicKey() ||
+		!info.Friends[0].Since.Equal(t1.t.conntimes.started) {
+		t.Errorf("must friend %+v", info.Friends)
+	}
+
+	t1.t.conntimes.friends[fn] = time.Now()
+	t1.t.FriendDelete(fn)
+	if !t1.t.conntimes.since(int64(fn)).Equal(t1.t.conntimes.started) {
+		t.Error("must forget deleted friend")
+	}
+	if _, err := json.Marshal(info); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNetworkInfoHandler(t *testing.T) {
+	t1 := NewMiniTox()
+	defer t1.t.Kill()
+	if _, err := NetworkInfoHandler(t1.t); err != ErrNotThreadSafe {
+		t.Error("must refuse racy instance", err)
+	}
+
+	t2 := NewTox(NewToxOptions(WithLocalDiscovery(false), WithThreadSafe(true)))
+	h, err := NetworkInfoHandler(t2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &NetworkInfo{}
+	err = json.NewDecoder(resp.Body).Decode(info)
+	resp.Body.Close()
+	if err != nil || info.Address != t2.SelfAddress() {
+		t.Error("must serve the running instance", err, info.Address)
+	}
+
+	t2.Kill()
+	resp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Error("must unavailable after kill", resp.Status)
+	}
+}
+
 func TestAV(t *testing.T) {
 	t.Run("create", func(t *testing.T) {
 		if tv1, err := NewToxAV(nil); tv1 != nil {
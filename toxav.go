@@ -25,13 +25,14 @@ extern void rgb_to_i420(unsigned char* rgb, vpx_image_t *img);
 */
 import "C"
 import (
+	"context"
 	"encoding/hex"
-	"errors"
+	"time"
 	"unsafe"
 )
 
 type cb_call_ftype func(this *ToxAV, friendNumber uint32, audioEnabled bool, videoEnabled bool, userData interface{})
-type cb_call_state_ftype func(this *ToxAV, friendNumber uint32, state uint32, userData interface{})
+type cb_call_state_ftype func(this *ToxAV, friendNumber uint32, state CallState, userData interface{})
 type cb_audio_bit_rate_ftype func(this *ToxAV, friendNumber uint32, audioBitRate uint32, userData interface{})
 type cb_video_bit_rate_ftype func(this *ToxAV, friendNumber uint32, videoBitRate uint32, userData interface{})
 type cb_audio_receive_frame_ftype func(this *ToxAV, friendNumber uint32, pcm []byte, sampleCount int, channels int, samplingRate int, userData interface{})
@@ -67,7 +68,7 @@ type ToxAV struct {
 
 func NewToxAV(tox *Tox) (*ToxAV, error) {
 	if tox == nil {
-		return nil, toxerr("tox can not nil")
+		return nil, &AVNewError{AV_ERR_NEW_NULL}
 	}
 
 	tav := new(ToxAV)
@@ -76,7 +77,7 @@ func NewToxAV(tox *Tox) (*ToxAV, error) {
 	var cerr C.Toxav_Err_New
 	tav.toxav = C.toxav_new(tox.toxcore, &cerr)
 	if cerr != 0 {
-		return nil, toxerr(cerr)
+		return nil, &AVNewError{int(cerr)}
 	}
 
 	cbAVUserDatas.set(tav.toxav, tav)
@@ -84,14 +85,31 @@ func NewToxAV(tox *Tox) (*ToxAV, error) {
 }
 
 func (this *ToxAV) Kill() {
+	if this == nil {
+		return
+	}
+	this.tox.avmu.Lock()
+	defer this.tox.avmu.Unlock()
+	if this.toxav == nil {
+		return
+	}
+	cbAVUserDatas.del(this.toxav)
 	C.toxav_kill(this.toxav)
+	this.toxav = nil
 }
 
 func (this *ToxAV) GetTox() *Tox {
 	return this.tox
 }
 
+// IterationInterval returns KILLED_ITERATION_INTERVAL once the instance was
+// killed.
 func (this *ToxAV) IterationInterval() int {
+	this.tox.avmu.Lock()
+	defer this.tox.avmu.Unlock()
+	if this.toxav == nil {
+		return KILLED_ITERATION_INTERVAL
+	}
 	return int(C.toxav_iteration_interval(this.toxav))
 }
 
@@ -99,11 +117,39 @@ func (this *ToxAV) Iterate() {
 	C.toxav_iterate(this.toxav)
 }
 
+// Run calls Iterate every IterationInterval until ctx is done or either the
+// ToxAV or its Tox instance is killed. The AV loop has a much shorter
+// cadence than the core loop, so it is meant to run in its own goroutine
+// next to Tox.Run. Each iteration holds the Tox's AV lock, so Tox.Kill and
+// Kill wait for it and Run returns ErrKilled at the next one.
+func (this *ToxAV) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		this.tox.avmu.Lock()
+		if this.toxav == nil || this.tox.Killed {
+			this.tox.avmu.Unlock()
+			return ErrKilled
+		}
+		C.toxav_iterate(this.toxav)
+		interval := time.Duration(C.toxav_iteration_interval(this.toxav)) * time.Millisecond
+		this.tox.avmu.Unlock()
+		timer.Reset(interval)
+	}
+}
+
 func (this *ToxAV) Call(friendNumber uint32, audioBitRate uint32, videoBitRate uint32) (bool, error) {
 	var cerr C.Toxav_Err_Call
 	r := C.toxav_call(this.toxav, C.uint32_t(friendNumber), C.uint32_t(audioBitRate), C.uint32_t(videoBitRate), &cerr)
 	if cerr != 0 {
-		return bool(r), toxerr(cerr)
+		return bool(r), &AVCallError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -130,7 +176,7 @@ func (this *ToxAV) Answer(friendNumber uint32, audioBitRate uint32, videoBitRate
 	var cerr C.Toxav_Err_Answer
 	r := C.toxav_answer(this.toxav, C.uint32_t(friendNumber), C.uint32_t(audioBitRate), C.uint32_t(videoBitRate), &cerr)
 	if cerr != C.TOXAV_ERR_ANSWER_OK {
-		return false, toxerr(cerr)
+		return false, &AVAnswerError{int(cerr)}
 	}
 
 	return bool(r), nil
@@ -140,7 +186,7 @@ func (this *ToxAV) Answer(friendNumber uint32, audioBitRate uint32, videoBitRate
 func callbackCallStateWrapperForC(m *C.ToxAV, friendNumber C.uint32_t, state C.uint32_t, a3 unsafe.Pointer) {
 	var this = cbAVUserDatas.get(m)
 	if this.cb_call_state != nil {
-		this.cb_call_state(this, uint32(friendNumber), uint32(state), this.cb_call_state_user_data)
+		this.cb_call_state(this, uint32(friendNumber), CallState(state), this.cb_call_state_user_data)
 	}
 }
 
@@ -152,11 +198,11 @@ func (this *ToxAV) CallbackCallState(cbfn cb_call_state_ftype, userData interfac
 	C.toxav_callback_call_state(this.toxav, _cbfn, nil)
 }
 
-func (this *ToxAV) CallControl(friendNumber uint32, control int) (bool, error) {
+func (this *ToxAV) CallControl(friendNumber uint32, control CallControlType) (bool, error) {
 	var cerr C.Toxav_Err_Call_Control
 	r := C.toxav_call_control(this.toxav, C.uint32_t(friendNumber), C.Toxav_Call_Control(control), &cerr)
 	if cerr != C.TOXAV_ERR_CALL_CONTROL_OK {
-		return bool(r), toxerr(cerr)
+		return bool(r), &AVCallControlError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -165,7 +211,7 @@ func (this *ToxAV) AudioSetBitRate(friendNumber uint32, audioBitRate uint32) (bo
 	var cerr C.Toxav_Err_Bit_Rate_Set
 	r := C.toxav_audio_set_bit_rate(this.toxav, C.uint32_t(friendNumber), C.uint32_t(audioBitRate), &cerr)
 	if cerr != C.TOXAV_ERR_BIT_RATE_SET_OK {
-		return bool(r), toxerr(cerr)
+		return bool(r), &AVBitRateSetError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -174,7 +220,7 @@ func (this *ToxAV) VideoSetBitRate(friendNumber uint32, videoBitRate uint32) (bo
 	var cerr C.Toxav_Err_Bit_Rate_Set
 	r := C.toxav_video_set_bit_rate(this.toxav, C.uint32_t(friendNumber), C.uint32_t(videoBitRate), &cerr)
 	if cerr != C.TOXAV_ERR_BIT_RATE_SET_OK {
-		return bool(r), toxerr(cerr)
+		return bool(r), &AVBitRateSetError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -216,7 +262,7 @@ func (this *ToxAV) AudioSendFrame(friendNumber uint32, pcm []byte, sampleCount i
 	var cerr C.Toxav_Err_Send_Frame
 	r := C.toxav_audio_send_frame(this.toxav, C.uint32_t(friendNumber), pcm_, C.size_t(sampleCount), C.uint8_t(channels), C.uint32_t(samplingRate), &cerr)
 	if cerr != C.TOXAV_ERR_SEND_FRAME_OK {
-		return false, toxerr(cerr)
+		return false, &AVSendFrameError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -242,7 +288,7 @@ func (this *ToxAV) VideoSendFrame(friendNumber uint32, width uint16, height uint
 		(*C.uint8_t)(this.in_image.planes[2]),
 		&cerr)
 	if cerr != C.TOXAV_ERR_SEND_FRAME_OK {
-		return false, toxerr(cerr)
+		return false, &AVSendFrameError{int(cerr)}
 	}
 	return bool(r), nil
 }
@@ -325,8 +371,8 @@ func (this *Tox) AddAVGroupChat(cbfn cb_audio_ftype) uint32 {
 
 func (this *Tox) JoinAVGroupChat(friendNumber uint32, cookie string, cbfn cb_audio_ftype) (uint32, error) {
 	data, err := hex.DecodeString(cookie)
-	if err != nil {
-		return 0, errors.New("Invalid cookie:" + cookie)
+	if err != nil || len(data) == 0 {
+		return 0, &ConferenceJoinError{ERR_CONFERENCE_JOIN_INVALID_LENGTH}
 	}
 
 	var _fn = C.uint32_t(friendNumber)
@@ -337,7 +383,7 @@ func (this *Tox) JoinAVGroupChat(friendNumber uint32, cookie string, cbfn cb_aud
 	r := C.toxav_join_av_groupchat(this.toxcore, _fn, _data, _length,
 		(*C.toxav_audio_data_cb)(unsafe.Pointer(C.callbackAudioForC)), nil)
 	if int(r) == -1 {
-		return uint32(r), errors.New("Join av group chat failed")
+		return uint32(r), ErrAVGroupChatJoin
 	}
 	if cbfn != nil {
 		this.cb_audios[uint32(r)] = cbfn
@@ -24,7 +24,7 @@ func Derive(passphrase []byte) (*ToxPassKey, error) {
 	var cerr C.Tox_Err_Key_Derivation
 	this.cpk = C.tox_pass_key_derive(passphrase_, C.size_t(len(passphrase)), &cerr)
 	if cerr != C.TOX_ERR_KEY_DERIVATION_OK {
-		return nil, toxerr(cerr)
+		return nil, &KeyDerivationError{int(cerr)}
 	}
 	return this, nil
 }
@@ -38,7 +38,7 @@ func DeriveWithSalt(passphrase []byte, salt []byte) (*ToxPassKey, error) {
 	var cerr C.Tox_Err_Key_Derivation
 	this.cpk = C.tox_pass_key_derive_with_salt(passphrase_, C.size_t(len(passphrase)), salt_, &cerr)
 	if cerr != C.TOX_ERR_KEY_DERIVATION_OK {
-		return nil, toxerr(cerr)
+		return nil, &KeyDerivationError{int(cerr)}
 	}
 	return this, nil
 }
@@ -53,7 +53,7 @@ func (this *ToxPassKey) Encrypt(plaintext []byte) (bool, error, []byte) {
 
 	var err error
 	if !bool(ok) {
-		err = toxerr(err)
+		err = &EncryptionError{int(cerr)}
 	}
 	return bool(ok), err, ciphertext
 }
@@ -67,7 +67,7 @@ func (this *ToxPassKey) Decrypt(ciphertext []byte) (bool, error, []byte) {
 	ok := C.tox_pass_key_decrypt(this.cpk, ciphertext_, C.size_t(len(ciphertext)), plaintext_, &cerr)
 	var err error
 	if !bool(ok) {
-		err = toxerr(cerr)
+		err = &DecryptionError{int(cerr)}
 	}
 	return bool(ok), err, plaintext
 }
@@ -81,7 +81,7 @@ func GetSalt(ciphertext []byte) (bool, error, []byte) {
 	ok := C.tox_get_salt(ciphertext_, salt_, &cerr)
 	var err error
 	if !bool(ok) {
-		err = toxerr(cerr)
+		err = &GetSaltError{int(cerr)}
 	}
 	return bool(ok), err, salt
 }
@@ -105,7 +105,7 @@ func PassEncrypt(plaintext []byte, passphrase []byte) (ciphertext []byte, err er
 	ok := C.tox_pass_encrypt(plaintext_, C.size_t(len(plaintext)), passphrase_, C.size_t(len(passphrase)), ciphertext_, &cerr)
 
 	if !bool(ok) {
-		err = toxerr(cerr)
+		err = &EncryptionError{int(cerr)}
 	}
 	return
 }
@@ -114,13 +114,13 @@ func PassDecrypt(ciphertext []byte, passphrase []byte) (plaintext []byte, err er
 	ciphertext_ := (*C.uint8_t)(&ciphertext[0])
 	plaintext = make([]byte, len(ciphertext)-PASS_ENCRYPTION_EXTRA_LENGTH)
 	plaintext_ := (*C.uint8_t)(&plaintext[0])
-	passphrase_ := (*C.uint8_t)(&plaintext[0])
+	passphrase_ := (*C.uint8_t)(&passphrase[0])
 
 	var cerr C.Tox_Err_Decryption
 	ok := C.tox_pass_decrypt(ciphertext_, C.size_t(len(ciphertext)), passphrase_, C.size_t(len(passphrase)), plaintext_, &cerr)
 
 	if !bool(ok) {
-		err = toxerr(cerr)
+		err = &DecryptionError{int(cerr)}
 	}
 	return
 }
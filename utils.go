@@ -3,7 +3,6 @@ package tox
 import (
 	"bytes"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -16,13 +15,11 @@ func safeptr(b []byte) unsafe.Pointer {
 	return unsafe.Pointer(h.Data)
 }
 
-func toxerr(errno interface{}) error {
-	return errors.New(fmt.Sprintf("toxcore error: %v", errno))
-}
+// ErrKilled is returned by the run loops once the instance was killed.
+var ErrKilled = errors.New("tox instance was killed")
 
-func toxerrf(f string, args ...interface{}) error {
-	return errors.New(fmt.Sprintf(f, args...))
-}
+// IterationInterval of a killed instance, in milliseconds
+const KILLED_ITERATION_INTERVAL = 50
 
 var toxdebug = false
 
@@ -44,41 +41,44 @@ func FileExist(fname string) bool {
 	return true
 }
 
-// the go-toxcore-c has data lost problem
-// we need first write tmp file, and if ok, then mv to real file
+// WriteSavedata writes the savedata to fname, unless it is unchanged.
 func (this *Tox) WriteSavedata(fname string) error {
-	if !FileExist(fname) {
-		err := ioutil.WriteFile(fname, this.GetSavedata(), 0755)
-		if err != nil {
-			return err
-		}
-	} else {
-		data, err := ioutil.ReadFile(fname)
-		if err != nil {
-			return err
-		}
-		liveData := this.GetSavedata()
-		if bytes.Compare(data, liveData) != 0 {
-			tfp, err := ioutil.TempFile(filepath.Dir(fname), "gotcb")
-			if err != nil {
-				return err
-			}
-			if _, err := tfp.Write(liveData); err != nil {
-				return err
-			}
-			tfname := tfp.Name()
-			if err := tfp.Close(); err != nil {
-				return err
-			}
-			if err := os.Remove(fname); err != nil {
-				return err
-			}
-			if err := os.Rename(tfname, fname); err != nil {
-				return err
-			}
-		}
+	liveData := this.GetSavedata()
+	if data, err := ioutil.ReadFile(fname); err == nil && bytes.Equal(data, liveData) {
+		return nil
+	}
+	return writeFileAtomic(fname, liveData)
+}
+
+// writeFileAtomic writes data to a 0600 temp file next to fname, syncs it and
+// renames it over fname, so a crash leaves either the old or the new file.
+func writeFileAtomic(fname string, data []byte) error {
+	tfp, err := ioutil.TempFile(filepath.Dir(fname), "."+filepath.Base(fname)+".tmp")
+	if err != nil {
+		return err
 	}
+	tfname := tfp.Name()
+	defer os.Remove(tfname)
 
+	if _, err := tfp.Write(data); err != nil {
+		tfp.Close()
+		return err
+	}
+	if err := tfp.Sync(); err != nil {
+		tfp.Close()
+		return err
+	}
+	if err := tfp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tfname, fname); err != nil {
+		return err
+	}
+	// make the rename itself durable
+	if dir, err := os.Open(filepath.Dir(fname)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
 	return nil
 }
 
@@ -90,14 +90,8 @@ func LoadSavedata(fname string) ([]byte, error) {
 	return ioutil.ReadFile(fname)
 }
 
+// Deprecated: use ConnectionType.String.
 func ConnStatusString(status int) (s string) {
-	switch status {
-	case CONNECTION_NONE:
-		s = "CONNECTION_NONE"
-	case CONNECTION_TCP:
-		s = "CONNECTION_TCP"
-	case CONNECTION_UDP:
-		s = "CONNECTION_UDP"
-	}
+	s, _ = enumLookup(connectionTypeNames, status)
 	return
 }
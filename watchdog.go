@@ -0,0 +1,222 @@
+package tox
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NetworkState is the connection of the instance as a dashboard shows it.
+type NetworkState int
+
+const (
+	NETWORK_OFFLINE  NetworkState = iota
+	NETWORK_DEGRADED              // TCP relays only
+	NETWORK_ONLINE                // UDP
+)
+
+func (this NetworkState) String() string {
+	switch this {
+	case NETWORK_OFFLINE:
+		return "offline"
+	case NETWORK_DEGRADED:
+		return "degraded (TCP only)"
+	case NETWORK_ONLINE:
+		return "online"
+	}
+	return fmt.Sprintf("NetworkState(%d)", int(this))
+}
+
+func networkState(status ConnectionType) NetworkState {
+	switch status {
+	case CONNECTION_UDP:
+		return NETWORK_ONLINE
+	case CONNECTION_TCP:
+		return NETWORK_DEGRADED
+	}
+	return NETWORK_OFFLINE
+}
+
+// NetworkEvent is emitted on every state change, and every ReportInterval
+// while offline.
+type NetworkEvent struct {
+	State    NetworkState
+	Since    time.Time     // when the state began
+	Offline  time.Duration // time offline so far, for NETWORK_OFFLINE
+	Attempts int           // re-bootstraps since going offline
+	TcpOnly  bool          // re-bootstrapping from TCP relays only
+}
+
+func (this NetworkEvent) String() string {
+	if this.State == NETWORK_OFFLINE && this.Offline > 0 {
+		s := fmt.Sprintf("offline for %s, %d re-bootstraps", this.Offline.Round(time.Second), this.Attempts)
+		if this.TcpOnly {
+			s += ", TCP relays only"
+		}
+		return s
+	}
+	return this.State.String()
+}
+
+// Watchdog follows the self connection status. While offline it
+// re-bootstraps from its Bootstrapper with exponential backoff, after
+// TcpFallback attempts with BootstrapRelays, in case UDP is blocked. The
+// switch is reported right away.
+// The Bootstrapper should not be started, the watchdog does its retries, and
+// updates its cache.
+type Watchdog struct {
+	MinBackoff     time.Duration // 5s if 0
+	MaxBackoff     time.Duration // 5m if 0
+	TcpFallback    int           // attempts before TCP only, 3 if 0
+	ReportInterval time.Duration // between offline events, 1m if 0
+	OnEvent        func(NetworkEvent)
+
+	t   *Tox
+	b   *Bootstrapper
+	cbh CallbackHandle
+
+	mu         sync.Mutex
+	state      NetworkState
+	since      time.Time
+	attempts   int
+	tcpOnly    bool
+	nextTry    time.Time
+	lastReport time.Time
+}
+
+func NewWatchdog(t *Tox, b *Bootstrapper) *Watchdog {
+	return &Watchdog{t: t, b: b}
+}
+
+// Start takes the current status and watches it from Iterate.
+func (this *Watchdog) Start() {
+	t := this.t
+	t.lock()
+	status := t.SelfGetConnectionStatus()
+	t.unlock()
+	this.setStatus(status, time.Now())
+
+	this.cbh = t.CallbackSelfConnectionStatusAdd(func(_ *Tox, status ConnectionType, _ interface{}) {
+		this.setStatus(status, time.Now())
+	}, nil)
+	t.lock()
+	t.watchdog = this
+	t.unlock()
+}
+
+func (this *Watchdog) Stop() {
+	t := this.t
+	t.CallbackRemove(this.cbh)
+	t.lock()
+	if t.watchdog == this {
+		t.watchdog = nil
+	}
+	t.unlock()
+}
+
+// State returns the current state and when it began.
+func (this *Watchdog) State() (NetworkState, time.Time) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.state, this.since
+}
+
+func (this *Watchdog) setStatus(status ConnectionType, now time.Time) {
+	state := networkState(status)
+	this.mu.Lock()
+	if state == this.state && !this.since.IsZero() {
+		this.mu.Unlock()
+		return
+	}
+	wasOffline := this.state == NETWORK_OFFLINE && !this.since.IsZero()
+	this.state = state
+	this.since = now
+	this.attempts = 0
+	this.tcpOnly = false
+	this.nextTry = now.Add(this.backoff(0))
+	this.lastReport = now
+	ev := NetworkEvent{State: state, Since: now}
+	this.mu.Unlock()
+
+	if wasOffline && state != NETWORK_OFFLINE && this.b != nil {
+		this.b.remember()
+	}
+	this.emit(ev)
+}
+
+// backoff is the delay after attempt number n, counting from 0.
+func (this *Watchdog) backoff(n int) time.Duration {
+	min, max := this.MinBackoff, this.MaxBackoff
+	if min <= 0 {
+		min = 5 * time.Second
+	}
+	if max <= 0 {
+		max = 5 * time.Minute
+	}
+	d := min
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// tick retries and reports while offline.
+func (this *Watchdog) tick(now time.Time) {
+	this.mu.Lock()
+	if this.state != NETWORK_OFFLINE {
+		this.mu.Unlock()
+		return
+	}
+	retry := !now.Before(this.nextTry)
+	tcpOnly := false
+	if retry {
+		fallback := this.TcpFallback
+		if fallback <= 0 {
+			fallback = 3
+		}
+		tcpOnly = this.attempts >= fallback
+		this.attempts++
+		this.nextTry = now.Add(this.backoff(this.attempts))
+	}
+	report := this.ReportInterval
+	if report <= 0 {
+		report = time.Minute
+	}
+	switched := tcpOnly && !this.tcpOnly
+	this.tcpOnly = this.tcpOnly || tcpOnly
+	var ev *NetworkEvent
+	if switched || now.Sub(this.lastReport) >= report {
+		this.lastReport = now
+		ev = &NetworkEvent{NETWORK_OFFLINE, this.since, now.Sub(this.since), this.attempts, this.tcpOnly}
+	}
+	this.mu.Unlock()
+
+	if retry && this.b != nil {
+		if tcpOnly {
+			this.b.BootstrapRelays()
+		} else {
+			this.b.Bootstrap()
+		}
+	}
+	if ev != nil {
+		this.emit(*ev)
+	}
+}
+
+func (this *Watchdog) emit(ev NetworkEvent) {
+	if this.OnEvent != nil {
+		this.OnEvent(ev)
+	}
+}
+
+func (this *Tox) watchdogIterate() {
+	this.lock()
+	w := this.watchdog
+	this.unlock()
+	if w != nil {
+		w.tick(time.Now())
+	}
+}